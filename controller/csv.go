@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+const mimeTextCSV = "text/csv"
+
+type (
+	// csvResponse only commits the response on the first write, so errors that
+	// happen before anything is written can still be answered with an error
+	// status.
+	csvResponse struct {
+		c echo.Context
+	}
+
+	// csvRideReader reads rides from a CSV file with the same header as the
+	// export, so an export can be imported again. Columns assigned by the server
+	// such as id are ignored. Lines are numbered by record, the header being
+	// line 1.
+	csvRideReader struct {
+		reader  *csv.Reader
+		columns map[string]int
+		line    int
+	}
+)
+
+func (w csvResponse) Write(p []byte) (int, error) {
+	res := w.c.Response()
+	if !res.Committed {
+		res.Header().Set(echo.HeaderContentType, mimeTextCSV)
+		res.Header().Set(echo.HeaderContentDisposition, "attachment; filename=\"rides.csv\"")
+		res.WriteHeader(http.StatusOK)
+	}
+	return res.Write(p)
+}
+
+func newCSVRideReader(r io.Reader) (*csvRideReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	for _, column := range []string{"startLat", "startLong", "endLat", "endLong", "riderName", "driverName", "driverVehicle"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("missing %s column", column)
+		}
+	}
+	return &csvRideReader{reader: reader, columns: columns, line: 1}, nil
+}
+
+func (r *csvRideReader) Read() (importLine, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return importLine{}, io.EOF
+	}
+	r.line++
+	if _, ok := err.(*csv.ParseError); ok {
+		return importLine{Number: r.line, Err: err}, nil
+	}
+	if err != nil {
+		return importLine{Number: r.line}, err
+	}
+
+	line := importLine{Number: r.line}
+	line.Ride, line.Err = r.parseRide(record)
+	return line, nil
+}
+
+func (r *csvRideReader) parseRide(record []string) (domain.Ride, error) {
+	var (
+		ride domain.Ride
+		errs []string
+	)
+	field := func(column string) string {
+		if i, ok := r.columns[column]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	parseFloat := func(column string) float64 {
+		f, err := strconv.ParseFloat(field(column), 64)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s is not a valid number", column))
+		}
+		return f
+	}
+	parseTime := func(column string) *time.Time {
+		if field(column) == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, field(column))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s is not a valid RFC3339 time", column))
+			return nil
+		}
+		return &t
+	}
+	parseID := func(column string) *int64 {
+		if field(column) == "" {
+			return nil
+		}
+		id, err := strconv.ParseInt(field(column), 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s is not a valid ID", column))
+			return nil
+		}
+		return &id
+	}
+
+	ride.StartLatitude = parseFloat("startLat")
+	ride.StartLongitude = parseFloat("startLong")
+	ride.EndLatitude = parseFloat("endLat")
+	ride.EndLongitude = parseFloat("endLong")
+	ride.RiderName = field("riderName")
+	ride.DriverName = field("driverName")
+	ride.DriverVehicle = field("driverVehicle")
+	ride.Status = domain.RideStatus(field("status"))
+	ride.StartedAt = parseTime("startedAt")
+	ride.EndedAt = parseTime("endedAt")
+	ride.VehicleID = parseID("vehicleId")
+
+	if len(errs) > 0 {
+		return ride, errors.New(strings.Join(errs, "; "))
+	}
+	return ride, nil
+}
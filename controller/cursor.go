@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+const (
+	cursorDirectionNext = "next"
+	cursorDirectionPrev = "prev"
+)
+
+type (
+	// cursor is what's carried by the opaque cursors handed out to clients. The
+	// position is the cursor returned by the repository, it's only accepted back
+	// along with the same filters it was issued for.
+	cursor struct {
+		Position  string `json:"p"`
+		Direction string `json:"d"`
+		Filters   string `json:"f"`
+	}
+
+	// cursorCipher encodes cursors as JSON sealed with AES-GCM in base64, so
+	// clients can neither read the positions nor forge or change them. Every
+	// cursor is sealed with a nonce read from nonces.
+	cursorCipher struct {
+		aead   cipher.AEAD
+		nonces io.Reader
+	}
+)
+
+// newCursorCipher returns a cipher sealing cursors with an AES-256 key derived
+// from key, so a key of any length can be used.
+func newCursorCipher(key []byte) cursorCipher {
+	derived := sha256.Sum256(key)
+	// NOTE: Neither can fail with a 32 byte AES key.
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return cursorCipher{aead: aead, nonces: rand.Reader}
+}
+
+// issue returns the opaque cursor of the repository position, which is empty
+// when there is no page in that direction.
+func (s cursorCipher) issue(position, direction string, filters url.Values) string {
+	if position == "" {
+		return ""
+	}
+	return s.encode(cursor{Position: position, Direction: direction, Filters: filters.Encode()})
+}
+
+// issuePage returns the opaque cursors of the pages around a page.
+func (s cursorCipher) issuePage(cursors domain.PageCursors, filters url.Values) domain.PageCursors {
+	return domain.PageCursors{
+		Next: s.issue(cursors.Next, cursorDirectionNext, filters),
+		Prev: s.issue(cursors.Prev, cursorDirectionPrev, filters),
+	}
+}
+
+// position returns the repository position of the opaque cursor after making
+// sure it was issued by the server for the same direction and filters.
+func (s cursorCipher) position(token, direction string, filters url.Values) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	c, err := s.decode(token)
+	if err != nil {
+		return "", err
+	}
+	if c.Direction != direction {
+		return "", fmt.Errorf("cursor pages %s instead of %s", c.Direction, direction)
+	}
+	if c.Filters != filters.Encode() {
+		return "", errors.New("cursor was issued for other filters")
+	}
+	return c.Position, nil
+}
+
+// encode seals the cursor after its nonce, it panics when no nonce can be read
+// the same as when there is no randomness left to read.
+func (s cursorCipher) encode(c cursor) string {
+	payload, _ := json.Marshal(c)
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(s.nonces, nonce); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, payload, nil))
+}
+
+func (s cursorCipher) decode(token string) (cursor, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return cursor{}, errors.New("cursor is malformed")
+	}
+	nonce, sealed := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	payload, err := s.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return cursor{}, errors.New("cursor wasn't issued by the server")
+	}
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return cursor{}, errors.New("cursor is malformed")
+	}
+	return c, nil
+}
+
+// pageFilters describes the filters of the page in a canonical form, the
+// cursor and limit aren't filters.
+func pageFilters(page domain.Pagination) url.Values {
+	filters := url.Values{}
+	if page.IncludeDeleted {
+		filters.Set("includeDeleted", "true")
+	}
+	if !page.From.IsZero() {
+		filters.Set("from", page.From.UTC().Format(time.RFC3339Nano))
+	}
+	if !page.To.IsZero() {
+		filters.Set("to", page.To.UTC().Format(time.RFC3339Nano))
+	}
+	if page.MinDistance > 0 {
+		filters.Set("minDistance", formatFloat(page.MinDistance))
+	}
+	if page.MaxDistance > 0 {
+		filters.Set("maxDistance", formatFloat(page.MaxDistance))
+	}
+	if page.RiderID != 0 {
+		filters.Set("riderId", strconv.FormatInt(page.RiderID, 10))
+	}
+	names := map[string]string{
+		"riderName":           page.RiderName,
+		"riderNamePrefix":     page.RiderNamePrefix,
+		"driverName":          page.DriverName,
+		"driverNamePrefix":    page.DriverNamePrefix,
+		"driverVehicle":       page.DriverVehicle,
+		"driverVehiclePrefix": page.DriverVehiclePrefix,
+	}
+	for name, value := range names {
+		if value != "" {
+			filters.Set(name, value)
+		}
+	}
+	// NOTE: Positions hold the values of the sort fields, so they can't be used
+	// with another sort. The page has already been validated.
+	if page.Sort != "" {
+		fields, _ := page.SortFields()
+		sort := make([]string, len(fields))
+		for i, field := range fields {
+			sort[i] = field.String()
+		}
+		filters.Set("sort", strings.Join(sort, ","))
+	}
+	return filters
+}
+
+// nearbyFilters describes the search along with the filters of its page.
+func nearbyFilters(query domain.NearbyQuery) url.Values {
+	filters := pageFilters(query.Pagination)
+	filters.Set("lat", formatFloat(query.Latitude))
+	filters.Set("lng", formatFloat(query.Longitude))
+	filters.Set("radius", formatFloat(query.Radius))
+	filters.Set("endpoint", string(query.Endpoint))
+	return filters
+}
+
+// searchFilters describes the search along with the filters of its page.
+func searchFilters(query domain.SearchQuery) url.Values {
+	filters := pageFilters(query.Pagination)
+	filters.Set("q", query.Q)
+	return filters
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorCipher_issue(t *testing.T) {
+	cipher := newCursorCipher([]byte("test-cursor-key"))
+	filters := url.Values{"riderName": {"John Doe"}}
+	position := "2021-04-01T10:00:00Z,42179531"
+
+	token := cipher.issue(position, cursorDirectionNext, filters)
+	assert.NotContains(t, token, "42179531")
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	assert.NoError(t, err)
+	for _, plain := range []string{"42179531", position, "John", cursorDirectionNext} {
+		assert.NotContains(t, string(sealed), plain)
+	}
+	assert.NotEqual(t, token, cipher.issue(position, cursorDirectionNext, filters))
+
+	got, err := cipher.position(token, cursorDirectionNext, filters)
+	assert.NoError(t, err)
+	assert.Equal(t, position, got)
+
+	_, err = newCursorCipher([]byte("other-key")).position(token, cursorDirectionNext, filters)
+	assert.EqualError(t, err, "cursor wasn't issued by the server")
+}
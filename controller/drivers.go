@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+type (
+	driverCntrl struct {
+		driverRepo   domain.DriverRepository
+		now          func() time.Time
+		queryTimeout time.Duration
+		cursors      cursorCipher
+	}
+
+	driversEnvelope struct {
+		Drivers    []domain.Driver `json:"drivers"`
+		NextCursor string          `json:"nextCursor"`
+	}
+)
+
+// SetupDriverController registers the driver routes, queries and cursors are
+// handled the same way as by SetupRideController.
+func SetupDriverController(e *echo.Echo, driverRepo domain.DriverRepository, queryTimeout time.Duration, cursorKey []byte) {
+	cntrl := &driverCntrl{driverRepo: driverRepo, now: time.Now, queryTimeout: queryTimeout, cursors: newCursorCipher(cursorKey)}
+
+	e.POST("/drivers", cntrl.addDriver)
+	e.GET("/drivers", cntrl.getAllDrivers)
+	e.GET("/drivers/:id", cntrl.getDriver)
+	e.PUT("/drivers/:id", cntrl.updateDriver)
+	e.DELETE("/drivers/:id", cntrl.deleteDriver)
+}
+
+func (cntrl driverCntrl) addDriver(c echo.Context) error {
+	var driver domain.Driver
+	if err := c.Bind(&driver); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	if err := driver.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+	now := cntrl.now().UTC()
+	driver.CreatedAt = now
+	driver.UpdatedAt = now
+
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	id, err := cntrl.driverRepo.Insert(ctx, driver)
+	if err != nil {
+		return repositoryError(err)
+	}
+	driver.ID = id
+	return c.JSON(http.StatusCreated, driver)
+}
+
+func (cntrl driverCntrl) getAllDrivers(c echo.Context) error {
+	var page domain.DriverPagination
+	if err := c.Bind(&page); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	after, err := cntrl.cursors.position(page.After, cursorDirectionNext, driverFilters())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", err))
+	}
+	page.After = after
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	drivers, next, err := cntrl.driverRepo.SelectAll(ctx, page)
+	if err != nil {
+		return repositoryError(err)
+	}
+	return c.JSON(http.StatusOK, driversEnvelope{
+		Drivers:    drivers,
+		NextCursor: cntrl.cursors.issue(next, cursorDirectionNext, driverFilters()),
+	})
+}
+
+func (cntrl driverCntrl) getDriver(c echo.Context) error {
+	driverID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	driver, err := cntrl.driverRepo.SelectByID(ctx, driverID, false)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if driver == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find driver with ID %d", driverID))
+	}
+	return c.JSON(http.StatusOK, driver)
+}
+
+// updateDriver doesn't touch the rides of the driver, they pick up its new name
+// and vehicle the next time they're saved.
+func (cntrl driverCntrl) updateDriver(c echo.Context) error {
+	driverID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	var driver domain.Driver
+	if err := c.Bind(&driver); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	driver.ID = driverID
+	if err := driver.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	updated, err := cntrl.driverRepo.Update(ctx, driver)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if updated == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find driver with ID %d", driverID))
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (cntrl driverCntrl) deleteDriver(c echo.Context) error {
+	driverID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	deleted, err := cntrl.driverRepo.Delete(ctx, driverID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if !deleted {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find driver with ID %d", driverID))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// driverFilters binds driver cursors to the drivers list, so they can't be
+// passed to the rides list as if they were ride positions.
+func driverFilters() url.Values {
+	return url.Values{"list": {"drivers"}}
+}
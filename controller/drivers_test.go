@@ -0,0 +1,368 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/repository/mock"
+)
+
+type setupMockDriverRepo func(mockRepo *mock.MockDriverRepository)
+
+func newDriverController(t *testing.T, fn setupMockDriverRepo) (driverCntrl, *gomock.Controller) {
+	mockCtrl := gomock.NewController(t)
+
+	driverRepo := mock.NewMockDriverRepository(mockCtrl)
+	if fn != nil {
+		fn(driverRepo)
+	}
+
+	return driverCntrl{driverRepo: driverRepo, now: testTime, cursors: testCursorCipher()}, mockCtrl
+}
+
+func TestDriverController_addDriver(t *testing.T) {
+	testCases := []struct {
+		testName            string
+		requestBody         string
+		setupMockDriverRepo setupMockDriverRepo
+		statusCode          int
+		responseBody        string
+		expectedErr         string
+	}{
+		{
+			testName:    "When request body is malformed, return status code 400 with error message",
+			requestBody: "invalid-json",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: code=400, message=Syntax error: offset=1, error=invalid character 'i' looking for beginning of value, internal=invalid character 'i' looking for beginning of value",
+		},
+		{
+			testName:    "When request is invalid, return status code 422 with error message",
+			requestBody: `{"name": "", "vehicle": ""}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: name can't be empty; vehicle can't be empty",
+		},
+		{
+			testName:    "When repository returns error, return status code 500 with error message",
+			requestBody: `{"name": "Driver", "vehicle": "Car"}`,
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Driver{Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()}).
+					Return(int64(-1), errors.New("Insert error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Insert error",
+		},
+		{
+			testName:    "When successful, return status code 201 with response body",
+			requestBody: `{"name": "Driver", "vehicle": "Car"}`,
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Driver{Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()}).
+					Return(int64(1), nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"name\":\"Driver\",\"vehicle\":\"Car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/drivers", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newDriverController(t, tc.setupMockDriverRepo)
+			defer mock.Finish()
+
+			err := cntrl.addDriver(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDriverController_getAllDrivers(t *testing.T) {
+	testCases := []struct {
+		testName            string
+		queryParams         string
+		setupMockDriverRepo setupMockDriverRepo
+		statusCode          int
+		responseBody        string
+		expectedErr         string
+	}{
+		{
+			testName:    "When cursor wasn't issued for drivers, return status code 400 with error message",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, nil),
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName:    "When repository returns error, return status code 500 with error message",
+			queryParams: "?limit=1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					SelectAll(gomock.Any(), domain.DriverPagination{Limit: 1}).
+					Return(nil, "", errors.New("Select All error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Select All error",
+		},
+		{
+			testName:    "When successful, return status code 200 with drivers and the next cursor",
+			queryParams: "?after=" + testCursor("1", cursorDirectionNext, driverFilters()) + "&limit=1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					SelectAll(gomock.Any(), domain.DriverPagination{After: "1", Limit: 1}).
+					Return([]domain.Driver{{ID: 2, Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()}}, "2", nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"drivers\":[{\"id\":2,\"name\":\"Driver\",\"vehicle\":\"Car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}],\"nextCursor\":\"" + testCursor("2", cursorDirectionNext, driverFilters()) + "\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/drivers"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newDriverController(t, tc.setupMockDriverRepo)
+			defer mock.Finish()
+
+			err := cntrl.getAllDrivers(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDriverController_getDriver(t *testing.T) {
+	testCases := []struct {
+		testName            string
+		paramID             string
+		setupMockDriverRepo setupMockDriverRepo
+		statusCode          int
+		responseBody        string
+		expectedErr         string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When repository returns no result, return status code 404 with error message",
+			paramID:  "1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find driver with ID 1",
+		},
+		{
+			testName: "When successful, return status code 200 with result",
+			paramID:  "1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), false).
+					Return(&domain.Driver{ID: 1, Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"name\":\"Driver\",\"vehicle\":\"Car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/drivers/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newDriverController(t, tc.setupMockDriverRepo)
+			defer mock.Finish()
+
+			err := cntrl.getDriver(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDriverController_updateDriver(t *testing.T) {
+	testCases := []struct {
+		testName            string
+		paramID             string
+		requestBody         string
+		setupMockDriverRepo setupMockDriverRepo
+		statusCode          int
+		responseBody        string
+		expectedErr         string
+	}{
+		{
+			testName:    "When request is invalid, return status code 422 with error message",
+			paramID:     "1",
+			requestBody: `{"name": "Driver", "vehicle": ""}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: vehicle can't be empty",
+		},
+		{
+			testName:    "When repository returns no result, return status code 404 with error message",
+			paramID:     "1",
+			requestBody: `{"name": "Driver", "vehicle": "Car"}`,
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().Update(gomock.Any(), domain.Driver{ID: 1, Name: "Driver", Vehicle: "Car"}).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find driver with ID 1",
+		},
+		{
+			testName:    "When successful, return status code 200 with updated driver",
+			paramID:     "1",
+			requestBody: `{"id": 2, "name": "Driver", "vehicle": "Motorcycle"}`,
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					Update(gomock.Any(), domain.Driver{ID: 1, Name: "Driver", Vehicle: "Motorcycle"}).
+					Return(&domain.Driver{ID: 1, Name: "Driver", Vehicle: "Motorcycle", CreatedAt: testTime(), UpdatedAt: testTime()}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"name\":\"Driver\",\"vehicle\":\"Motorcycle\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/drivers/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newDriverController(t, tc.setupMockDriverRepo)
+			defer mock.Finish()
+
+			err := cntrl.updateDriver(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDriverController_deleteDriver(t *testing.T) {
+	testCases := []struct {
+		testName            string
+		paramID             string
+		setupMockDriverRepo setupMockDriverRepo
+		statusCode          int
+		expectedErr         string
+	}{
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			paramID:  "1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(1)).Return(false, errors.New("Delete error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Delete error",
+		},
+		{
+			testName: "When nothing is deleted, return status code 404 with error message",
+			paramID:  "1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(1)).Return(false, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find driver with ID 1",
+		},
+		{
+			testName: "When successful, return status code 204",
+			paramID:  "1",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(1)).Return(true, nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/drivers/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newDriverController(t, tc.setupMockDriverRepo)
+			defer mock.Finish()
+
+			err := cntrl.deleteDriver(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+const mimeApplicationGeoJSON = "application/geo+json"
+
+type (
+	geoJSONGeometry struct {
+		Type        string       `json:"type"`
+		Coordinates [][2]float64 `json:"coordinates"`
+	}
+
+	geoJSONFeature struct {
+		Type       string                 `json:"type"`
+		ID         int64                  `json:"id"`
+		Geometry   geoJSONGeometry        `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+
+	// geoJSONFeatureCollection carries the pagination cursors and the included
+	// counts as foreign members so clients can keep paging through GeoJSON
+	// responses.
+	geoJSONFeatureCollection struct {
+		Type       string                         `json:"type"`
+		Features   []geoJSONFeature               `json:"features"`
+		NextCursor string                         `json:"nextCursor"`
+		PrevCursor string                         `json:"prevCursor"`
+		Total      *int64                         `json:"total,omitempty"`
+		Facets     map[string][]domain.FacetCount `json:"facets,omitempty"`
+	}
+)
+
+// acceptsGeoJSON reports whether the client listed GeoJSON in its Accept header.
+func acceptsGeoJSON(c echo.Context) bool {
+	for _, accept := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == mimeApplicationGeoJSON {
+			return true
+		}
+	}
+	return false
+}
+
+// newGeoJSONFeature represents the ride as a line from its start to its end
+// point, every other attribute of the ride becomes a property.
+func newGeoJSONFeature(ride domain.Ride) (geoJSONFeature, error) {
+	encoded, err := json.Marshal(ride)
+	if err != nil {
+		return geoJSONFeature{}, err
+	}
+	var properties map[string]interface{}
+	if err := json.Unmarshal(encoded, &properties); err != nil {
+		return geoJSONFeature{}, err
+	}
+	for _, key := range []string{"id", "startLatitude", "startLongitude", "endLatitude", "endLongitude"} {
+		delete(properties, key)
+	}
+
+	return geoJSONFeature{
+		Type: "Feature",
+		ID:   ride.ID,
+		Geometry: geoJSONGeometry{
+			Type: "LineString",
+			Coordinates: [][2]float64{
+				{ride.StartLongitude, ride.StartLatitude},
+				{ride.EndLongitude, ride.EndLatitude},
+			},
+		},
+		Properties: properties,
+	}, nil
+}
+
+func newGeoJSONFeatureCollection(rides []domain.Ride, info domain.PageInfo) (geoJSONFeatureCollection, error) {
+	features := make([]geoJSONFeature, len(rides))
+	for i, ride := range rides {
+		feature, err := newGeoJSONFeature(ride)
+		if err != nil {
+			return geoJSONFeatureCollection{}, err
+		}
+		features[i] = feature
+	}
+	return geoJSONFeatureCollection{
+		Type:       "FeatureCollection",
+		Features:   features,
+		NextCursor: info.Next,
+		PrevCursor: info.Prev,
+		Total:      info.Total,
+		Facets:     info.Facets,
+	}, nil
+}
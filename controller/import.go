@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+const (
+	mimeApplicationNDJSON = "application/x-ndjson"
+
+	// importBatchSize is how many rides are inserted in a single transaction.
+	importBatchSize = 500
+	// maxImportLineSize is the longest line accepted in an NDJSON import.
+	maxImportLineSize = 1 << 20
+)
+
+type (
+	// rideReader reads the rides of an import one line at a time. A line that
+	// can't be turned into a ride is reported through importLine.Err so the
+	// rest can still be read, the returned error means nothing more can be read.
+	rideReader interface {
+		Read() (importLine, error)
+	}
+
+	importLine struct {
+		Number int
+		Ride   domain.Ride
+		Err    error
+	}
+
+	ndjsonRideReader struct {
+		scanner *bufio.Scanner
+		body    *errReader
+		line    int
+	}
+
+	// errReader sets err to the error other than io.EOF returned by r, which
+	// tells whether the last line returned by the scanner may be cut short.
+	errReader struct {
+		r       io.Reader
+		pending error
+		err     error
+	}
+
+	importedRide struct {
+		Line int   `json:"line"`
+		ID   int64 `json:"id"`
+	}
+
+	rejectedLine struct {
+		Line   int    `json:"line"`
+		Reason string `json:"reason"`
+	}
+
+	importReport struct {
+		Accepted []importedRide `json:"accepted"`
+		Rejected []rejectedLine `json:"rejected"`
+	}
+)
+
+func newNDJSONRideReader(r io.Reader) *ndjsonRideReader {
+	body := &errReader{r: r}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	return &ndjsonRideReader{scanner: scanner, body: body}
+}
+
+// Read skips blank lines, every other line must be a JSON ride just like the
+// body of POST /rides.
+func (r *ndjsonRideReader) Read() (importLine, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if r.body.err != nil {
+			// NOTE: The scanner still returns the line it was reading when
+			// the error happened, which may be cut short.
+			return importLine{Number: r.line}, r.body.err
+		}
+		text := r.scanner.Bytes()
+		if strings.TrimSpace(string(text)) == "" {
+			continue
+		}
+		line := importLine{Number: r.line}
+		line.Err = json.Unmarshal(text, &line.Ride)
+		return line, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return importLine{Number: r.line + 1}, err
+	}
+	return importLine{}, io.EOF
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.pending != nil {
+		r.err, r.pending = r.pending, nil
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		if n > 0 {
+			// NOTE: What was read along with the error is returned first, so
+			// the scanner has every complete line before the error by the
+			// time it's set.
+			r.pending = err
+			return n, nil
+		}
+		r.err = err
+	}
+	return n, err
+}
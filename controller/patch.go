@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"encoding/json"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+const mimeApplicationMergePatchJSON = "application/merge-patch+json"
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7396) document on top of the
+// JSON representation of the ride.
+func applyMergePatch(ride domain.Ride, patch []byte) (domain.Ride, error) {
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return ride, err
+	}
+
+	original, err := json.Marshal(ride)
+	if err != nil {
+		return ride, err
+	}
+	var target interface{}
+	if err := json.Unmarshal(original, &target); err != nil {
+		return ride, err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, patchDoc))
+	if err != nil {
+		return ride, err
+	}
+	var patched domain.Ride
+	if err := json.Unmarshal(merged, &patched); err != nil {
+		return ride, err
+	}
+	return patched, nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+type riderCntrl struct {
+	riderRepo    domain.RiderRepository
+	rideRepo     domain.RideRepository
+	now          func() time.Time
+	queryTimeout time.Duration
+}
+
+// SetupRiderController registers the rider routes, the rides of each rider are
+// registered by SetupRideController. Renamed riders are renamed in rideRepo.
+func SetupRiderController(e *echo.Echo, riderRepo domain.RiderRepository, rideRepo domain.RideRepository, queryTimeout time.Duration) {
+	cntrl := &riderCntrl{riderRepo: riderRepo, rideRepo: rideRepo, now: time.Now, queryTimeout: queryTimeout}
+
+	e.POST("/riders", cntrl.addRider)
+	e.GET("/riders/:id", cntrl.getRider)
+	e.PUT("/riders/:id", cntrl.updateRider)
+}
+
+func (cntrl riderCntrl) addRider(c echo.Context) error {
+	var rider domain.Rider
+	if err := c.Bind(&rider); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	if err := rider.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+	now := cntrl.now().UTC()
+	rider.CreatedAt = now
+	rider.UpdatedAt = now
+
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	id, err := cntrl.riderRepo.Insert(ctx, rider)
+	if err != nil {
+		return repositoryError(err)
+	}
+	rider.ID = id
+	return c.JSON(http.StatusCreated, rider)
+}
+
+func (cntrl riderCntrl) getRider(c echo.Context) error {
+	riderID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	rider, err := cntrl.riderRepo.SelectByID(ctx, riderID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if rider == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find rider with ID %d", riderID))
+	}
+	return c.JSON(http.StatusOK, rider)
+}
+
+// updateRider renames the rides of the rider along with it, so that they're
+// still found by the name of their rider.
+func (cntrl riderCntrl) updateRider(c echo.Context) error {
+	riderID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	var rider domain.Rider
+	if err := c.Bind(&rider); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	rider.ID = riderID
+	if err := rider.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	updated, err := cntrl.riderRepo.Update(ctx, rider)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if updated == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find rider with ID %d", riderID))
+	}
+	if err := cntrl.rideRepo.RenameRider(ctx, updated.ID, updated.Name); err != nil {
+		return repositoryError(err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/repository/mock"
+)
+
+type setupMockRiderRepo func(mockRepo *mock.MockRiderRepository)
+
+func newRiderController(t *testing.T, fn setupMockRiderRepo) (riderCntrl, *gomock.Controller) {
+	mockCtrl := gomock.NewController(t)
+
+	riderRepo := mock.NewMockRiderRepository(mockCtrl)
+	if fn != nil {
+		fn(riderRepo)
+	}
+
+	return riderCntrl{riderRepo: riderRepo, now: testTime}, mockCtrl
+}
+
+func TestRiderController_addRider(t *testing.T) {
+	testCases := []struct {
+		testName           string
+		requestBody        string
+		setupMockRiderRepo setupMockRiderRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
+		{
+			testName:    "When request body is malformed, return status code 400 with error message",
+			requestBody: "invalid-json",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: code=400, message=Syntax error: offset=1, error=invalid character 'i' looking for beginning of value, internal=invalid character 'i' looking for beginning of value",
+		},
+		{
+			testName:    "When request is invalid, return status code 422 with error message",
+			requestBody: `{"name": "John Doe", "phone": "0812", "preferences": {"contactBy": "email"}}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: 0812 is not a valid phone number, must be in E.164 format; email can't be empty when contacted by email",
+		},
+		{
+			testName:    "When repository returns error, return status code 500 with error message",
+			requestBody: `{"name": "John Doe"}`,
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Rider{Name: "John Doe", CreatedAt: testTime(), UpdatedAt: testTime()}).
+					Return(int64(-1), errors.New("Insert error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Insert error",
+		},
+		{
+			testName:    "When successful, return status code 201 with response body",
+			requestBody: `{"name": "John Doe", "email": "john@example.com", "preferences": {"contactBy": "email", "language": "id"}}`,
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Rider{
+						Name:        "John Doe",
+						Email:       "john@example.com",
+						Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactEmail, Language: "id"},
+						CreatedAt:   testTime(),
+						UpdatedAt:   testTime(),
+					}).
+					Return(int64(1), nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"name\":\"John Doe\",\"email\":\"john@example.com\",\"preferences\":{\"contactBy\":\"email\",\"language\":\"id\"},\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/riders", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRiderController(t, tc.setupMockRiderRepo)
+			defer mock.Finish()
+
+			err := cntrl.addRider(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRiderController_getRider(t *testing.T) {
+	testCases := []struct {
+		testName           string
+		paramID            string
+		setupMockRiderRepo setupMockRiderRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When repository returns no result, return status code 404 with error message",
+			paramID:  "1",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1)).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find rider with ID 1",
+		},
+		{
+			testName: "When successful, return status code 200 with result",
+			paramID:  "1",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1)).
+					Return(&domain.Rider{ID: 1, Name: "John Doe", CreatedAt: testTime(), UpdatedAt: testTime()}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"name\":\"John Doe\",\"preferences\":{},\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/riders/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRiderController(t, tc.setupMockRiderRepo)
+			defer mock.Finish()
+
+			err := cntrl.getRider(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRiderController_updateRider(t *testing.T) {
+	testCases := []struct {
+		testName           string
+		paramID            string
+		requestBody        string
+		setupMockRiderRepo setupMockRiderRepo
+		setupMockRepo      setupMockRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
+		{
+			testName:    "When request is invalid, return status code 422 with error message",
+			paramID:     "1",
+			requestBody: `{"name": "John Doe", "preferences": {"contactBy": "pigeon"}}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: pigeon is not a valid contactBy, must be either email or phone",
+		},
+		{
+			testName:    "When repository returns no result, return status code 404 with error message",
+			paramID:     "1",
+			requestBody: `{"name": "John Doe"}`,
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().Update(gomock.Any(), domain.Rider{ID: 1, Name: "John Doe"}).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find rider with ID 1",
+		},
+		{
+			testName:    "When renaming the rides of the rider fails, return status code 500 with error message",
+			paramID:     "1",
+			requestBody: `{"name": "Johnny Doe"}`,
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					Update(gomock.Any(), domain.Rider{ID: 1, Name: "Johnny Doe"}).
+					Return(&domain.Rider{ID: 1, Name: "Johnny Doe", CreatedAt: testTime(), UpdatedAt: testTime()}, nil)
+			},
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().RenameRider(gomock.Any(), int64(1), "Johnny Doe").Return(errors.New("Exec error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Exec error",
+		},
+		{
+			testName:    "When successful, return status code 200 with updated rider",
+			paramID:     "1",
+			requestBody: `{"id": 2, "name": "John Doe", "phone": "+6281234567890", "preferences": {"contactBy": "phone"}}`,
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					Update(gomock.Any(), domain.Rider{
+						ID:          1,
+						Name:        "John Doe",
+						Phone:       "+6281234567890",
+						Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactPhone},
+					}).
+					Return(&domain.Rider{
+						ID:          1,
+						Name:        "John Doe",
+						Phone:       "+6281234567890",
+						Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactPhone},
+						CreatedAt:   testTime(),
+						UpdatedAt:   testTime(),
+					}, nil)
+			},
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().RenameRider(gomock.Any(), int64(1), "John Doe").Return(nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"name\":\"John Doe\",\"phone\":\"+6281234567890\",\"preferences\":{\"contactBy\":\"phone\"},\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/riders/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRiderController(t, tc.setupMockRiderRepo)
+			defer mock.Finish()
+			rides, rideMock := newRideController(t, tc.setupMockRepo)
+			defer rideMock.Finish()
+			cntrl.rideRepo = rides.rideRepo
+
+			err := cntrl.updateRider(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
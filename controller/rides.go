@@ -1,9 +1,16 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -12,23 +19,102 @@ import (
 
 type (
 	rideCntrl struct {
-		rideRepo domain.RideRepository
+		rideRepo     domain.RideRepository
+		driverRepo   domain.DriverRepository
+		riderRepo    domain.RiderRepository
+		vehicleRepo  domain.VehicleRepository
+		now          func() time.Time
+		queryTimeout time.Duration
+		// maxImportSize is the largest body accepted by importRides in bytes.
+		maxImportSize int64
+		cursors       cursorCipher
+	}
+
+	// RideConfig is what SetupRideController sets the ride routes up with.
+	RideConfig struct {
+		Rides domain.RideRepository
+		// Drivers, Riders and Vehicles are what rides referencing a driver, a
+		// rider or a vehicle are checked against.
+		Drivers  domain.DriverRepository
+		Riders   domain.RiderRepository
+		Vehicles domain.VehicleRepository
+		// QueryTimeout cancels the queries made while handling a request, zero
+		// means they are only cancelled when the client goes away.
+		QueryTimeout time.Duration
+		// MaxImportSize is the largest import body accepted in bytes.
+		MaxImportSize int64
+		// CursorKey encrypts pagination cursors.
+		CursorKey []byte
 	}
 
 	ridesEnvelope struct {
-		Rides  []domain.Ride `json:"rides"`
-		Cursor string        `json:"cursor"`
+		Rides      []domain.Ride `json:"rides"`
+		NextCursor string        `json:"nextCursor"`
+		PrevCursor string        `json:"prevCursor"`
+		// Total and Facets are only set when included.
+		Total  *int64                         `json:"total,omitempty"`
+		Facets map[string][]domain.FacetCount `json:"facets,omitempty"`
+	}
+
+	rideStatsEnvelope struct {
+		GroupBy string             `json:"groupBy"`
+		Stats   []domain.RideStats `json:"stats"`
+	}
+
+	rideQuery struct {
+		IncludeDeleted bool `query:"includeDeleted"`
+	}
+
+	transitionRequest struct {
+		Status domain.RideStatus `json:"status"`
+	}
+
+	// unknownReferenceError is returned when a ride references a driver or a
+	// rider that doesn't exist, or a driver that was deleted.
+	unknownReferenceError struct {
+		name string
+		id   int64
+	}
+
+	// riderNameError is returned when an edited ride keeps its rider but is
+	// given another rider name, riders are renamed on their own instead.
+	riderNameError struct {
+		id        int64
+		name      string
+		riderName string
 	}
 )
 
-func SetupRideController(e *echo.Echo, rideRepo domain.RideRepository) {
-	cntrl := &rideCntrl{rideRepo: rideRepo}
+// SetupRideController registers the ride routes, along with the rides of each
+// rider, as configured by config.
+func SetupRideController(e *echo.Echo, config RideConfig) {
+	cntrl := &rideCntrl{
+		rideRepo:      config.Rides,
+		driverRepo:    config.Drivers,
+		riderRepo:     config.Riders,
+		vehicleRepo:   config.Vehicles,
+		now:           time.Now,
+		queryTimeout:  config.QueryTimeout,
+		maxImportSize: config.MaxImportSize,
+		cursors:       newCursorCipher(config.CursorKey),
+	}
 
 	e.GET("/health", healthCheck)
 
 	e.POST("/rides", cntrl.addRide)
+	e.POST("/rides/import", cntrl.importRides)
 	e.GET("/rides", cntrl.getAllRides)
+	e.GET("/rides/nearby", cntrl.getNearbyRides)
+	e.GET("/rides/search", cntrl.searchRides)
+	e.GET("/rides/stats", cntrl.getRideStats)
+	e.GET("/rides/export.csv", cntrl.exportRides)
 	e.GET("/rides/:id", cntrl.getRide)
+	e.PUT("/rides/:id", cntrl.updateRide)
+	e.PATCH("/rides/:id", cntrl.patchRide)
+	e.DELETE("/rides/:id", cntrl.deleteRide)
+	e.POST("/rides/:id/restore", cntrl.restoreRide)
+	e.POST("/rides/:id/transitions", cntrl.transitionRide)
+	e.GET("/riders/:id/rides", cntrl.getRiderRides)
 }
 
 func healthCheck(c echo.Context) error {
@@ -40,41 +126,693 @@ func (cntrl rideCntrl) addRide(c echo.Context) error {
 	if err := c.Bind(&ride); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
 	}
-	if err := ride.Validate(); err != nil {
-		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	ride = cntrl.newRide(ride)
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	if err := cntrl.checkRide(ctx, nil, &ride); err != nil {
+		return checkRideError(err)
 	}
-	lastInsertID, err := cntrl.rideRepo.Insert(ride)
+	if err := cntrl.linkRider(ctx, &ride); err != nil {
+		return repositoryError(err)
+	}
+	lastInsertID, err := cntrl.rideRepo.Insert(ctx, ride)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Internal server error: %s", err))
+		return repositoryError(err)
 	}
 	ride.ID = lastInsertID
 	return c.JSON(http.StatusCreated, ride)
 }
 
+// importRides inserts every valid ride in the body, invalid ones are reported
+// back by line without stopping the import. A body larger than maxImportSize
+// is rejected up front when its length is known. Otherwise the import stops once
+// the limit is reached, and the rides read so far are reported as usual with
+// status 413.
+func (cntrl rideCntrl) importRides(c echo.Context) error {
+	if c.Request().ContentLength > cntrl.maxImportSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large: imports are limited to %d bytes", cntrl.maxImportSize))
+	}
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, cntrl.maxImportSize)
+
+	var reader rideReader
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	switch mediaType, _, _ := mime.ParseMediaType(contentType); mediaType {
+	case mimeTextCSV:
+		csvReader, err := newCSVRideReader(body)
+		if bodyTooLarge(err) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large: imports are limited to %d bytes", cntrl.maxImportSize))
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+		}
+		reader = csvReader
+	case mimeApplicationNDJSON:
+		reader = newNDJSONRideReader(body)
+	default:
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported content type: %s", contentType))
+	}
+
+	report := importReport{Accepted: []importedRide{}, Rejected: []rejectedLine{}}
+	status := http.StatusOK
+	batch := make([]importLine, 0, importBatchSize)
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if bodyTooLarge(err) {
+			report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Request body too large: imports are limited to %d bytes", cntrl.maxImportSize)})
+			status = http.StatusRequestEntityTooLarge
+			break
+		}
+		if err != nil {
+			report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Malformed request body: %s", err)})
+			break
+		}
+		if line.Err != nil {
+			report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Malformed line: %s", line.Err)})
+			continue
+		}
+		line.Ride = cntrl.newImportedRide(line.Ride)
+		if err := cntrl.checkImportedRide(c.Request().Context(), &line.Ride); err != nil {
+			if invalidRide(err) {
+				report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Invalid ride: %s", err)})
+			} else {
+				report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Internal server error: %s", err)})
+			}
+			continue
+		}
+		if err := cntrl.linkImportedRider(c.Request().Context(), &line.Ride); err != nil {
+			report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Internal server error: %s", err)})
+			continue
+		}
+		if batch = append(batch, line); len(batch) == importBatchSize {
+			cntrl.insertBatch(c.Request().Context(), batch, &report)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		cntrl.insertBatch(c.Request().Context(), batch, &report)
+	}
+	return c.JSON(status, report)
+}
+
+// bodyTooLarge tells whether err is returned by http.MaxBytesReader for a body
+// larger than its limit.
+func bodyTooLarge(err error) bool {
+	// NOTE: There is no error type to check for before Go 1.19.
+	return err != nil && err.Error() == "http: request body too large"
+}
+
+// checkImportedRide checks an imported ride within its own query timeout, the
+// same as every batch is inserted.
+func (cntrl rideCntrl) checkImportedRide(ctx context.Context, ride *domain.Ride) error {
+	ctx, cancel := cntrl.withQueryTimeout(ctx)
+	defer cancel()
+	return cntrl.checkRide(ctx, nil, ride)
+}
+
+// linkImportedRider links an imported ride to its rider within its own query
+// timeout, the same as its references are looked up.
+func (cntrl rideCntrl) linkImportedRider(ctx context.Context, ride *domain.Ride) error {
+	ctx, cancel := cntrl.withQueryTimeout(ctx)
+	defer cancel()
+	return cntrl.linkRider(ctx, ride)
+}
+
+// insertBatch inserts the lines in a single transaction and adds the outcome to
+// the report, a failed batch rejects all of its lines. Every batch gets its own
+// query timeout.
+func (cntrl rideCntrl) insertBatch(ctx context.Context, batch []importLine, report *importReport) {
+	rides := make([]domain.Ride, len(batch))
+	for i, line := range batch {
+		rides[i] = line.Ride
+	}
+	ctx, cancel := cntrl.withQueryTimeout(ctx)
+	defer cancel()
+	ids, err := cntrl.rideRepo.InsertBatch(ctx, rides)
+	for i, line := range batch {
+		if err != nil {
+			report.Rejected = append(report.Rejected, rejectedLine{Line: line.Number, Reason: fmt.Sprintf("Internal server error: %s", err)})
+			continue
+		}
+		report.Accepted = append(report.Accepted, importedRide{Line: line.Number, ID: ids[i]})
+	}
+}
+
 func (cntrl rideCntrl) getAllRides(c echo.Context) error {
 	var page domain.Pagination
 	if err := c.Bind(&page); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
 	}
-	rides, cursor, err := cntrl.rideRepo.SelectAll(page)
+	if err := page.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
+	}
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	return cntrl.respondPage(ctx, c, page)
+}
+
+// getRiderRides pages through the rides referencing a rider the same way as
+// getAllRides.
+func (cntrl rideCntrl) getRiderRides(c echo.Context) error {
+	riderID, err := parseID(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Internal server error: %s", err))
+		return err
+	}
+	var page domain.Pagination
+	if err := c.Bind(&page); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	if err := page.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
+	}
+	page.RiderID = riderID
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	rider, err := cntrl.riderRepo.SelectByID(ctx, riderID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if rider == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find rider with ID %d", riderID))
+	}
+	return cntrl.respondPage(ctx, c, page)
+}
+
+// respondPage responds with the page of rides the cursors of the validated
+// page point to.
+func (cntrl rideCntrl) respondPage(ctx context.Context, c echo.Context, page domain.Pagination) error {
+	filters := pageFilters(page)
+	after, err := cntrl.cursors.position(page.After, cursorDirectionNext, filters)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", err))
+	}
+	before, err := cntrl.cursors.position(page.Before, cursorDirectionPrev, filters)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", err))
+	}
+	page.After, page.Before = after, before
+	rides, info, err := cntrl.rideRepo.SelectAll(ctx, page)
+	if err != nil {
+		return repositoryError(err)
+	}
+	info.PageCursors = cntrl.cursors.issuePage(info.PageCursors, filters)
+	setPageLinks(c, info.PageCursors)
+	return respondRides(c, rides, info)
+}
+
+func (cntrl rideCntrl) getNearbyRides(c echo.Context) error {
+	query := domain.NearbyQuery{Endpoint: domain.RideEndpointStart}
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	if err := query.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
+	}
+	filters := nearbyFilters(query)
+	after, err := cntrl.cursors.position(query.After, cursorDirectionNext, filters)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", err))
+	}
+	query.After = after
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	rides, next, err := cntrl.rideRepo.SelectNearby(ctx, query)
+	if errors.Is(err, domain.ErrTooManyNearbyRides) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+	return respondRides(c, rides, domain.PageInfo{PageCursors: domain.PageCursors{Next: cntrl.cursors.issue(next, cursorDirectionNext, filters)}})
+}
+
+func (cntrl rideCntrl) searchRides(c echo.Context) error {
+	var query domain.SearchQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	if err := query.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
+	}
+	filters := searchFilters(query)
+	after, err := cntrl.cursors.position(query.After, cursorDirectionNext, filters)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", err))
+	}
+	query.After = after
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	rides, next, err := cntrl.rideRepo.Search(ctx, query)
+	if errors.Is(err, domain.ErrSearchUnavailable) {
+		return echo.NewHTTPError(http.StatusNotImplemented, fmt.Sprintf("Not implemented: %s", err))
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+	return respondRides(c, rides, domain.PageInfo{PageCursors: domain.PageCursors{Next: cntrl.cursors.issue(next, cursorDirectionNext, filters)}})
+}
+
+func (cntrl rideCntrl) getRideStats(c echo.Context) error {
+	var query domain.StatsQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	if err := query.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
+	}
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	stats, err := cntrl.rideRepo.Stats(ctx, query)
+	if err != nil {
+		return repositoryError(err)
+	}
+	return c.JSON(http.StatusOK, rideStatsEnvelope{GroupBy: query.GroupBy, Stats: stats})
+}
+
+func (cntrl rideCntrl) exportRides(c echo.Context) error {
+	var page domain.Pagination
+	if err := c.Bind(&page); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	if err := page.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid query: %s", err))
 	}
-	return c.JSON(http.StatusOK, ridesEnvelope{Rides: rides, Cursor: cursor})
+	// NOTE: Once the first row is written the status can't be changed anymore,
+	// errors after that point only cut the response short. The export isn't
+	// bound by the query timeout as it runs for as long as the client reads.
+	if err := cntrl.rideRepo.Export(c.Request().Context(), page, csvResponse{c}); err != nil {
+		return repositoryError(err)
+	}
+	return nil
 }
 
 func (cntrl rideCntrl) getRide(c echo.Context) error {
-	id := c.Param("id")
-	rideID, err := strconv.ParseInt(id, 10, 64)
+	rideID, err := parseID(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid ID: %s", err))
+		return err
+	}
+	var query rideQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
 	}
-	ride, err := cntrl.rideRepo.SelectByID(rideID)
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	ride, err := cntrl.rideRepo.SelectByID(ctx, rideID, query.IncludeDeleted)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Internal server error: %s", err))
+		return repositoryError(err)
 	}
 	if ride == nil {
-		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %s", id))
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", rideID))
+	}
+	return respondRide(c, http.StatusOK, *ride)
+}
+
+func (cntrl rideCntrl) updateRide(c echo.Context) error {
+	rideID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	var ride domain.Ride
+	if err := c.Bind(&ride); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	ride.ID = rideID
+
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	current, err := cntrl.rideRepo.SelectByID(ctx, rideID, false)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if current == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", rideID))
+	}
+	return cntrl.saveRide(ctx, c, *current, ride)
+}
+
+func (cntrl rideCntrl) patchRide(c echo.Context) error {
+	rideID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(contentType, mimeApplicationMergePatchJSON) && !strings.HasPrefix(contentType, echo.MIMEApplicationJSON) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported content type: %s", contentType))
+	}
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	ride, err := cntrl.rideRepo.SelectByID(ctx, rideID, false)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if ride == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", rideID))
+	}
+	patched, err := applyMergePatch(*ride, patch)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	patched.ID = rideID
+	return cntrl.saveRide(ctx, c, *ride, patched)
+}
+
+// saveRide overwrites the current ride with the given one. Its status can only
+// be left out or kept as it is, it's changed through transitions instead. A
+// ride saved without a riderId keeps its rider, it's only linked to another one
+// by ID rather than by a new name. Likewise a ride saved without a vehicleId
+// keeps its vehicle rather than going back to a driverVehicle of its own.
+func (cntrl rideCntrl) saveRide(ctx context.Context, c echo.Context, current, ride domain.Ride) error {
+	if ride.Status != "" && ride.Status != current.Status {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: status can't be changed from %s to %s, use POST /rides/%d/transitions instead", current.Status, ride.Status, ride.ID))
+	}
+	if ride.RiderID == nil {
+		ride.RiderID = current.RiderID
+	}
+	if ride.VehicleID == nil {
+		ride.VehicleID = current.VehicleID
+	}
+	if err := cntrl.checkRide(ctx, &current, &ride); err != nil {
+		return checkRideError(err)
+	}
+	if err := cntrl.linkRider(ctx, &ride); err != nil {
+		return repositoryError(err)
+	}
+	updated, err := cntrl.rideRepo.Update(ctx, ride)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if updated == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", ride.ID))
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (cntrl rideCntrl) deleteRide(c echo.Context) error {
+	rideID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	deleted, err := cntrl.rideRepo.Delete(ctx, rideID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if !deleted {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", rideID))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (cntrl rideCntrl) restoreRide(c echo.Context) error {
+	rideID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	ride, err := cntrl.rideRepo.Restore(ctx, rideID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if ride == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", rideID))
+	}
+	return c.JSON(http.StatusOK, ride)
+}
+
+func (cntrl rideCntrl) transitionRide(c echo.Context) error {
+	rideID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	var req transitionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	if !req.Status.Valid() {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s is not a valid status", req.Status))
+	}
+	ctx, cancel := cntrl.withQueryTimeout(c.Request().Context())
+	defer cancel()
+	ride, err := cntrl.rideRepo.UpdateStatus(ctx, rideID, req.Status)
+	var transitionErr domain.RideTransitionError
+	if errors.As(err, &transitionErr) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Conflict: %s", err))
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+	if ride == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find ride with ID %d", rideID))
 	}
 	return c.JSON(http.StatusOK, ride)
 }
+
+// newRide fills in what the server assigns to a ride when it's created, every
+// ride starts out requested and only moves on through its transitions.
+func (cntrl rideCntrl) newRide(ride domain.Ride) domain.Ride {
+	ride.Status = domain.RideStatusRequested
+	return cntrl.newImportedRide(ride)
+}
+
+// newImportedRide is newRide for rides imported from elsewhere, which keep
+// their status as they may already be under way or done. Only a missing status
+// defaults to requested.
+func (cntrl rideCntrl) newImportedRide(ride domain.Ride) domain.Ride {
+	if ride.Status == "" {
+		ride.Status = domain.RideStatusRequested
+	}
+	now := cntrl.now().UTC()
+	ride.CreatedAt = now
+	ride.UpdatedAt = now
+	ride.DistanceMeters = ride.Distance()
+	ride.BearingDegrees = ride.Bearing()
+	return ride
+}
+
+// checkRide assigns what the ride references and validates it, current is the
+// stored ride when it's being edited and nil when it's created.
+func (cntrl rideCntrl) checkRide(ctx context.Context, current, ride *domain.Ride) error {
+	vehicles := cntrl.vehicleLookup(ctx)
+	if err := cntrl.assignReferences(ctx, current, ride, vehicles); err != nil {
+		return err
+	}
+	return ride.Validate(current, vehicles)
+}
+
+// vehicleLookup looks up vehicles in the repository for a single ride, a
+// vehicle is only selected once while the ride is checked.
+func (cntrl rideCntrl) vehicleLookup(ctx context.Context) domain.VehicleLookup {
+	vehicles := map[int64]*domain.Vehicle{}
+	return func(id int64) (*domain.Vehicle, error) {
+		if vehicle, ok := vehicles[id]; ok {
+			return vehicle, nil
+		}
+		vehicle, err := cntrl.vehicleRepo.SelectByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		vehicles[id] = vehicle
+		return vehicle, nil
+	}
+}
+
+// assignReferences copies the name and vehicle of the driver, the name of the
+// rider and the description of the vehicle the ride references into it, what
+// isn't referenced is left as it is. It returns an unknownReferenceError when
+// there is no such driver or rider, and a riderNameError when an edited ride
+// keeps its rider but is given another name than the rider's. A deleted driver
+// is only rejected as a new reference, current is the stored ride when it's
+// being edited and nil when it's created. Whether the vehicle can be
+// referenced is up to Ride.Validate.
+func (cntrl rideCntrl) assignReferences(ctx context.Context, current, ride *domain.Ride, vehicles domain.VehicleLookup) error {
+	if ride.DriverID != nil {
+		kept := current != nil && current.DriverID != nil && *current.DriverID == *ride.DriverID
+		driver, err := cntrl.driverRepo.SelectByID(ctx, *ride.DriverID, kept)
+		if err != nil {
+			return err
+		}
+		if driver == nil {
+			return unknownReferenceError{name: "driver", id: *ride.DriverID}
+		}
+		ride.DriverName = driver.Name
+		ride.DriverVehicle = driver.Vehicle
+	}
+	if ride.RiderID != nil {
+		rider, err := cntrl.riderRepo.SelectByID(ctx, *ride.RiderID)
+		if err != nil {
+			return err
+		}
+		if rider == nil {
+			return unknownReferenceError{name: "rider", id: *ride.RiderID}
+		}
+		kept := current != nil && current.RiderID != nil && *current.RiderID == *ride.RiderID
+		if kept && ride.RiderName != "" && ride.RiderName != current.RiderName && ride.RiderName != rider.Name {
+			return riderNameError{id: rider.ID, name: rider.Name, riderName: ride.RiderName}
+		}
+		ride.RiderName = rider.Name
+	}
+	if ride.VehicleID != nil {
+		vehicle, err := vehicles(*ride.VehicleID)
+		if err != nil {
+			return err
+		}
+		if vehicle != nil {
+			ride.DriverVehicle = vehicle.Description()
+		}
+	}
+	return nil
+}
+
+// linkRider links a ride given only the name of its rider to the oldest rider
+// with the name, the same as the rides created before riders were. A rider is
+// created for a name that isn't known yet. The ride is expected to be valid so
+// no rider is created for a ride that is rejected anyway.
+func (cntrl rideCntrl) linkRider(ctx context.Context, ride *domain.Ride) error {
+	if ride.RiderID != nil {
+		return nil
+	}
+	rider, err := cntrl.riderRepo.SelectByName(ctx, ride.RiderName)
+	if err != nil {
+		return err
+	}
+	if rider != nil {
+		ride.RiderID = &rider.ID
+		return nil
+	}
+	now := cntrl.now().UTC()
+	id, err := cntrl.riderRepo.Insert(ctx, domain.Rider{Name: ride.RiderName, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		return err
+	}
+	ride.RiderID = &id
+	return nil
+}
+
+// checkRideError responds to the error returned by checkRide, invalid rides
+// and references are part of an invalid request body.
+func checkRideError(err error) error {
+	if invalidRide(err) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+	return repositoryError(err)
+}
+
+// invalidRide tells whether err is returned by checkRide for the ride or what
+// it references rather than by a repository.
+func invalidRide(err error) bool {
+	var unknown unknownReferenceError
+	var riderName riderNameError
+	var invalid domain.InvalidRideError
+	return errors.As(err, &unknown) || errors.As(err, &riderName) || errors.As(err, &invalid)
+}
+
+func (e unknownReferenceError) Error() string {
+	return fmt.Sprintf("can't find %s with ID %d", e.name, e.id)
+}
+
+func (e riderNameError) Error() string {
+	return fmt.Sprintf("riderName %s doesn't match %s, the name of rider with ID %d, rename the rider or set riderId to link the ride to another one", e.riderName, e.name, e.id)
+}
+
+// respondRides writes the rides in the representation the client accepts along
+// with the opaque cursors of the pages around them and the included counts.
+func respondRides(c echo.Context, rides []domain.Ride, info domain.PageInfo) error {
+	if !acceptsGeoJSON(c) {
+		return c.JSON(http.StatusOK, ridesEnvelope{
+			Rides:      rides,
+			NextCursor: info.Next,
+			PrevCursor: info.Prev,
+			Total:      info.Total,
+			Facets:     info.Facets,
+		})
+	}
+	collection, err := newGeoJSONFeatureCollection(rides, info)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Internal server error: %s", err))
+	}
+	c.Response().Header().Set(echo.HeaderContentType, mimeApplicationGeoJSON)
+	return c.JSON(http.StatusOK, collection)
+}
+
+// setPageLinks links the pages around the current one and the first page with
+// RFC 8288 Link headers, keeping every query param except the cursors.
+func setPageLinks(c echo.Context, cursors domain.PageCursors) {
+	link := func(rel, param, cursor string) string {
+		query := c.Request().URL.Query()
+		query.Del("after")
+		query.Del("before")
+		if param != "" {
+			query.Set(param, cursor)
+		}
+		target := url.URL{Scheme: c.Scheme(), Host: c.Request().Host, Path: c.Request().URL.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+	}
+
+	links := []string{}
+	if cursors.Next != "" {
+		links = append(links, link("next", "after", cursors.Next))
+	}
+	if cursors.Prev != "" {
+		links = append(links, link("prev", "before", cursors.Prev))
+	}
+	links = append(links, link("first", "", ""))
+	c.Response().Header().Set("Link", strings.Join(links, ", "))
+}
+
+// respondRide writes the ride in the representation the client accepts.
+func respondRide(c echo.Context, code int, ride domain.Ride) error {
+	if !acceptsGeoJSON(c) {
+		return c.JSON(code, ride)
+	}
+	feature, err := newGeoJSONFeature(ride)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Internal server error: %s", err))
+	}
+	c.Response().Header().Set(echo.HeaderContentType, mimeApplicationGeoJSON)
+	return c.JSON(code, feature)
+}
+
+// withQueryTimeout bounds the queries made while handling a request, they are
+// also cancelled as soon as ctx is.
+func (cntrl rideCntrl) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withQueryTimeout(ctx, cntrl.queryTimeout)
+}
+
+// withQueryTimeout cancels the queries made with the context after the
+// timeout, zero means they are only cancelled along with ctx.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// repositoryError turns an error returned by the repository into a response,
+// queries that ran out of time are reported as the service being unavailable
+// and a taken plate number as a conflict.
+func repositoryError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, fmt.Sprintf("Query timed out: %s", err))
+	}
+	if errors.Is(err, domain.ErrDuplicatePlateNumber) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Conflict: %s", err))
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Internal server error: %s", err))
+}
+
+func parseID(c echo.Context) (int64, error) {
+	rideID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid ID: %s", err))
+	}
+	return rideID, nil
+}
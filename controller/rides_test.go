@@ -1,11 +1,16 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	domain "github.com/hawarir/backend-coding-test"
@@ -24,7 +29,67 @@ func newRideController(t *testing.T, fn setupMockRepo) (rideCntrl, *gomock.Contr
 		fn(rideRepo)
 	}
 
-	return rideCntrl{rideRepo: rideRepo}, mockCtrl
+	// NOTE: Every rider is known by name, tests of linking rides to riders
+	// replace the rider repository.
+	riderRepo := mock.NewMockRiderRepository(mockCtrl)
+	riderRepo.EXPECT().
+		SelectByName(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, name string) (*domain.Rider, error) {
+			return &domain.Rider{ID: testRiderID, Name: name}, nil
+		}).
+		AnyTimes()
+
+	// NOTE: Every vehicle is registered, tests of referencing vehicles replace
+	// the vehicle repository.
+	vehicleRepo := mock.NewMockVehicleRepository(mockCtrl)
+	vehicleRepo.EXPECT().
+		SelectByID(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id int64) (*domain.Vehicle, error) {
+			vehicle := testVehicle(id)
+			return &vehicle, nil
+		}).
+		AnyTimes()
+
+	return rideCntrl{rideRepo: rideRepo, riderRepo: riderRepo, vehicleRepo: vehicleRepo, now: testTime, maxImportSize: testMaxImportSize, cursors: testCursorCipher()}, mockCtrl
+}
+
+// testRiderID is the ID of the rider every ride is linked to by the name of
+// its rider.
+var testRiderID = int64(99)
+
+// testVehicleID is the ID of the registered vehicle new rides reference.
+var testVehicleID = int64(3)
+
+// testMaxImportSize fits every import of the tests that isn't meant to be too
+// large.
+const testMaxImportSize = 1 << 20
+
+// testCursorCipher seals every cursor with a zero nonce, so the cursors handed
+// out by the controllers can be compared with the expected ones.
+func testCursorCipher() cursorCipher {
+	cipher := newCursorCipher([]byte("test-cursor-key"))
+	cipher.nonces = zeroReader{}
+	return cipher
+}
+
+// zeroReader reads an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// testCursor returns the opaque cursor the controller hands out for the
+// repository position.
+func testCursor(position, direction string, filters url.Values) string {
+	return testCursorCipher().issue(position, direction, filters)
+}
+
+func testTime() time.Time {
+	return time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC)
 }
 
 func TestRideController_addRide(t *testing.T) {
@@ -46,45 +111,85 @@ func TestRideController_addRide(t *testing.T) {
 			testName:    "When request is invalid, return status code 422 with error message",
 			requestBody: `{"startLatitude": -100, "startLongitude": -200, "endLatitude": 100, "endLongitude": 200, "riderName": "", "driverName": "", "driverVehicle": ""}`,
 			statusCode:  http.StatusUnprocessableEntity,
-			expectedErr: "code=422, message=Invalid request body: -100.000000 is not a valid latitude value; 100.000000 is not a valid latitude value; -200.000000 is not a valid longitude value; 200.000000 is not a valid longitude value; riderName can't be empty; driverName can't be empty; driverVehicle can't be empty",
+			expectedErr: "code=422, message=Invalid request body: -100.000000 is not a valid latitude value; 100.000000 is not a valid latitude value; -200.000000 is not a valid longitude value; 200.000000 is not a valid longitude value; riderName can't be empty; driverName can't be empty; driverVehicle can't be empty; vehicleId can't be empty",
+		},
+		{
+			testName:    "When ride ends before it starts, return status code 422 with error message",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 3, "startedAt": "2021-04-01T10:00:00Z", "endedAt": "2021-04-01T09:00:00Z"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: endedAt can't be before startedAt",
 		},
 		{
 			testName:    "When repository returns error, return status code 500 with error message",
-			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 3}`,
 			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
 				mockRepo.EXPECT().
-					Insert(domain.Ride{
+					Insert(gomock.Any(), domain.Ride{
 						StartLatitude:  90,
 						StartLongitude: 180,
 						EndLatitude:    90,
 						EndLongitude:   180,
 						RiderName:      "John Doe",
 						DriverName:     "Driver",
-						DriverVehicle:  "Car",
+						DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						RiderID:        &testRiderID,
+						VehicleID:      &testVehicleID,
 					}).
 					Return(int64(-1), errors.New("Insert error"))
 			},
 			statusCode:  http.StatusInternalServerError,
 			expectedErr: "code=500, message=Internal server error: Insert error",
 		},
+		{
+			testName:    "When status is given, create the ride as requested",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 3, "status": "completed"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Ride{
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						RiderID:        &testRiderID,
+						VehicleID:      &testVehicleID,
+					}).
+					Return(int64(1), nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":99,\"vehicleId\":3}\n",
+		},
 		{
 			testName:    "When successful, return status code 201 with response body",
-			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 3}`,
 			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
 				mockRepo.EXPECT().
-					Insert(domain.Ride{
+					Insert(gomock.Any(), domain.Ride{
 						StartLatitude:  90,
 						StartLongitude: 180,
 						EndLatitude:    90,
 						EndLongitude:   180,
 						RiderName:      "John Doe",
 						DriverName:     "Driver",
-						DriverVehicle:  "Car",
+						DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						RiderID:        &testRiderID,
+						VehicleID:      &testVehicleID,
 					}).
 					Return(int64(1), nil)
 			},
 			statusCode:   http.StatusCreated,
-			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\"}\n",
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":99,\"vehicleId\":3}\n",
 		},
 	}
 
@@ -115,90 +220,147 @@ func TestRideController_addRide(t *testing.T) {
 	}
 }
 
-func TestRideController_getAllRides(t *testing.T) {
+func TestRideController_addRideWithDriver(t *testing.T) {
+	driverID := int64(7)
 	testCases := []struct {
-		testName      string
-		setupMockRepo setupMockRepo
-		queryParams   string
-		statusCode    int
-		responseBody  string
-		expectedErr   string
+		testName            string
+		setupMockDriverRepo setupMockDriverRepo
+		setupMockRepo       setupMockRepo
+		statusCode          int
+		responseBody        string
+		expectedErr         string
 	}{
 		{
-			testName:    "When failed to bind query params, return status code 400 with error message",
-			queryParams: "?limit=not-number",
-			statusCode:  http.StatusBadRequest,
-			expectedErr: "code=400, message=Bad request: code=400, message=strconv.ParseUint: parsing \"not-number\": invalid syntax, internal=strconv.ParseUint: parsing \"not-number\": invalid syntax",
+			testName: "When driver doesn't exist, return status code 422 with error message",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), driverID, false).Return(nil, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: can't find driver with ID 7",
 		},
 		{
-			testName: "When repository returns error, return status code 500 with error message",
-			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
-				mockRepo.EXPECT().SelectAll(domain.Pagination{}).
-					Return(nil, "", errors.New("Select All error"))
+			testName: "When driver repository returns error, return status code 500 with error message",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), driverID, false).Return(nil, errors.New("Select By ID error"))
 			},
 			statusCode:  http.StatusInternalServerError,
-			expectedErr: "code=500, message=Internal server error: Select All error",
+			expectedErr: "code=500, message=Internal server error: Select By ID error",
 		},
 		{
-			testName: "When repository returns empty result, return status code 200 with empty array in response body",
+			testName: "When driver exists, copy its name into the ride",
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), driverID, false).
+					Return(&domain.Driver{ID: driverID, Name: "Driver", Vehicle: "Car"}, nil)
+			},
 			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
-				mockRepo.EXPECT().SelectAll(domain.Pagination{}).
-					Return([]domain.Ride{}, "", nil)
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Ride{
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						DriverID:       &driverID,
+						RiderID:        &testRiderID,
+						VehicleID:      &testVehicleID,
+					}).
+					Return(int64(1), nil)
 			},
-			statusCode:   http.StatusOK,
-			responseBody: "{\"rides\":[],\"cursor\":\"\"}\n",
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"driverId\":7,\"riderId\":99,\"vehicleId\":3}\n",
 		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			body := `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Someone else", "driverId": 7, "vehicleId": 3}`
+			req := httptest.NewRequest(http.MethodPost, "/rides", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			drivers, driverMock := newDriverController(t, tc.setupMockDriverRepo)
+			defer driverMock.Finish()
+			cntrl.driverRepo = drivers.driverRepo
+
+			err := cntrl.addRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_addRideWithRider(t *testing.T) {
+	riderID := int64(7)
+	testCases := []struct {
+		testName           string
+		setupMockRiderRepo setupMockRiderRepo
+		setupMockRepo      setupMockRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
 		{
-			testName: "When repository returns results, return status code 200 with the results as array",
-			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
-				mockRepo.EXPECT().SelectAll(domain.Pagination{}).
-					Return([]domain.Ride{
-						{
-							ID:             1,
-							StartLatitude:  90,
-							StartLongitude: 180,
-							EndLatitude:    90,
-							EndLongitude:   180,
-							RiderName:      "John Doe",
-							DriverName:     "Driver",
-							DriverVehicle:  "Car",
-						},
-					}, "", nil)
+			testName: "When rider doesn't exist, return status code 422 with error message",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), riderID).Return(nil, nil)
 			},
-			statusCode:   http.StatusOK,
-			responseBody: "{\"rides\":[{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\"}],\"cursor\":\"\"}\n",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: can't find rider with ID 7",
 		},
 		{
-			testName: "When provided query params, use it as arguments",
+			testName: "When rider exists, copy its name into the ride",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), riderID).
+					Return(&domain.Rider{ID: riderID, Name: "John Doe"}, nil)
+			},
 			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
-				mockRepo.EXPECT().SelectAll(domain.Pagination{Cursor: "3", Limit: 1}).
-					Return([]domain.Ride{
-						{
-							ID:             3,
-							StartLatitude:  90,
-							StartLongitude: 180,
-							EndLatitude:    90,
-							EndLongitude:   180,
-							RiderName:      "John Doe",
-							DriverName:     "Driver",
-							DriverVehicle:  "Car",
-						},
-					}, "2", nil)
+				mockRepo.EXPECT().
+					Insert(gomock.Any(), domain.Ride{
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						RiderID:        &riderID,
+						VehicleID:      &testVehicleID,
+					}).
+					Return(int64(1), nil)
 			},
-			queryParams:  "?cursor=3&limit=1",
-			statusCode:   http.StatusOK,
-			responseBody: "{\"rides\":[{\"id\":3,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\"}],\"cursor\":\"2\"}\n",
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":7,\"vehicleId\":3}\n",
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
-			path := "/rides"
-			if tc.queryParams != "" {
-				path += tc.queryParams
-			}
-
-			req := httptest.NewRequest(http.MethodGet, path, nil)
+			body := `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "Someone else", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 3, "riderId": 7}`
+			req := httptest.NewRequest(http.MethodPost, "/rides", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
 
 			e := echo.New()
@@ -206,8 +368,11 @@ func TestRideController_getAllRides(t *testing.T) {
 
 			cntrl, mock := newRideController(t, tc.setupMockRepo)
 			defer mock.Finish()
+			riders, riderMock := newRiderController(t, tc.setupMockRiderRepo)
+			defer riderMock.Finish()
+			cntrl.riderRepo = riders.riderRepo
 
-			err := cntrl.getAllRides(c)
+			err := cntrl.addRide(c)
 			if tc.expectedErr != "" {
 				httpErr, ok := err.(*echo.HTTPError)
 				if ok {
@@ -222,80 +387,2299 @@ func TestRideController_getAllRides(t *testing.T) {
 	}
 }
 
-func TestRideController_getRide(t *testing.T) {
+func TestRideController_addRideByRiderName(t *testing.T) {
+	riderID := int64(7)
+	ride := func() domain.Ride {
+		return domain.Ride{
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      "John Doe",
+			DriverName:     "Driver",
+			DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+			RiderID:        &riderID,
+			VehicleID:      &testVehicleID,
+		}
+	}
 	testCases := []struct {
-		testName      string
-		paramID       string
-		setupMockRepo setupMockRepo
-		statusCode    int
-		responseBody  string
-		expectedErr   string
+		testName           string
+		setupMockRiderRepo setupMockRiderRepo
+		setupMockRepo      setupMockRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
 	}{
-		{
-			testName:    "When ID is not an integer, return status code 422 with error message",
-			paramID:     "not-a-string",
-			statusCode:  http.StatusUnprocessableEntity,
-			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
-		},
 		{
 			testName: "When repository returns error, return status code 500 with error message",
-			paramID:  "1",
-			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
-				mockRepo.EXPECT().
-					SelectByID(int64(1)).
-					Return(nil, errors.New("Select By ID error"))
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByName(gomock.Any(), "John Doe").Return(nil, errors.New("Select error"))
 			},
 			statusCode:  http.StatusInternalServerError,
-			expectedErr: "code=500, message=Internal server error: Select By ID error",
+			expectedErr: "code=500, message=Internal server error: Select error",
 		},
 		{
-			testName: "When repository returns no result, return status code 404 with error message",
-			paramID:  "1",
+			testName: "When rider with the name exists, link the ride to it",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().
+					SelectByName(gomock.Any(), "John Doe").
+					Return(&domain.Rider{ID: riderID, Name: "John Doe"}, nil)
+			},
 			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Insert(gomock.Any(), ride()).Return(int64(1), nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":7,\"vehicleId\":3}\n",
+		},
+		{
+			testName: "When no rider has the name, create one and link the ride to it",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByName(gomock.Any(), "John Doe").Return(nil, nil)
 				mockRepo.EXPECT().
-					SelectByID(int64(1)).
-					Return(nil, nil)
+					Insert(gomock.Any(), domain.Rider{Name: "John Doe", CreatedAt: testTime(), UpdatedAt: testTime()}).
+					Return(riderID, nil)
 			},
-			statusCode:  http.StatusNotFound,
-			expectedErr: "code=404, message=Can't find ride with ID 1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Insert(gomock.Any(), ride()).Return(int64(1), nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":7,\"vehicleId\":3}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			body := `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 3}`
+			req := httptest.NewRequest(http.MethodPost, "/rides", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			riders, riderMock := newRiderController(t, tc.setupMockRiderRepo)
+			defer riderMock.Finish()
+			cntrl.riderRepo = riders.riderRepo
+
+			err := cntrl.addRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_addRideWithVehicle(t *testing.T) {
+	vehicleID := int64(7)
+	vehicle := testVehicle(vehicleID)
+	deactivated := testVehicle(vehicleID)
+	deactivatedAt := testTime()
+	deactivated.DeactivatedAt = &deactivatedAt
+	testCases := []struct {
+		testName             string
+		setupMockVehicleRepo setupMockVehicleRepo
+		setupMockRepo        setupMockRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName: "When vehicle doesn't exist, return status code 422 with error message",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), vehicleID).Return(nil, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: can't find vehicle with ID 7",
 		},
 		{
-			testName: "When successful, return status code 200 with result",
-			paramID:  "1",
+			testName: "When vehicle is deactivated, return status code 422 with error message",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), vehicleID).Return(&deactivated, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: vehicle with ID 7 is deactivated",
+		},
+		{
+			testName: "When vehicle exists, copy its description into the ride",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), vehicleID).Return(&vehicle, nil)
+			},
 			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
 				mockRepo.EXPECT().
-					SelectByID(int64(1)).
-					Return(&domain.Ride{
-						ID:             1,
+					Insert(gomock.Any(), domain.Ride{
 						StartLatitude:  90,
 						StartLongitude: 180,
 						EndLatitude:    90,
 						EndLongitude:   180,
 						RiderName:      "John Doe",
 						DriverName:     "Driver",
-						DriverVehicle:  "Car",
-					}, nil)
+						DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						VehicleID:      &vehicleID,
+						RiderID:        &testRiderID,
+					}).
+					Return(int64(1), nil)
 			},
-			statusCode:   http.StatusOK,
-			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\"}\n",
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":99,\"vehicleId\":7}\n",
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			body := `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "vehicleId": 7}`
+			req := httptest.NewRequest(http.MethodPost, "/rides", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
 
 			e := echo.New()
 			c := e.NewContext(req, rec)
-			c.SetPath("/rides/:id")
-			c.SetParamNames("id")
-			c.SetParamValues(tc.paramID)
 
 			cntrl, mock := newRideController(t, tc.setupMockRepo)
 			defer mock.Finish()
+			vehicles, vehicleMock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer vehicleMock.Finish()
+			cntrl.vehicleRepo = vehicles.vehicleRepo
 
-			err := cntrl.getRide(c)
+			err := cntrl.addRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_importRides(t *testing.T) {
+	importedRide := func(riderName string) domain.Ride {
+		return domain.Ride{
+			RiderName:     riderName,
+			DriverName:    "Driver",
+			DriverVehicle: "Toyota Avanza (B 1234 XYZ)",
+			Status:        domain.RideStatusRequested,
+			CreatedAt:     testTime(),
+			UpdatedAt:     testTime(),
+			RiderID:       &testRiderID,
+			VehicleID:     &testVehicleID,
+		}
+	}
+	csvHeader := "id,startLat,startLong,endLat,endLong,riderName,driverName,driverVehicle,status,vehicleId\n"
+	ndjsonLine := func(riderName string) string {
+		return fmt.Sprintf("{\"startLatitude\":0,\"startLongitude\":0,\"endLatitude\":0,\"endLongitude\":0,\"riderName\":%q,\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"vehicleId\":3}\n", riderName)
+	}
+
+	testCases := []struct {
+		testName      string
+		contentType   string
+		requestBody   string
+		unknownLength bool
+		setupMockRepo setupMockRepo
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When content type is not supported, return status code 415 with error message",
+			contentType: echo.MIMEApplicationJSON,
+			statusCode:  http.StatusUnsupportedMediaType,
+			expectedErr: "code=415, message=Unsupported content type: application/json",
+		},
+		{
+			testName:    "When CSV header misses a column, return status code 400 with error message",
+			contentType: "text/csv",
+			requestBody: "startLat,startLong,endLat,endLong,riderName,driverName\n",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: missing driverVehicle column",
+		},
+		{
+			testName:    "When body is larger than the limit, return status code 413 with error message",
+			contentType: "application/x-ndjson",
+			requestBody: strings.Repeat(ndjsonLine("John Doe"), testMaxImportSize/100),
+			statusCode:  http.StatusRequestEntityTooLarge,
+			expectedErr: "code=413, message=Request body too large: imports are limited to 1048576 bytes",
+		},
+		{
+			testName:      "When CSV header goes past the limit, return status code 413 with error message",
+			contentType:   "text/csv",
+			requestBody:   strings.Repeat("startLat,", testMaxImportSize/8),
+			unknownLength: true,
+			statusCode:    http.StatusRequestEntityTooLarge,
+			expectedErr:   "code=413, message=Request body too large: imports are limited to 1048576 bytes",
+		},
+		{
+			testName:      "When body goes past the limit, insert the rides read so far and return status code 413 with the report",
+			contentType:   "application/x-ndjson",
+			requestBody:   ndjsonLine("John Doe") + "{\"riderName\":\"" + strings.Repeat("a", testMaxImportSize) + "\"}\n" + ndjsonLine("Jane Doe"),
+			unknownLength: true,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					InsertBatch(gomock.Any(), []domain.Ride{importedRide("John Doe")}).
+					Return([]int64{1}, nil)
+			},
+			statusCode:   http.StatusRequestEntityTooLarge,
+			responseBody: "{\"accepted\":[{\"line\":1,\"id\":1}],\"rejected\":[{\"line\":2,\"reason\":\"Request body too large: imports are limited to 1048576 bytes\"}]}\n",
+		},
+		{
+			testName:    "When importing CSV, insert valid rows and report the rest",
+			contentType: "text/csv; charset=utf-8",
+			requestBody: csvHeader +
+				"7,0,0,0,0,John Doe,Driver,Car,,3\n" +
+				"8,north,0,0,0,John Doe,Driver,Car,requested,3\n" +
+				"9,0,0,0,0,,Driver,Car,finished,3\n" +
+				"10,0,0,0\n" +
+				"11,0,0,0,0,Jane Doe,Driver,Car,requested,3\n" +
+				"12,0,0,0,0,Jane Doe,Driver,Car,requested,\n",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					InsertBatch(gomock.Any(), []domain.Ride{importedRide("John Doe"), importedRide("Jane Doe")}).
+					Return([]int64{1, 2}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"accepted\":[{\"line\":2,\"id\":1},{\"line\":6,\"id\":2}],\"rejected\":[{\"line\":3,\"reason\":\"Malformed line: startLat is not a valid number\"},{\"line\":4,\"reason\":\"Invalid ride: riderName can't be empty; finished is not a valid status\"},{\"line\":5,\"reason\":\"Malformed line: record on line 5: wrong number of fields\"},{\"line\":7,\"reason\":\"Invalid ride: vehicleId can't be empty\"}]}\n",
+		},
+		{
+			testName:    "When importing NDJSON, skip blank lines and report rejected lines",
+			contentType: "application/x-ndjson",
+			requestBody: ndjsonLine("John Doe") + "\n{\"riderName\":\n" + ndjsonLine(""),
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					InsertBatch(gomock.Any(), []domain.Ride{importedRide("John Doe")}).
+					Return([]int64{1}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"accepted\":[{\"line\":1,\"id\":1}],\"rejected\":[{\"line\":3,\"reason\":\"Malformed line: unexpected end of JSON input\"},{\"line\":4,\"reason\":\"Invalid ride: riderName can't be empty\"}]}\n",
+		},
+		{
+			testName:    "When repository returns error, reject every line of the batch",
+			contentType: "application/x-ndjson",
+			requestBody: ndjsonLine("John Doe") + ndjsonLine("Jane Doe"),
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					InsertBatch(gomock.Any(), []domain.Ride{importedRide("John Doe"), importedRide("Jane Doe")}).
+					Return(nil, errors.New("Insert Batch error"))
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"accepted\":[],\"rejected\":[{\"line\":1,\"reason\":\"Internal server error: Insert Batch error\"},{\"line\":2,\"reason\":\"Internal server error: Insert Batch error\"}]}\n",
+		},
+		{
+			testName:    "When there are more rides than fit in a batch, insert them in several batches",
+			contentType: "application/x-ndjson",
+			requestBody: strings.Repeat(ndjsonLine("John Doe"), importBatchSize+1),
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				ids := make([]int64, importBatchSize)
+				for i := range ids {
+					ids[i] = int64(i + 1)
+				}
+				gomock.InOrder(
+					mockRepo.EXPECT().InsertBatch(gomock.Any(), gomock.Len(importBatchSize)).Return(ids, nil),
+					mockRepo.EXPECT().InsertBatch(gomock.Any(), gomock.Len(1)).Return(nil, errors.New("Insert Batch error")),
+				)
+			},
+			statusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/rides/import", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			if tc.unknownLength {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.importRides(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				if tc.responseBody != "" {
+					assert.Equal(t, tc.responseBody, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRideController_getAllRides(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		setupMockRepo setupMockRepo
+		queryParams   string
+		accept        string
+		statusCode    int
+		responseBody  string
+		link          string
+		expectedErr   string
+	}{
+		{
+			testName:    "When failed to bind query params, return status code 400 with error message",
+			queryParams: "?limit=not-number",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Bad request: code=400, message=strconv.ParseUint: parsing \"not-number\": invalid syntax, internal=strconv.ParseUint: parsing \"not-number\": invalid syntax",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{}).
+					Return(nil, domain.PageInfo{}, errors.New("Select All error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Select All error",
+		},
+		{
+			testName: "When repository returns empty result, return status code 200 with empty array in response body",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName: "When repository returns results, return status code 200 with the results as array",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{}).
+					Return([]domain.Ride{
+						{
+							ID:             1,
+							StartLatitude:  90,
+							StartLongitude: 180,
+							EndLatitude:    90,
+							EndLongitude:   180,
+							RiderName:      "John Doe",
+							DriverName:     "Driver",
+							DriverVehicle:  "Car",
+							Status:         domain.RideStatusRequested,
+							CreatedAt:      testTime(),
+							UpdatedAt:      testTime(),
+						},
+					}, domain.PageInfo{}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName: "When provided query params, use it as arguments",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "4", Limit: 1}).
+					Return([]domain.Ride{
+						{
+							ID:             3,
+							StartLatitude:  90,
+							StartLongitude: 180,
+							EndLatitude:    90,
+							EndLongitude:   180,
+							RiderName:      "John Doe",
+							DriverName:     "Driver",
+							DriverVehicle:  "Car",
+							Status:         domain.RideStatusRequested,
+							CreatedAt:      testTime(),
+							UpdatedAt:      testTime(),
+						},
+					}, domain.PageInfo{PageCursors: domain.PageCursors{Next: "3", Prev: "3"}}, nil)
+			},
+			queryParams:  "?after=" + testCursor("4", cursorDirectionNext, url.Values{}) + "&limit=1",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[{\"id\":3,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}],\"nextCursor\":\"" + testCursor("3", cursorDirectionNext, url.Values{}) + "\",\"prevCursor\":\"" + testCursor("3", cursorDirectionPrev, url.Values{}) + "\"}\n",
+			link: "<http://example.com/rides?after=" + testCursor("3", cursorDirectionNext, url.Values{}) + "&limit=1>; rel=\"next\", " +
+				"<http://example.com/rides?before=" + testCursor("3", cursorDirectionPrev, url.Values{}) + "&limit=1>; rel=\"prev\", " +
+				"<http://example.com/rides?limit=1>; rel=\"first\"",
+		},
+		{
+			testName: "When paged back, use the position of the previous cursor",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{Before: "2", Limit: 1, MinDistance: 1000}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			queryParams:  "?before=" + testCursor("2", cursorDirectionPrev, url.Values{"minDistance": {"1000"}}) + "&limit=1&minDistance=1000",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+			link:         "<http://example.com/rides?limit=1&minDistance=1000>; rel=\"first\"",
+		},
+		{
+			testName:    "When next cursor is used to page back, return status code 400 with error message",
+			queryParams: "?before=" + testCursor("2", cursorDirectionNext, url.Values{}) + "&limit=1",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor pages next instead of prev",
+		},
+		{
+			testName:    "When paged both ways, return status code 422 with error message",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, url.Values{}) + "&before=" + testCursor("2", cursorDirectionPrev, url.Values{}),
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: after and before can't be used together",
+		},
+		{
+			testName:    "When cursor is malformed, return status code 400 with error message",
+			queryParams: "?after=3&limit=1",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor is malformed",
+		},
+		{
+			testName:    "When cursor is tampered with, return status code 400 with error message",
+			queryParams: "?after=B" + testCursor("4", cursorDirectionNext, url.Values{})[1:] + "&limit=1",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor wasn't issued by the server",
+		},
+		{
+			testName:    "When cursor is encrypted with another key, return status code 400 with error message",
+			queryParams: "?after=" + newCursorCipher([]byte("other-key")).issue("4", cursorDirectionNext, url.Values{}) + "&limit=1",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor wasn't issued by the server",
+		},
+		{
+			testName:    "When cursor was issued for other filters, return status code 400 with error message",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, url.Values{}) + "&limit=1&minDistance=1000",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName: "When cursor was issued for the same filters, use its position",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "4", Limit: 1, MinDistance: 1000, IncludeDeleted: true}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			queryParams:  "?after=" + testCursor("4", cursorDirectionNext, url.Values{"minDistance": {"1000"}, "includeDeleted": {"true"}}) + "&limit=1&minDistance=1e3&includeDeleted=true",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName: "When provided name filters, use them as arguments and bind the cursor to them",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "4", Limit: 1, RiderNamePrefix: "Jo", DriverName: "Jane Doe", DriverVehicle: "Car"}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			queryParams:  "?after=" + testCursor("4", cursorDirectionNext, url.Values{"riderNamePrefix": {"Jo"}, "driverName": {"Jane Doe"}, "driverVehicle": {"Car"}}) + "&limit=1&riderNamePrefix=Jo&driverName=Jane+Doe&driverVehicle=Car",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName:    "When cursor was issued for another driver, return status code 400 with error message",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, url.Values{"driverName": {"Jane Doe"}}) + "&limit=1&driverName=John+Doe",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName: "When sorted, use the sort as argument and bind the cursor to it",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "2500,4", Limit: 1, Sort: "-distance"}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			queryParams:  "?after=" + testCursor("2500,4", cursorDirectionNext, url.Values{"sort": {"-distance,-id"}}) + "&limit=1&sort=-distance",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName:    "When cursor was issued for another sort, return status code 400 with error message",
+			queryParams: "?after=" + testCursor("2500,4", cursorDirectionNext, url.Values{"sort": {"-distance,-id"}}) + "&limit=1&sort=distance",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName:    "When sorted by an unknown field, return status code 422 with error message",
+			queryParams: "?sort=password",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: password is not a sortable field, must be one of id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, createdAt, updatedAt",
+		},
+		{
+			testName: "When including the total and facets, return them along with the page regardless of the cursor",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				total := int64(5)
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "4", Limit: 1, Include: "total,facets"}).
+					Return([]domain.Ride{}, domain.PageInfo{
+						Total: &total,
+						Facets: map[string][]domain.FacetCount{
+							"driverName":    {{Value: "Driver", Count: 5}},
+							"driverVehicle": {{Value: "Car", Count: 3}, {Value: "Van", Count: 2}},
+						},
+					}, nil)
+			},
+			queryParams:  "?after=" + testCursor("4", cursorDirectionNext, url.Values{}) + "&limit=1&include=total,facets",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\",\"total\":5,\"facets\":{\"driverName\":[{\"value\":\"Driver\",\"count\":5}],\"driverVehicle\":[{\"value\":\"Car\",\"count\":3},{\"value\":\"Van\",\"count\":2}]}}\n",
+		},
+		{
+			testName:    "When including an unknown part, return status code 422 with error message",
+			queryParams: "?include=everything",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: everything can't be included, must be one of total, facets",
+		},
+		{
+			testName:    "When from is not a valid RFC3339 time, return status code 400 with error message",
+			queryParams: "?from=yesterday",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Bad request: code=400, message=parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\", internal=parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\"",
+		},
+		{
+			testName: "When provided distance range, use it as arguments",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{MinDistance: 1000, MaxDistance: 2500.5}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			queryParams:  "?minDistance=1000&maxDistance=2500.5",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName: "When provided time range, use it as arguments",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{
+					From: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+					To:   time.Date(2021, 4, 2, 0, 0, 0, 0, time.UTC),
+				}).
+					Return([]domain.Ride{}, domain.PageInfo{}, nil)
+			},
+			queryParams:  "?from=2021-04-01T00:00:00Z&to=2021-04-02T00:00:00Z",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"\"}\n",
+		},
+		{
+			testName: "When GeoJSON is accepted, return status code 200 with a feature collection",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "4", Limit: 1}).
+					Return([]domain.Ride{
+						{
+							ID:             3,
+							StartLatitude:  -6.2,
+							StartLongitude: 106.8,
+							EndLatitude:    -6.2,
+							EndLongitude:   106.8,
+							RiderName:      "John Doe",
+							DriverName:     "Driver",
+							DriverVehicle:  "Car",
+							Status:         domain.RideStatusRequested,
+							CreatedAt:      testTime(),
+							UpdatedAt:      testTime(),
+						},
+					}, domain.PageInfo{PageCursors: domain.PageCursors{Next: "3", Prev: "3"}}, nil)
+			},
+			queryParams:  "?after=" + testCursor("4", cursorDirectionNext, url.Values{}) + "&limit=1",
+			accept:       "application/geo+json",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"type\":\"FeatureCollection\",\"features\":[{\"type\":\"Feature\",\"id\":3,\"geometry\":{\"type\":\"LineString\",\"coordinates\":[[106.8,-6.2],[106.8,-6.2]]},\"properties\":{\"bearingDegrees\":0,\"createdAt\":\"2021-04-01T10:00:00Z\",\"distanceMeters\":0,\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"riderName\":\"John Doe\",\"status\":\"requested\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}}],\"nextCursor\":\"" + testCursor("3", cursorDirectionNext, url.Values{}) + "\",\"prevCursor\":\"" + testCursor("3", cursorDirectionPrev, url.Values{}) + "\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			path := "/rides"
+			if tc.queryParams != "" {
+				path += tc.queryParams
+			}
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(echo.HeaderAccept, tc.accept)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.getAllRides(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+				if tc.link != "" {
+					assert.Equal(t, tc.link, rec.Header().Get("Link"))
+				}
+			}
+		})
+	}
+}
+
+func TestRideController_getRiderRides(t *testing.T) {
+	riderFilters := url.Values{"riderId": {"1"}}
+	testCases := []struct {
+		testName           string
+		paramID            string
+		queryParams        string
+		setupMockRiderRepo setupMockRiderRepo
+		setupMockRepo      setupMockRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When rider doesn't exist, return status code 404 with error message",
+			paramID:  "1",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1)).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find rider with ID 1",
+		},
+		{
+			testName:    "When cursor was issued for all rides, return status code 400 with error message",
+			paramID:     "1",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, url.Values{}),
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1)).Return(&domain.Rider{ID: 1, Name: "John Doe"}, nil)
+			},
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName:    "When successful, return status code 200 with the rides of the rider",
+			paramID:     "1",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, riderFilters) + "&limit=1",
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1)).Return(&domain.Rider{ID: 1, Name: "John Doe"}, nil)
+			},
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectAll(gomock.Any(), domain.Pagination{After: "4", Limit: 1, RiderID: 1}).
+					Return([]domain.Ride{}, domain.PageInfo{PageCursors: domain.PageCursors{Prev: "3"}}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[],\"nextCursor\":\"\",\"prevCursor\":\"" + testCursor("3", cursorDirectionPrev, riderFilters) + "\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/riders/:id/rides")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			riders, riderMock := newRiderController(t, tc.setupMockRiderRepo)
+			defer riderMock.Finish()
+			cntrl.riderRepo = riders.riderRepo
+
+			err := cntrl.getRiderRides(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_getNearbyRides(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		setupMockRepo setupMockRepo
+		queryParams   string
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When failed to bind query params, return status code 400 with error message",
+			queryParams: "?lat=north",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Bad request: code=400, message=strconv.ParseFloat: parsing \"north\": invalid syntax, internal=strconv.ParseFloat: parsing \"north\": invalid syntax",
+		},
+		{
+			testName:    "When query is invalid, return status code 422 with error message",
+			queryParams: "?lat=-91&lng=181&endpoint=middle",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: -91.000000 is not a valid latitude value; 181.000000 is not a valid longitude value; radius must be greater than 0; middle is not a valid endpoint, must be either start or end",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectNearby(gomock.Any(), domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 2000, Endpoint: domain.RideEndpointStart}).
+					Return(nil, "", errors.New("Select Nearby error"))
+			},
+			queryParams: "?lat=-6.2&lng=106.8&radius=2000",
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Select Nearby error",
+		},
+		{
+			testName: "When too many rides are around the center, return status code 422 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectNearby(gomock.Any(), domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 50000, Endpoint: domain.RideEndpointStart}).
+					Return(nil, "", domain.ErrTooManyNearbyRides)
+			},
+			queryParams: "?lat=-6.2&lng=106.8&radius=50000",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: too many rides around the center, use a limit or a smaller radius",
+		},
+		{
+			testName:    "When cursor was issued for another search, return status code 400 with error message",
+			queryParams: "?lat=-6.2&lng=106.8&radius=5000&endpoint=end&limit=1&after=" + testCursor("250.5_2", cursorDirectionNext, url.Values{"lat": {"-6.2"}, "lng": {"106.8"}, "radius": {"2000"}, "endpoint": {"end"}}),
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName: "When successful, return status code 200 with the results and cursor",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectNearby(gomock.Any(), domain.NearbyQuery{
+						Latitude:   -6.2,
+						Longitude:  106.8,
+						Radius:     2000,
+						Endpoint:   domain.RideEndpointEnd,
+						Pagination: domain.Pagination{Limit: 1},
+					}).
+					Return([]domain.Ride{
+						{
+							ID:             1,
+							StartLatitude:  90,
+							StartLongitude: 180,
+							EndLatitude:    90,
+							EndLongitude:   180,
+							RiderName:      "John Doe",
+							DriverName:     "Driver",
+							DriverVehicle:  "Car",
+							Status:         domain.RideStatusRequested,
+							CreatedAt:      testTime(),
+							UpdatedAt:      testTime(),
+						},
+					}, "250.5_2", nil)
+			},
+			queryParams:  "?lat=-6.2&lng=106.8&radius=2000&endpoint=end&limit=1",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}],\"nextCursor\":\"" + testCursor("250.5_2", cursorDirectionNext, url.Values{"lat": {"-6.2"}, "lng": {"106.8"}, "radius": {"2000"}, "endpoint": {"end"}}) + "\",\"prevCursor\":\"\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rides/nearby"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.getNearbyRides(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_searchRides(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		setupMockRepo setupMockRepo
+		queryParams   string
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When failed to bind query params, return status code 400 with error message",
+			queryParams: "?q=john&limit=all",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Bad request: code=400, message=strconv.ParseUint: parsing \"all\": invalid syntax, internal=strconv.ParseUint: parsing \"all\": invalid syntax",
+		},
+		{
+			testName:    "When query is invalid, return status code 422 with error message",
+			queryParams: "?q=jo&sort=riderName",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: q must have a word of at least 3 characters; search results are always sorted by rank",
+		},
+		{
+			testName:    "When cursor was issued for another search, return status code 400 with error message",
+			queryParams: "?q=jane&limit=1&after=" + testCursor("-2_4", cursorDirectionNext, url.Values{"q": {"john"}}),
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName: "When search is unavailable, return status code 501 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Search(gomock.Any(), domain.SearchQuery{Q: "john"}).
+					Return(nil, "", domain.ErrSearchUnavailable)
+			},
+			queryParams: "?q=john",
+			statusCode:  http.StatusNotImplemented,
+			expectedErr: "code=501, message=Not implemented: search is unavailable",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Search(gomock.Any(), domain.SearchQuery{Q: "john"}).
+					Return(nil, "", errors.New("Search error"))
+			},
+			queryParams: "?q=john",
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Search error",
+		},
+		{
+			testName: "When successful, return status code 200 with the results and cursor",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					Search(gomock.Any(), domain.SearchQuery{Q: "john", Pagination: domain.Pagination{After: "-2_4", Limit: 1, DriverName: "Driver"}}).
+					Return([]domain.Ride{
+						{
+							ID:            3,
+							RiderName:     "John Doe",
+							DriverName:    "Driver",
+							DriverVehicle: "Car",
+							Status:        domain.RideStatusRequested,
+							CreatedAt:     testTime(),
+							UpdatedAt:     testTime(),
+						},
+					}, "-2_3", nil)
+			},
+			queryParams:  "?q=john&limit=1&driverName=Driver&after=" + testCursor("-2_4", cursorDirectionNext, url.Values{"q": {"john"}, "driverName": {"Driver"}}),
+			statusCode:   http.StatusOK,
+			responseBody: "{\"rides\":[{\"id\":3,\"startLatitude\":0,\"startLongitude\":0,\"endLatitude\":0,\"endLongitude\":0,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}],\"nextCursor\":\"" + testCursor("-2_3", cursorDirectionNext, url.Values{"q": {"john"}, "driverName": {"Driver"}}) + "\",\"prevCursor\":\"\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rides/search"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.searchRides(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_getRideStats(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		setupMockRepo setupMockRepo
+		queryParams   string
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When failed to bind query params, return status code 400 with error message",
+			queryParams: "?groupBy=day&minDistance=far",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Bad request: code=400, message=strconv.ParseFloat: parsing \"far\": invalid syntax, internal=strconv.ParseFloat: parsing \"far\": invalid syntax",
+		},
+		{
+			testName:    "When query is invalid, return status code 422 with error message",
+			queryParams: "?groupBy=month&limit=10",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: month is not a valid groupBy, must be one of day, week, driverName, driverVehicle; stats can't be paged",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Stats(gomock.Any(), domain.StatsQuery{GroupBy: "day"}).
+					Return(nil, errors.New("Stats error"))
+			},
+			queryParams: "?groupBy=day",
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Stats error",
+		},
+		{
+			testName: "When successful, return status code 200 with the stats of every group",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					Stats(gomock.Any(), domain.StatsQuery{GroupBy: "driverVehicle", Pagination: domain.Pagination{From: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)}}).
+					Return([]domain.RideStats{
+						{Key: "Car", Count: 2, TotalDistanceMeters: 3000, AvgDistanceMeters: 1500, P50DistanceMeters: 1000, P95DistanceMeters: 2000, UniqueRiders: 1},
+					}, nil)
+			},
+			queryParams:  "?groupBy=driverVehicle&from=2021-04-01T00:00:00Z",
+			statusCode:   http.StatusOK,
+			responseBody: "{\"groupBy\":\"driverVehicle\",\"stats\":[{\"key\":\"Car\",\"count\":2,\"totalDistanceMeters\":3000,\"avgDistanceMeters\":1500,\"p50DistanceMeters\":1000,\"p95DistanceMeters\":2000,\"uniqueRiders\":1}]}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rides/stats"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.getRideStats(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_exportRides(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		setupMockRepo setupMockRepo
+		queryParams   string
+		statusCode    int
+		contentType   string
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When failed to bind query params, return status code 400 with error message",
+			queryParams: "?minDistance=far",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Bad request: code=400, message=strconv.ParseFloat: parsing \"far\": invalid syntax, internal=strconv.ParseFloat: parsing \"far\": invalid syntax",
+		},
+		{
+			testName:    "When sorted by an unknown field, return status code 422 with error message",
+			queryParams: "?sort=-password",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid query: password is not a sortable field, must be one of id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, createdAt, updatedAt",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Export(gomock.Any(), domain.Pagination{}, gomock.Any()).
+					Return(errors.New("Export error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Export error",
+		},
+		{
+			testName: "When successful, return status code 200 with the CSV written by repository",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Export(gomock.Any(), domain.Pagination{IncludeDeleted: true}, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, page domain.Pagination, w io.Writer) error {
+						_, err := io.WriteString(w, "id,riderName\n1,John Doe\n")
+						return err
+					})
+			},
+			queryParams:  "?includeDeleted=true",
+			statusCode:   http.StatusOK,
+			contentType:  "text/csv",
+			responseBody: "id,riderName\n1,John Doe\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rides/export.csv"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.exportRides(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+				assert.False(t, c.Response().Committed)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.contentType, rec.Header().Get(echo.HeaderContentType))
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_getRide(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		paramID       string
+		queryParams   string
+		accept        string
+		setupMockRepo setupMockRepo
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), false).
+					Return(nil, errors.New("Select By ID error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Select By ID error",
+		},
+		{
+			testName: "When repository returns no result, return status code 404 with error message",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), false).
+					Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName: "When successful, return status code 200 with result",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), false).
+					Return(&domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+					}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+		{
+			testName:    "When includeDeleted is provided, pass it to repository",
+			paramID:     "1",
+			queryParams: "?includeDeleted=true",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				deletedAt := time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC)
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), true).
+					Return(&domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+						DeletedAt:      &deletedAt,
+					}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"deletedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+		{
+			testName: "When GeoJSON is accepted, return status code 200 with a feature",
+			paramID:  "3",
+			accept:   "application/json;q=0.9, application/geo+json",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(3), false).
+					Return(&domain.Ride{
+						ID:             3,
+						StartLatitude:  -6.2,
+						StartLongitude: 106.8,
+						EndLatitude:    -6.2,
+						EndLongitude:   106.8,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+					}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"type\":\"Feature\",\"id\":3,\"geometry\":{\"type\":\"LineString\",\"coordinates\":[[106.8,-6.2],[106.8,-6.2]]},\"properties\":{\"bearingDegrees\":0,\"createdAt\":\"2021-04-01T10:00:00Z\",\"distanceMeters\":0,\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"riderName\":\"John Doe\",\"status\":\"requested\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.queryParams, nil)
+			req.Header.Set(echo.HeaderAccept, tc.accept)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.getRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_queryTimeout(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		queryTimeout  time.Duration
+		setupMockRepo setupMockRepo
+		statusCode    int
+		expectedErr   string
+	}{
+		{
+			testName:     "When query runs out of time, return status code 503 with error message",
+			queryTimeout: time.Minute,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), false).
+					DoAndReturn(func(ctx context.Context, id int64, includeDeleted bool) (*domain.Ride, error) {
+						deadline, ok := ctx.Deadline()
+						assert.True(t, ok)
+						assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+						return nil, context.DeadlineExceeded
+					})
+			},
+			statusCode:  http.StatusServiceUnavailable,
+			expectedErr: "code=503, message=Query timed out: context deadline exceeded",
+		},
+		{
+			testName: "When there is no timeout, only bound the query by the request",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), int64(1), false).
+					DoAndReturn(func(ctx context.Context, id int64, includeDeleted bool) (*domain.Ride, error) {
+						_, ok := ctx.Deadline()
+						assert.False(t, ok)
+						assert.Equal(t, context.Canceled, ctx.Err())
+						return nil, ctx.Err()
+					})
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: context canceled",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.queryTimeout == 0 {
+				cancel()
+			} else {
+				defer cancel()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			cntrl.queryTimeout = tc.queryTimeout
+
+			err := cntrl.getRide(c)
+			httpErr, ok := err.(*echo.HTTPError)
+			if assert.True(t, ok) {
+				assert.Equal(t, tc.statusCode, httpErr.Code)
+				assert.Equal(t, tc.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestRideController_transitionRide(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		paramID       string
+		requestBody   string
+		setupMockRepo setupMockRepo
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			requestBody: `{"status": "accepted"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName:    "When request body is malformed, return status code 400 with error message",
+			paramID:     "1",
+			requestBody: "invalid-json",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: code=400, message=Syntax error: offset=1, error=invalid character 'i' looking for beginning of value, internal=invalid character 'i' looking for beginning of value",
+		},
+		{
+			testName:    "When status is unknown, return status code 422 with error message",
+			paramID:     "1",
+			requestBody: `{"status": "teleported"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: teleported is not a valid status",
+		},
+		{
+			testName:    "When transition is not allowed, return status code 409 with error message",
+			paramID:     "1",
+			requestBody: `{"status": "cancelled"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					UpdateStatus(gomock.Any(), int64(1), domain.RideStatusCancelled).
+					Return(nil, domain.RideTransitionError{From: domain.RideStatusCompleted, To: domain.RideStatusCancelled})
+			},
+			statusCode:  http.StatusConflict,
+			expectedErr: "code=409, message=Conflict: can't transition ride from completed to cancelled",
+		},
+		{
+			testName:    "When repository returns error, return status code 500 with error message",
+			paramID:     "1",
+			requestBody: `{"status": "accepted"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					UpdateStatus(gomock.Any(), int64(1), domain.RideStatusAccepted).
+					Return(nil, errors.New("Update status error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Update status error",
+		},
+		{
+			testName:    "When repository returns no result, return status code 404 with error message",
+			paramID:     "1",
+			requestBody: `{"status": "accepted"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					UpdateStatus(gomock.Any(), int64(1), domain.RideStatusAccepted).
+					Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName:    "When successful, return status code 200 with updated ride",
+			paramID:     "1",
+			requestBody: `{"status": "accepted"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().
+					UpdateStatus(gomock.Any(), int64(1), domain.RideStatusAccepted).
+					Return(&domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						Status:         domain.RideStatusAccepted,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+					}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"accepted\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id/transitions")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.transitionRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_updateRide(t *testing.T) {
+	existingRide := func() *domain.Ride {
+		return &domain.Ride{
+			ID:             1,
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      "John Doe",
+			DriverName:     "Driver",
+			DriverVehicle:  "Cra",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+		}
+	}
+
+	testCases := []struct {
+		testName      string
+		paramID       string
+		requestBody   string
+		setupMockRepo setupMockRepo
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName:    "When request body is malformed, return status code 400 with error message",
+			paramID:     "1",
+			requestBody: "invalid-json",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: code=400, message=Syntax error: offset=1, error=invalid character 'i' looking for beginning of value, internal=invalid character 'i' looking for beginning of value",
+		},
+		{
+			testName:    "When request is invalid, return status code 422 with error message",
+			paramID:     "1",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": ""}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: driverVehicle can't be empty",
+		},
+		{
+			testName:    "When ride doesn't exist, return status code 404 with error message",
+			paramID:     "1",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName:    "When status is changed, return status code 422 with error message",
+			paramID:     "1",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car", "status": "completed"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: status can't be changed from requested to completed, use POST /rides/1/transitions instead",
+		},
+		{
+			testName:    "When repository returns error, return status code 500 with error message",
+			paramID:     "1",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+				mockRepo.EXPECT().
+					Update(gomock.Any(), domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						RiderID:        &testRiderID,
+					}).
+					Return(nil, errors.New("Update error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Update error",
+		},
+		{
+			testName:    "When repository returns no result, return status code 404 with error message",
+			paramID:     "1",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+				mockRepo.EXPECT().
+					Update(gomock.Any(), domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						RiderID:        &testRiderID,
+					}).
+					Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName:    "When successful, return status code 200 with updated ride",
+			paramID:     "1",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+				mockRepo.EXPECT().
+					Update(gomock.Any(), domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						RiderID:        &testRiderID,
+					}).
+					Return(&domain.Ride{
+						ID:             1,
+						StartLatitude:  90,
+						StartLongitude: 180,
+						EndLatitude:    90,
+						EndLongitude:   180,
+						RiderName:      "John Doe",
+						DriverName:     "Driver",
+						DriverVehicle:  "Car",
+						Status:         domain.RideStatusRequested,
+						CreatedAt:      testTime(),
+						UpdatedAt:      testTime(),
+					}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.updateRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_updateRideWithDriver(t *testing.T) {
+	driverID := int64(7)
+	storedRide := func(driverID *int64) *domain.Ride {
+		return &domain.Ride{
+			ID:             1,
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      "John Doe",
+			DriverName:     "Driver",
+			DriverVehicle:  "Car",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+			DriverID:       driverID,
+		}
+	}
+	testCases := []struct {
+		testName            string
+		setupMockDriverRepo setupMockDriverRepo
+		setupMockRepo       setupMockRepo
+		statusCode          int
+		responseBody        string
+		expectedErr         string
+	}{
+		{
+			testName: "When ride is given a deleted driver, return status code 422 with error message",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(nil), nil)
+			},
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), driverID, false).Return(nil, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: can't find driver with ID 7",
+		},
+		{
+			testName: "When ride keeps a deleted driver, return status code 200 with updated ride",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				updated := storedRide(&driverID)
+				updated.Status = ""
+				updated.CreatedAt = time.Time{}
+				updated.UpdatedAt = time.Time{}
+				updated.StartLatitude = 80
+				updated.RiderID = &testRiderID
+
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(&driverID), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), *updated).Return(storedRide(&driverID), nil)
+			},
+			setupMockDriverRepo: func(mockRepo *mock.MockDriverRepository) {
+				mockRepo.EXPECT().
+					SelectByID(gomock.Any(), driverID, true).
+					Return(&domain.Driver{ID: driverID, Name: "Driver", Vehicle: "Car"}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"driverId\":7}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			body := `{"startLatitude": 80, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverId": 7}`
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			drivers, driverMock := newDriverController(t, tc.setupMockDriverRepo)
+			defer driverMock.Finish()
+			cntrl.driverRepo = drivers.driverRepo
+
+			err := cntrl.updateRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_updateRideWithVehicle(t *testing.T) {
+	vehicleID := int64(7)
+	deactivated := testVehicle(vehicleID)
+	deactivatedAt := testTime()
+	deactivated.DeactivatedAt = &deactivatedAt
+	storedRide := func(vehicleID *int64) *domain.Ride {
+		return &domain.Ride{
+			ID:             1,
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      "John Doe",
+			DriverName:     "Driver",
+			DriverVehicle:  "Toyota Avanza (B 1234 XYZ)",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+			VehicleID:      vehicleID,
+		}
+	}
+	body := `{"startLatitude": 80, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "vehicleId": 7}`
+	testCases := []struct {
+		testName             string
+		requestBody          string
+		setupMockVehicleRepo setupMockVehicleRepo
+		setupMockRepo        setupMockRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName:    "When ride is given a deactivated vehicle, return status code 422 with error message",
+			requestBody: body,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(nil), nil)
+			},
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), vehicleID).Return(&deactivated, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: vehicle with ID 7 is deactivated",
+		},
+		{
+			testName:    "When ride keeps a deactivated vehicle, return status code 200 with updated ride",
+			requestBody: body,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				updated := storedRide(&vehicleID)
+				updated.Status = ""
+				updated.CreatedAt = time.Time{}
+				updated.UpdatedAt = time.Time{}
+				updated.StartLatitude = 80
+				updated.RiderID = &testRiderID
+
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(&vehicleID), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), *updated).Return(storedRide(&vehicleID), nil)
+			},
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), vehicleID).Return(&deactivated, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"vehicleId\":7}\n",
+		},
+		{
+			testName:    "When ride is replaced without a vehicle, keep its vehicle",
+			requestBody: `{"startLatitude": 80, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				updated := storedRide(&vehicleID)
+				updated.Status = ""
+				updated.CreatedAt = time.Time{}
+				updated.UpdatedAt = time.Time{}
+				updated.StartLatitude = 80
+				updated.RiderID = &testRiderID
+
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(&vehicleID), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), *updated).Return(storedRide(&vehicleID), nil)
+			},
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), vehicleID).Return(&deactivated, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Toyota Avanza (B 1234 XYZ)\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"vehicleId\":7}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			vehicles, vehicleMock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer vehicleMock.Finish()
+			cntrl.vehicleRepo = vehicles.vehicleRepo
+
+			err := cntrl.updateRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_updateRideWithRider(t *testing.T) {
+	riderID, otherRiderID := int64(7), int64(8)
+	storedRide := func(riderID int64, riderName string) *domain.Ride {
+		return &domain.Ride{
+			ID:             1,
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      riderName,
+			DriverName:     "Driver",
+			DriverVehicle:  "Car",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+			RiderID:        &riderID,
+		}
+	}
+	updatedRide := func(riderID int64, riderName string) domain.Ride {
+		ride := storedRide(riderID, riderName)
+		ride.Status = ""
+		ride.CreatedAt = time.Time{}
+		ride.UpdatedAt = time.Time{}
+		return *ride
+	}
+	testCases := []struct {
+		testName           string
+		requestBody        string
+		setupMockRiderRepo setupMockRiderRepo
+		setupMockRepo      setupMockRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
+		{
+			testName:    "When ride without riderId is given another rider name, return status code 422 with error message",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "Jon Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(riderID, "John Doe"), nil)
+			},
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), riderID).Return(&domain.Rider{ID: riderID, Name: "John Doe"}, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: riderName Jon Doe doesn't match John Doe, the name of rider with ID 7, rename the rider or set riderId to link the ride to another one",
+		},
+		{
+			testName:    "When ride without riderId keeps the rider name, keep the rider and return status code 200 with updated ride",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "John Doe", "driverName": "Driver", "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(riderID, "John Doe"), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), updatedRide(riderID, "John Doe")).Return(storedRide(riderID, "John Doe"), nil)
+			},
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), riderID).Return(&domain.Rider{ID: riderID, Name: "John Doe"}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":7}\n",
+		},
+		{
+			testName:    "When ride is given another riderId, link it to that rider and return status code 200 with updated ride",
+			requestBody: `{"startLatitude": 90, "startLongitude": 180, "endLatitude": 90, "endLongitude": 180, "riderName": "Jon Doe", "driverName": "Driver", "driverVehicle": "Car", "riderId": 8}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(riderID, "John Doe"), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), updatedRide(otherRiderID, "Jane Doe")).Return(storedRide(otherRiderID, "Jane Doe"), nil)
+			},
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), otherRiderID).Return(&domain.Rider{ID: otherRiderID, Name: "Jane Doe"}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"Jane Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":8}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			riders, riderMock := newRiderController(t, tc.setupMockRiderRepo)
+			defer riderMock.Finish()
+			cntrl.riderRepo = riders.riderRepo
+
+			err := cntrl.updateRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if assert.True(t, ok) {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_patchRide(t *testing.T) {
+	existingRide := func() *domain.Ride {
+		return &domain.Ride{
+			ID:             1,
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      "John Doe",
+			DriverName:     "Driver",
+			DriverVehicle:  "Cra",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+		}
+	}
+
+	testCases := []struct {
+		testName      string
+		paramID       string
+		contentType   string
+		requestBody   string
+		setupMockRepo setupMockRepo
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			contentType: "application/merge-patch+json",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName:    "When content type is not supported, return status code 415 with error message",
+			paramID:     "1",
+			contentType: "text/plain",
+			requestBody: "driverVehicle=Car",
+			statusCode:  http.StatusUnsupportedMediaType,
+			expectedErr: "code=415, message=Unsupported content type: text/plain",
+		},
+		{
+			testName:    "When repository returns no ride, return status code 404 with error message",
+			paramID:     "1",
+			contentType: "application/merge-patch+json",
+			requestBody: `{"driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName:    "When patch is malformed, return status code 400 with error message",
+			paramID:     "1",
+			contentType: "application/merge-patch+json",
+			requestBody: "invalid-json",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+			},
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: invalid character 'i' looking for beginning of value",
+		},
+		{
+			testName:    "When patch removes a required field, return status code 422 with error message",
+			paramID:     "1",
+			contentType: "application/merge-patch+json",
+			requestBody: `{"driverName": null}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: driverName can't be empty",
+		},
+		{
+			testName:    "When patch changes the status, return status code 422 with error message",
+			paramID:     "1",
+			contentType: "application/merge-patch+json",
+			requestBody: `{"status": "cancelled"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: status can't be changed from requested to cancelled, use POST /rides/1/transitions instead",
+		},
+		{
+			testName:    "When successful, return status code 200 with patched ride",
+			paramID:     "1",
+			contentType: "application/merge-patch+json",
+			requestBody: `{"id": 5, "driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				patched := existingRide()
+				patched.DriverVehicle = "Car"
+				patched.RiderID = &testRiderID
+
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(existingRide(), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), *patched).Return(patched, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":99}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.patchRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_patchRideWithRider(t *testing.T) {
+	riderID, otherRiderID := int64(7), int64(8)
+	storedRide := func(riderID int64, riderName string) *domain.Ride {
+		return &domain.Ride{
+			ID:             1,
+			StartLatitude:  90,
+			StartLongitude: 180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      riderName,
+			DriverName:     "Driver",
+			DriverVehicle:  "Car",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+			RiderID:        &riderID,
+		}
+	}
+	testCases := []struct {
+		testName           string
+		requestBody        string
+		setupMockRiderRepo setupMockRiderRepo
+		setupMockRepo      setupMockRepo
+		statusCode         int
+		responseBody       string
+		expectedErr        string
+	}{
+		{
+			testName:    "When patch changes the rider name only, return status code 422 with error message",
+			requestBody: `{"riderName": "Jon Doe"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(riderID, "John Doe"), nil)
+			},
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), riderID).Return(&domain.Rider{ID: riderID, Name: "John Doe"}, nil)
+			},
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: riderName Jon Doe doesn't match John Doe, the name of rider with ID 7, rename the rider or set riderId to link the ride to another one",
+		},
+		{
+			testName:    "When ride was saved before its rider was renamed, copy the new name and return status code 200 with patched ride",
+			requestBody: `{"driverVehicle": "Car"}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(riderID, "John Doe"), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), *storedRide(riderID, "Johnny Doe")).Return(storedRide(riderID, "Johnny Doe"), nil)
+			},
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), riderID).Return(&domain.Rider{ID: riderID, Name: "Johnny Doe"}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"Johnny Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":7}\n",
+		},
+		{
+			testName:    "When patch changes the riderId, link the ride to that rider and return status code 200 with patched ride",
+			requestBody: `{"riderId": 8}`,
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1), false).Return(storedRide(riderID, "John Doe"), nil)
+				mockRepo.EXPECT().Update(gomock.Any(), *storedRide(otherRiderID, "Jane Doe")).Return(storedRide(otherRiderID, "Jane Doe"), nil)
+			},
+			setupMockRiderRepo: func(mockRepo *mock.MockRiderRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), otherRiderID).Return(&domain.Rider{ID: otherRiderID, Name: "Jane Doe"}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"Jane Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"riderId\":8}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+			riders, riderMock := newRiderController(t, tc.setupMockRiderRepo)
+			defer riderMock.Finish()
+			cntrl.riderRepo = riders.riderRepo
+
+			err := cntrl.patchRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if assert.True(t, ok) {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_deleteRide(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		paramID       string
+		setupMockRepo setupMockRepo
+		statusCode    int
+		expectedErr   string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(1)).Return(false, errors.New("Delete error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Delete error",
+		},
+		{
+			testName: "When nothing is deleted, return status code 404 with error message",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(1)).Return(false, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName: "When successful, return status code 204",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Delete(gomock.Any(), int64(1)).Return(true, nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.deleteRide(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRideController_restoreRide(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		paramID       string
+		setupMockRepo setupMockRepo
+		statusCode    int
+		responseBody  string
+		expectedErr   string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Restore(gomock.Any(), int64(1)).Return(nil, errors.New("Restore error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Restore error",
+		},
+		{
+			testName: "When repository returns no result, return status code 404 with error message",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Restore(gomock.Any(), int64(1)).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find ride with ID 1",
+		},
+		{
+			testName: "When successful, return status code 200 with restored ride",
+			paramID:  "1",
+			setupMockRepo: func(mockRepo *mock.MockRideRepository) {
+				mockRepo.EXPECT().Restore(gomock.Any(), int64(1)).Return(&domain.Ride{
+					ID:             1,
+					StartLatitude:  90,
+					StartLongitude: 180,
+					EndLatitude:    90,
+					EndLongitude:   180,
+					RiderName:      "John Doe",
+					DriverName:     "Driver",
+					DriverVehicle:  "Car",
+					Status:         domain.RideStatusRequested,
+					CreatedAt:      testTime(),
+					UpdatedAt:      testTime(),
+				}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"startLatitude\":90,\"startLongitude\":180,\"endLatitude\":90,\"endLongitude\":180,\"riderName\":\"John Doe\",\"driverName\":\"Driver\",\"driverVehicle\":\"Car\",\"distanceMeters\":0,\"bearingDegrees\":0,\"status\":\"requested\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/rides/:id/restore")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newRideController(t, tc.setupMockRepo)
+			defer mock.Finish()
+
+			err := cntrl.restoreRide(c)
 			if tc.expectedErr != "" {
 				httpErr, ok := err.(*echo.HTTPError)
 				if ok {
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+type (
+	vehicleCntrl struct {
+		vehicleRepo  domain.VehicleRepository
+		plates       domain.PlateValidators
+		now          func() time.Time
+		queryTimeout time.Duration
+		cursors      cursorCipher
+	}
+
+	vehiclesEnvelope struct {
+		Vehicles   []domain.Vehicle `json:"vehicles"`
+		NextCursor string           `json:"nextCursor"`
+	}
+)
+
+// SetupVehicleController registers the vehicle routes, plate numbers are
+// validated by plates. Queries and cursors are handled the same way as by
+// SetupRideController.
+func SetupVehicleController(e *echo.Echo, vehicleRepo domain.VehicleRepository, plates domain.PlateValidators, queryTimeout time.Duration, cursorKey []byte) {
+	cntrl := &vehicleCntrl{
+		vehicleRepo:  vehicleRepo,
+		plates:       plates,
+		now:          time.Now,
+		queryTimeout: queryTimeout,
+		cursors:      newCursorCipher(cursorKey),
+	}
+
+	e.POST("/vehicles", cntrl.addVehicle)
+	e.GET("/vehicles", cntrl.getAllVehicles)
+	e.GET("/vehicles/:id", cntrl.getVehicle)
+	e.PUT("/vehicles/:id", cntrl.updateVehicle)
+	e.POST("/vehicles/:id/deactivate", cntrl.deactivateVehicle)
+}
+
+func (cntrl vehicleCntrl) addVehicle(c echo.Context) error {
+	var vehicle domain.Vehicle
+	if err := c.Bind(&vehicle); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	if err := vehicle.Validate(cntrl.plates); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+	now := cntrl.now().UTC()
+	vehicle.CreatedAt = now
+	vehicle.UpdatedAt = now
+	vehicle.DeactivatedAt = nil
+
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	id, err := cntrl.vehicleRepo.Insert(ctx, vehicle)
+	if err != nil {
+		return repositoryError(err)
+	}
+	vehicle.ID = id
+	return c.JSON(http.StatusCreated, vehicle)
+}
+
+func (cntrl vehicleCntrl) getAllVehicles(c echo.Context) error {
+	var page domain.VehiclePagination
+	if err := c.Bind(&page); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Bad request: %s", err))
+	}
+	after, err := cntrl.cursors.position(page.After, cursorDirectionNext, vehicleFilters())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid cursor: %s", err))
+	}
+	page.After = after
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	vehicles, next, err := cntrl.vehicleRepo.SelectAll(ctx, page)
+	if err != nil {
+		return repositoryError(err)
+	}
+	return c.JSON(http.StatusOK, vehiclesEnvelope{
+		Vehicles:   vehicles,
+		NextCursor: cntrl.cursors.issue(next, cursorDirectionNext, vehicleFilters()),
+	})
+}
+
+func (cntrl vehicleCntrl) getVehicle(c echo.Context) error {
+	vehicleID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	vehicle, err := cntrl.vehicleRepo.SelectByID(ctx, vehicleID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if vehicle == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find vehicle with ID %d", vehicleID))
+	}
+	return c.JSON(http.StatusOK, vehicle)
+}
+
+// updateVehicle leaves deactivating the vehicle to deactivateVehicle. Rides
+// hold a copy of its description, it's refreshed when they're saved.
+func (cntrl vehicleCntrl) updateVehicle(c echo.Context) error {
+	vehicleID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	var vehicle domain.Vehicle
+	if err := c.Bind(&vehicle); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err))
+	}
+	vehicle.ID = vehicleID
+	vehicle.DeactivatedAt = nil
+	if err := vehicle.Validate(cntrl.plates); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request body: %s", err))
+	}
+
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	updated, err := cntrl.vehicleRepo.Update(ctx, vehicle)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if updated == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find vehicle with ID %d", vehicleID))
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+// deactivateVehicle keeps new rides from referencing the vehicle, deactivating
+// it again leaves it as it is.
+func (cntrl vehicleCntrl) deactivateVehicle(c echo.Context) error {
+	vehicleID, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withQueryTimeout(c.Request().Context(), cntrl.queryTimeout)
+	defer cancel()
+	vehicle, err := cntrl.vehicleRepo.Deactivate(ctx, vehicleID)
+	if err != nil {
+		return repositoryError(err)
+	}
+	if vehicle == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find vehicle with ID %d", vehicleID))
+	}
+	return c.JSON(http.StatusOK, vehicle)
+}
+
+// vehicleFilters binds vehicle cursors to the vehicles list the same way as
+// driverFilters.
+func vehicleFilters() url.Values {
+	return url.Values{"list": {"vehicles"}}
+}
@@ -0,0 +1,384 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/repository/mock"
+)
+
+type setupMockVehicleRepo func(mockRepo *mock.MockVehicleRepository)
+
+func newVehicleController(t *testing.T, fn setupMockVehicleRepo) (vehicleCntrl, *gomock.Controller) {
+	mockCtrl := gomock.NewController(t)
+
+	vehicleRepo := mock.NewMockVehicleRepository(mockCtrl)
+	if fn != nil {
+		fn(vehicleRepo)
+	}
+
+	return vehicleCntrl{vehicleRepo: vehicleRepo, plates: domain.DefaultPlateValidators(), now: testTime, cursors: testCursorCipher()}, mockCtrl
+}
+
+func testVehicle(id int64) domain.Vehicle {
+	return domain.Vehicle{
+		ID:          id,
+		PlateNumber: "B 1234 XYZ",
+		Country:     "ID",
+		Make:        "Toyota",
+		Model:       "Avanza",
+		Capacity:    6,
+		Type:        domain.VehicleTypeCar,
+		CreatedAt:   testTime(),
+		UpdatedAt:   testTime(),
+	}
+}
+
+func TestVehicleController_addVehicle(t *testing.T) {
+	testCases := []struct {
+		testName             string
+		requestBody          string
+		setupMockVehicleRepo setupMockVehicleRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName:    "When request body is malformed, return status code 400 with error message",
+			requestBody: "invalid-json",
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Malformed request body: code=400, message=Syntax error: offset=1, error=invalid character 'i' looking for beginning of value, internal=invalid character 'i' looking for beginning of value",
+		},
+		{
+			testName:    "When plate number doesn't match its country, return status code 422 with error message",
+			requestBody: `{"plateNumber": "B 1234 XYZ", "country": "SG", "make": "Toyota", "model": "Avanza", "capacity": 6, "type": "car"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: B 1234 XYZ is not a valid plate number, must be formatted like SBA 1234 A",
+		},
+		{
+			testName:    "When repository returns error, return status code 500 with error message",
+			requestBody: `{"plateNumber": "B 1234 XYZ", "country": "ID", "make": "Toyota", "model": "Avanza", "capacity": 6, "type": "car"}`,
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().Insert(gomock.Any(), testVehicle(0)).Return(int64(-1), errors.New("Insert error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Insert error",
+		},
+		{
+			testName:    "When plate number is taken, return status code 409 with error message",
+			requestBody: `{"plateNumber": "B 1234 XYZ", "country": "ID", "make": "Toyota", "model": "Avanza", "capacity": 6, "type": "car"}`,
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().Insert(gomock.Any(), testVehicle(0)).Return(int64(-1), domain.ErrDuplicatePlateNumber)
+			},
+			statusCode:  http.StatusConflict,
+			expectedErr: "code=409, message=Conflict: a vehicle with the plate number is already registered in the country",
+		},
+		{
+			testName:    "When successful, return status code 201 with response body",
+			requestBody: `{"plateNumber": "B 1234 XYZ", "country": "ID", "make": "Toyota", "model": "Avanza", "capacity": 6, "type": "car", "deactivatedAt": "2021-04-01T10:00:00Z"}`,
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().Insert(gomock.Any(), testVehicle(0)).Return(int64(1), nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: "{\"id\":1,\"plateNumber\":\"B 1234 XYZ\",\"country\":\"ID\",\"make\":\"Toyota\",\"model\":\"Avanza\",\"capacity\":6,\"type\":\"car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer mock.Finish()
+
+			err := cntrl.addVehicle(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestVehicleController_getAllVehicles(t *testing.T) {
+	testCases := []struct {
+		testName             string
+		queryParams          string
+		setupMockVehicleRepo setupMockVehicleRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName:    "When cursor was issued for drivers, return status code 400 with error message",
+			queryParams: "?after=" + testCursor("4", cursorDirectionNext, driverFilters()),
+			statusCode:  http.StatusBadRequest,
+			expectedErr: "code=400, message=Invalid cursor: cursor was issued for other filters",
+		},
+		{
+			testName:    "When successful, return status code 200 with vehicles and the next cursor",
+			queryParams: "?after=" + testCursor("1", cursorDirectionNext, vehicleFilters()) + "&limit=1",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().
+					SelectAll(gomock.Any(), domain.VehiclePagination{After: "1", Limit: 1}).
+					Return([]domain.Vehicle{testVehicle(2)}, "2", nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"vehicles\":[{\"id\":2,\"plateNumber\":\"B 1234 XYZ\",\"country\":\"ID\",\"make\":\"Toyota\",\"model\":\"Avanza\",\"capacity\":6,\"type\":\"car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}],\"nextCursor\":\"" + testCursor("2", cursorDirectionNext, vehicleFilters()) + "\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/vehicles"+tc.queryParams, nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+
+			cntrl, mock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer mock.Finish()
+
+			err := cntrl.getAllVehicles(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestVehicleController_getVehicle(t *testing.T) {
+	testCases := []struct {
+		testName             string
+		paramID              string
+		setupMockVehicleRepo setupMockVehicleRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName:    "When ID is not an integer, return status code 422 with error message",
+			paramID:     "not-a-string",
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid ID: strconv.ParseInt: parsing \"not-a-string\": invalid syntax",
+		},
+		{
+			testName: "When repository returns no result, return status code 404 with error message",
+			paramID:  "1",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1)).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find vehicle with ID 1",
+		},
+		{
+			testName: "When successful, return status code 200 with result",
+			paramID:  "1",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				vehicle := testVehicle(1)
+				mockRepo.EXPECT().SelectByID(gomock.Any(), int64(1)).Return(&vehicle, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"plateNumber\":\"B 1234 XYZ\",\"country\":\"ID\",\"make\":\"Toyota\",\"model\":\"Avanza\",\"capacity\":6,\"type\":\"car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/vehicles/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer mock.Finish()
+
+			err := cntrl.getVehicle(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestVehicleController_updateVehicle(t *testing.T) {
+	testCases := []struct {
+		testName             string
+		paramID              string
+		requestBody          string
+		setupMockVehicleRepo setupMockVehicleRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName:    "When request is invalid, return status code 422 with error message",
+			paramID:     "1",
+			requestBody: `{"plateNumber": "B 1234 XYZ", "country": "ID", "make": "Toyota", "model": "Avanza", "capacity": 0, "type": "car"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+			expectedErr: "code=422, message=Invalid request body: 0 is not a valid capacity, must be at least 1",
+		},
+		{
+			testName:    "When repository returns no result, return status code 404 with error message",
+			paramID:     "1",
+			requestBody: `{"plateNumber": "B 1234 XYZ", "country": "ID", "make": "Toyota", "model": "Avanza", "capacity": 6, "type": "car"}`,
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				vehicle := testVehicle(1)
+				vehicle.CreatedAt, vehicle.UpdatedAt = time.Time{}, time.Time{}
+				mockRepo.EXPECT().Update(gomock.Any(), vehicle).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find vehicle with ID 1",
+		},
+		{
+			testName:    "When successful, return status code 200 with updated vehicle",
+			paramID:     "1",
+			requestBody: `{"id": 2, "plateNumber": "B 1234 XYZ", "country": "ID", "make": "Toyota", "model": "Avanza", "capacity": 6, "type": "car"}`,
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				vehicle := testVehicle(1)
+				vehicle.CreatedAt, vehicle.UpdatedAt = time.Time{}, time.Time{}
+				updated := testVehicle(1)
+				mockRepo.EXPECT().Update(gomock.Any(), vehicle).Return(&updated, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"plateNumber\":\"B 1234 XYZ\",\"country\":\"ID\",\"make\":\"Toyota\",\"model\":\"Avanza\",\"capacity\":6,\"type\":\"car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/vehicles/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer mock.Finish()
+
+			err := cntrl.updateVehicle(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestVehicleController_deactivateVehicle(t *testing.T) {
+	testCases := []struct {
+		testName             string
+		paramID              string
+		setupMockVehicleRepo setupMockVehicleRepo
+		statusCode           int
+		responseBody         string
+		expectedErr          string
+	}{
+		{
+			testName: "When repository returns error, return status code 500 with error message",
+			paramID:  "1",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().Deactivate(gomock.Any(), int64(1)).Return(nil, errors.New("Deactivate error"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			expectedErr: "code=500, message=Internal server error: Deactivate error",
+		},
+		{
+			testName: "When repository returns no result, return status code 404 with error message",
+			paramID:  "1",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				mockRepo.EXPECT().Deactivate(gomock.Any(), int64(1)).Return(nil, nil)
+			},
+			statusCode:  http.StatusNotFound,
+			expectedErr: "code=404, message=Can't find vehicle with ID 1",
+		},
+		{
+			testName: "When successful, return status code 200 with deactivated vehicle",
+			paramID:  "1",
+			setupMockVehicleRepo: func(mockRepo *mock.MockVehicleRepository) {
+				vehicle := testVehicle(1)
+				deactivatedAt := testTime()
+				vehicle.DeactivatedAt = &deactivatedAt
+				mockRepo.EXPECT().Deactivate(gomock.Any(), int64(1)).Return(&vehicle, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: "{\"id\":1,\"plateNumber\":\"B 1234 XYZ\",\"country\":\"ID\",\"make\":\"Toyota\",\"model\":\"Avanza\",\"capacity\":6,\"type\":\"car\",\"createdAt\":\"2021-04-01T10:00:00Z\",\"updatedAt\":\"2021-04-01T10:00:00Z\",\"deactivatedAt\":\"2021-04-01T10:00:00Z\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			c.SetPath("/vehicles/:id/deactivate")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.paramID)
+
+			cntrl, mock := newVehicleController(t, tc.setupMockVehicleRepo)
+			defer mock.Finish()
+
+			err := cntrl.deactivateVehicle(c)
+			if tc.expectedErr != "" {
+				httpErr, ok := err.(*echo.HTTPError)
+				if ok {
+					assert.Equal(t, tc.statusCode, httpErr.Code)
+					assert.Equal(t, tc.expectedErr, err.Error())
+				}
+			} else {
+				assert.Equal(t, tc.statusCode, rec.Code)
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,38 +1,460 @@
 package domain
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/mail"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hawarir/backend-coding-test/geo"
 )
 
 type (
+	RideStatus string
+
+	RideEndpoint string
+
+	RiderContact string
+
+	VehicleType string
+
 	Ride struct {
-		ID             int64   `json:"id"`
-		StartLatitude  float64 `json:"startLatitude"`
-		StartLongitude float64 `json:"startLongitude"`
-		EndLatitude    float64 `json:"endLatitude"`
-		EndLongitude   float64 `json:"endLongitude"`
-		RiderName      string  `json:"riderName"`
-		DriverName     string  `json:"driverName"`
-		DriverVehicle  string  `json:"driverVehicle"`
+		ID             int64      `json:"id"`
+		StartLatitude  float64    `json:"startLatitude"`
+		StartLongitude float64    `json:"startLongitude"`
+		EndLatitude    float64    `json:"endLatitude"`
+		EndLongitude   float64    `json:"endLongitude"`
+		RiderName      string     `json:"riderName"`
+		DriverName     string     `json:"driverName"`
+		DriverVehicle  string     `json:"driverVehicle"`
+		DistanceMeters float64    `json:"distanceMeters"`
+		BearingDegrees float64    `json:"bearingDegrees"`
+		Status         RideStatus `json:"status"`
+		StartedAt      *time.Time `json:"startedAt,omitempty"`
+		EndedAt        *time.Time `json:"endedAt,omitempty"`
+		CreatedAt      time.Time  `json:"createdAt"`
+		UpdatedAt      time.Time  `json:"updatedAt"`
+		DeletedAt      *time.Time `json:"deletedAt,omitempty"`
+		// DriverID references the driver of the ride, DriverName and
+		// DriverVehicle are copied from it whenever the ride is saved.
+		DriverID *int64 `json:"driverId,omitempty"`
+		// RiderID references the rider of the ride, RiderName is copied from it
+		// the same way.
+		RiderID *int64 `json:"riderId,omitempty"`
+		// VehicleID references the vehicle of the ride, DriverVehicle is copied
+		// from it the same way and takes precedence over the vehicle of the
+		// driver. Every new ride references a vehicle.
+		VehicleID *int64 `json:"vehicleId,omitempty"`
+	}
+
+	RideTransitionError struct {
+		From RideStatus
+		To   RideStatus
+	}
+
+	// InvalidRideError tells why Ride.Validate rejects a ride, Reasons are
+	// joined by semicolons in its message.
+	InvalidRideError struct {
+		Reasons []string
 	}
 
+	// VehicleLookup finds the vehicle with the ID for Ride.Validate, it returns
+	// nil when there is no such vehicle.
+	VehicleLookup func(id int64) (*Vehicle, error)
+
 	Pagination struct {
-		Cursor string `query:"cursor"`
-		Limit  uint64 `query:"limit"`
+		// After and Before are cursors returned along with a page, After pages
+		// forward and Before pages back. Only one of them can be set.
+		After          string `query:"after"`
+		Before         string `query:"before"`
+		Limit          uint64 `query:"limit"`
+		IncludeDeleted bool   `query:"includeDeleted"`
+
+		// From and To filter rides by their creation time, From is inclusive and
+		// To is exclusive.
+		From time.Time `query:"from"`
+		To   time.Time `query:"to"`
+
+		// MinDistance and MaxDistance filter rides by their distance in meters,
+		// both are inclusive and ignored when zero.
+		MinDistance float64 `query:"minDistance"`
+		MaxDistance float64 `query:"maxDistance"`
+
+		// RiderName, DriverName and DriverVehicle only match rides with exactly
+		// the same value, the prefix filters match the ones starting with it.
+		// Values are compared case sensitively and ignored when empty.
+		RiderName           string `query:"riderName"`
+		RiderNamePrefix     string `query:"riderNamePrefix"`
+		DriverName          string `query:"driverName"`
+		DriverNamePrefix    string `query:"driverNamePrefix"`
+		DriverVehicle       string `query:"driverVehicle"`
+		DriverVehiclePrefix string `query:"driverVehiclePrefix"`
+
+		// Sort is a comma separated list of the fields rides are sorted by, each
+		// prefixed with - to sort it descending. Rides are sorted latest first
+		// when it's empty.
+		Sort string `query:"sort"`
+
+		// Include is a comma separated list of what's counted along with the
+		// page, the total and the facets. Counting reads every ride matching
+		// the filters, so it's skipped unless included.
+		Include string `query:"include"`
+
+		// RiderID only matches the rides of the rider, it's ignored when zero.
+		// It's taken from the path of the rider's rides instead of the query.
+		RiderID int64
 	}
 
+	// SortField is a field rides are sorted by, fields are named after the
+	// columns they're stored in.
+	SortField struct {
+		Name string
+		Desc bool
+	}
+
+	// PageCursors point at the pages around a page, Next is passed back as After
+	// and Prev as Before. They're empty when there is no such page.
+	PageCursors struct {
+		Next string
+		Prev string
+	}
+
+	// PageInfo is what SelectAll returns along with the rides. Total and Facets
+	// count the rides matching the filters of the page regardless of its
+	// cursors, they're only set when included. Facets holds the most common
+	// values of each faceted field, most common first.
+	PageInfo struct {
+		PageCursors
+		Total  *int64
+		Facets map[string][]FacetCount
+	}
+
+	// FacetCount is how many rides have the value.
+	FacetCount struct {
+		Value string `json:"value"`
+		Count int64  `json:"count"`
+	}
+
+	NearbyQuery struct {
+		Latitude  float64      `query:"lat"`
+		Longitude float64      `query:"lng"`
+		Radius    float64      `query:"radius"`
+		Endpoint  RideEndpoint `query:"endpoint"`
+
+		Pagination
+	}
+
+	// SearchQuery matches rides by their rider, driver and vehicle names. Words
+	// of Q match names sharing any three consecutive characters with them, so
+	// partial and misspelled names are found too. Results are ranked by how
+	// well they match.
+	SearchQuery struct {
+		Q string `query:"q"`
+
+		Pagination
+	}
+
+	// StatsQuery groups the rides matching the filters of Pagination by
+	// GroupBy, which is either the UTC day or week they were created in, weeks
+	// starting on Monday, or their driver name or vehicle.
+	StatsQuery struct {
+		GroupBy string `query:"groupBy"`
+
+		Pagination
+	}
+
+	// RideStats summarizes the rides of a group. Key is the first day of the
+	// group formatted as YYYY-MM-DD, or its driver name or vehicle. The
+	// percentiles are the distances at the nearest rank. UniqueRiders counts
+	// riders by ID, rides without one aren't counted.
+	RideStats struct {
+		Key                 string  `json:"key"`
+		Count               int64   `json:"count"`
+		TotalDistanceMeters float64 `json:"totalDistanceMeters"`
+		AvgDistanceMeters   float64 `json:"avgDistanceMeters"`
+		P50DistanceMeters   float64 `json:"p50DistanceMeters"`
+		P95DistanceMeters   float64 `json:"p95DistanceMeters"`
+		UniqueRiders        int64   `json:"uniqueRiders"`
+	}
+
+	// Driver drives rides under the same name and vehicle, instead of both
+	// being typed in for every ride.
+	Driver struct {
+		ID        int64     `json:"id"`
+		Name      string    `json:"name"`
+		Vehicle   string    `json:"vehicle"`
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	// Rider is who rides are logged for. Contact details are optional, unless
+	// the rider prefers to be contacted through them.
+	Rider struct {
+		ID          int64            `json:"id"`
+		Name        string           `json:"name"`
+		Email       string           `json:"email,omitempty"`
+		Phone       string           `json:"phone,omitempty"`
+		Preferences RiderPreferences `json:"preferences"`
+		CreatedAt   time.Time        `json:"createdAt"`
+		UpdatedAt   time.Time        `json:"updatedAt"`
+	}
+
+	RiderPreferences struct {
+		ContactBy RiderContact `json:"contactBy,omitempty"`
+		// Language is the language the rider is contacted in, as a BCP 47 tag.
+		Language string `json:"language,omitempty"`
+	}
+
+	// Vehicle is registered once with its plate number and referenced by rides.
+	// Deactivated vehicles are kept for the rides referencing them, but new
+	// rides can't reference them.
+	Vehicle struct {
+		ID          int64  `json:"id"`
+		PlateNumber string `json:"plateNumber"`
+		// Country is the ISO 3166-1 alpha-2 code of the country the plate is
+		// issued in, the plate number is validated by its format there.
+		Country string `json:"country"`
+		Make    string `json:"make"`
+		Model   string `json:"model"`
+		// Capacity is how many riders the vehicle seats.
+		Capacity      int         `json:"capacity"`
+		Type          VehicleType `json:"type"`
+		CreatedAt     time.Time   `json:"createdAt"`
+		UpdatedAt     time.Time   `json:"updatedAt"`
+		DeactivatedAt *time.Time  `json:"deactivatedAt,omitempty"`
+	}
+
+	// DriverPagination pages through drivers oldest first.
+	DriverPagination struct {
+		After string `query:"after"`
+		Limit uint64 `query:"limit"`
+	}
+
+	// VehiclePagination pages through vehicles oldest first.
+	VehiclePagination struct {
+		After string `query:"after"`
+		Limit uint64 `query:"limit"`
+	}
+
+	// RideRepository stores rides. RenameRider copies the new name of a rider
+	// into every one of its rides, deleted or not.
 	RideRepository interface {
-		InitTable() error
+		Insert(context.Context, Ride) (int64, error)
+		InsertBatch(context.Context, []Ride) ([]int64, error)
+		SelectAll(context.Context, Pagination) ([]Ride, PageInfo, error)
+		SelectByID(ctx context.Context, id int64, includeDeleted bool) (*Ride, error)
+		SelectNearby(context.Context, NearbyQuery) ([]Ride, string, error)
+		Search(context.Context, SearchQuery) ([]Ride, string, error)
+		Stats(context.Context, StatsQuery) ([]RideStats, error)
+		Export(context.Context, Pagination, io.Writer) error
+		Update(context.Context, Ride) (*Ride, error)
+		UpdateStatus(context.Context, int64, RideStatus) (*Ride, error)
+		Delete(context.Context, int64) (bool, error)
+		Restore(context.Context, int64) (*Ride, error)
+		RenameRider(ctx context.Context, riderID int64, name string) error
+	}
+
+	// DriverRepository stores drivers. Deleted drivers are kept for the rides
+	// referencing them, they're only read back by SelectByID when including
+	// deleted ones.
+	DriverRepository interface {
+		Insert(context.Context, Driver) (int64, error)
+		SelectAll(context.Context, DriverPagination) ([]Driver, string, error)
+		SelectByID(ctx context.Context, id int64, includeDeleted bool) (*Driver, error)
+		Update(context.Context, Driver) (*Driver, error)
+		Delete(context.Context, int64) (bool, error)
+	}
 
-		Insert(Ride) (int64, error)
-		SelectAll(Pagination) ([]Ride, string, error)
-		SelectByID(int64) (*Ride, error)
+	// RiderRepository stores riders. Riders aren't unique by name, SelectByName
+	// reads back the oldest rider with the name.
+	RiderRepository interface {
+		Insert(context.Context, Rider) (int64, error)
+		SelectByID(context.Context, int64) (*Rider, error)
+		SelectByName(context.Context, string) (*Rider, error)
+		Update(context.Context, Rider) (*Rider, error)
 	}
+
+	// VehicleRepository stores vehicles. Deactivated vehicles can still be read
+	// back so rides referencing them can be told apart from unknown ones. Plate
+	// numbers are unique within a country, Insert and Update return
+	// ErrDuplicatePlateNumber otherwise.
+	VehicleRepository interface {
+		Insert(context.Context, Vehicle) (int64, error)
+		SelectAll(context.Context, VehiclePagination) ([]Vehicle, string, error)
+		SelectByID(context.Context, int64) (*Vehicle, error)
+		Update(context.Context, Vehicle) (*Vehicle, error)
+		Deactivate(context.Context, int64) (*Vehicle, error)
+	}
+)
+
+// ErrSearchUnavailable is returned by Search when the database can't search
+// rides, which is the case for SQLite built without FTS5.
+var ErrSearchUnavailable = errors.New("search is unavailable")
+
+// ErrDuplicatePlateNumber is returned by VehicleRepository when the vehicle has
+// the plate number of another vehicle of the same country, deactivated or not.
+var ErrDuplicatePlateNumber = errors.New("a vehicle with the plate number is already registered in the country")
+
+// ErrTooManyNearbyRides is returned by SelectNearby without a limit when more
+// rides lie around the center than are read at once, a limit pages through
+// them instead.
+var ErrTooManyNearbyRides = errors.New("too many rides around the center, use a limit or a smaller radius")
+
+const (
+	RideStatusRequested RideStatus = "requested"
+	RideStatusAccepted  RideStatus = "accepted"
+	RideStatusStarted   RideStatus = "started"
+	RideStatusCompleted RideStatus = "completed"
+	RideStatusCancelled RideStatus = "cancelled"
+)
+
+const (
+	RideEndpointStart RideEndpoint = "start"
+	RideEndpointEnd   RideEndpoint = "end"
+)
+
+const (
+	RiderContactEmail RiderContact = "email"
+	RiderContactPhone RiderContact = "phone"
 )
 
-func (r Ride) Validate() error {
+const (
+	VehicleTypeCar        VehicleType = "car"
+	VehicleTypeMotorcycle VehicleType = "motorcycle"
+	VehicleTypeVan        VehicleType = "van"
+)
+
+// maxNearbyRadius caps the radius of nearby searches in meters.
+const maxNearbyRadius = 50000
+
+// maxSearchLength caps how long searches are, every word of a search adds to
+// the terms matched against the names.
+const maxSearchLength = 100
+
+// sortableRideFields lists the fields rides can be sorted by, the ones that can
+// be null are left out.
+func sortableRideFields() []string {
+	return []string{
+		"id",
+		"startLat",
+		"startLong",
+		"endLat",
+		"endLong",
+		"riderName",
+		"driverName",
+		"driverVehicle",
+		"distance",
+		"bearing",
+		"status",
+		"createdAt",
+		"updatedAt",
+	}
+}
+
+// rideStatsGroups lists what rides can be grouped by for stats.
+func rideStatsGroups() []string {
+	return []string{"day", "week", "driverName", "driverVehicle"}
+}
+
+// vehicleTypes lists the types a vehicle can be of.
+func vehicleTypes() []VehicleType {
+	return []VehicleType{VehicleTypeCar, VehicleTypeMotorcycle, VehicleTypeVan}
+}
+
+// pageIncludes lists what can be counted along with a page.
+func pageIncludes() []string {
+	return []string{"total", "facets"}
+}
+
+// rideStatusTransitions lists the statuses a ride can move to from each status,
+// completed and cancelled are terminal.
+func rideStatusTransitions() map[RideStatus][]RideStatus {
+	return map[RideStatus][]RideStatus{
+		RideStatusRequested: {RideStatusAccepted, RideStatusCancelled},
+		RideStatusAccepted:  {RideStatusStarted, RideStatusCancelled},
+		RideStatusStarted:   {RideStatusCompleted, RideStatusCancelled},
+		RideStatusCompleted: {},
+		RideStatusCancelled: {},
+	}
+}
+
+func (s RideStatus) Valid() bool {
+	_, ok := rideStatusTransitions()[s]
+	return ok
+}
+
+func (s RideStatus) CanTransitionTo(next RideStatus) bool {
+	for _, status := range rideStatusTransitions()[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Predecessors returns every status that is allowed to transition into s.
+func (s RideStatus) Predecessors() []RideStatus {
+	predecessors := []RideStatus{}
+	for _, from := range []RideStatus{
+		RideStatusRequested,
+		RideStatusAccepted,
+		RideStatusStarted,
+		RideStatusCompleted,
+		RideStatusCancelled,
+	} {
+		if from.CanTransitionTo(s) {
+			predecessors = append(predecessors, from)
+		}
+	}
+	return predecessors
+}
+
+func (e RideTransitionError) Error() string {
+	return fmt.Sprintf("can't transition ride from %s to %s", e.From, e.To)
+}
+
+func (e InvalidRideError) Error() string {
+	return strings.Join(e.Reasons, "; ")
+}
+
+// Distance returns the great-circle distance in meters between the start and
+// end of the ride.
+func (r Ride) Distance() float64 {
+	return geo.Distance(r.startPoint(), r.endPoint())
+}
+
+// Bearing returns the initial bearing in degrees from the start to the end of
+// the ride.
+func (r Ride) Bearing() float64 {
+	return geo.InitialBearing(r.startPoint(), r.endPoint())
+}
+
+// Point returns the start or end point of the ride.
+func (r Ride) Point(endpoint RideEndpoint) geo.Point {
+	if endpoint == RideEndpointEnd {
+		return r.endPoint()
+	}
+	return r.startPoint()
+}
+
+func (r Ride) startPoint() geo.Point {
+	return geo.Point{Latitude: r.StartLatitude, Longitude: r.StartLongitude}
+}
+
+func (r Ride) endPoint() geo.Point {
+	return geo.Point{Latitude: r.EndLatitude, Longitude: r.EndLongitude}
+}
+
+// Validate validates the ride, current is the stored ride when it's being
+// edited and nil when it's created. A new ride needs a registered vehicle, its
+// vehicle is looked up in vehicles and can't be deactivated unless the ride
+// already referenced it. Invalid rides are rejected with an InvalidRideError,
+// any other error is returned by vehicles.
+func (r Ride) Validate(current *Ride, vehicles VehicleLookup) error {
 	errs := []string{}
 	correctLatitude := func(lat float64) bool {
 		return lat >= -90 && lat <= 90
@@ -56,12 +478,343 @@ func (r Ride) Validate() error {
 	for _, tuple := range [][2]string{
 		{"riderName", r.RiderName},
 		{"driverName", r.DriverName},
-		{"driverVehicle", r.DriverVehicle},
 	} {
 		if stringEmpty(tuple[1]) {
 			errs = append(errs, fmt.Sprintf("%s can't be empty", tuple[0]))
 		}
 	}
+	if r.StartedAt != nil && r.EndedAt != nil && r.EndedAt.Before(*r.StartedAt) {
+		errs = append(errs, "endedAt can't be before startedAt")
+	}
+	if r.Status != "" && !r.Status.Valid() {
+		errs = append(errs, fmt.Sprintf("%s is not a valid status", r.Status))
+	}
+	if r.VehicleID == nil {
+		// NOTE: Rides logged before vehicles were registered are still edited
+		// with their driverVehicle alone.
+		if stringEmpty(r.DriverVehicle) {
+			errs = append(errs, "driverVehicle can't be empty")
+		}
+		if current == nil {
+			errs = append(errs, "vehicleId can't be empty")
+		}
+	} else {
+		vehicle, err := vehicles(*r.VehicleID)
+		if err != nil {
+			return err
+		}
+		kept := current != nil && current.VehicleID != nil && *current.VehicleID == *r.VehicleID
+		switch {
+		case vehicle == nil:
+			errs = append(errs, fmt.Sprintf("can't find vehicle with ID %d", *r.VehicleID))
+		case vehicle.DeactivatedAt != nil && !kept:
+			errs = append(errs, fmt.Sprintf("vehicle with ID %d is deactivated", *r.VehicleID))
+		}
+	}
+
+	if len(errs) > 0 {
+		return InvalidRideError{Reasons: errs}
+	}
+	return nil
+}
+
+func (d Driver) Validate() error {
+	errs := []string{}
+	if d.Name == "" {
+		errs = append(errs, "name can't be empty")
+	}
+	if d.Vehicle == "" {
+		errs = append(errs, "vehicle can't be empty")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (r Rider) Validate() error {
+	errs := []string{}
+	if r.Name == "" {
+		errs = append(errs, "name can't be empty")
+	}
+	if r.Email != "" {
+		if address, err := mail.ParseAddress(r.Email); err != nil || address.Address != r.Email {
+			errs = append(errs, fmt.Sprintf("%s is not a valid email", r.Email))
+		}
+	}
+	if r.Phone != "" && !validPhone(r.Phone) {
+		errs = append(errs, fmt.Sprintf("%s is not a valid phone number, must be in E.164 format", r.Phone))
+	}
+	switch r.Preferences.ContactBy {
+	case "":
+	case RiderContactEmail:
+		if r.Email == "" {
+			errs = append(errs, "email can't be empty when contacted by email")
+		}
+	case RiderContactPhone:
+		if r.Phone == "" {
+			errs = append(errs, "phone can't be empty when contacted by phone")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("%s is not a valid contactBy, must be either email or phone", r.Preferences.ContactBy))
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Validate validates the vehicle, its plate number is validated by the
+// validator of its country in plates.
+func (v Vehicle) Validate(plates PlateValidators) error {
+	errs := []string{}
+	if v.PlateNumber == "" {
+		errs = append(errs, "plateNumber can't be empty")
+	} else if err := plates.Validate(v.Country, v.PlateNumber); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if v.Make == "" {
+		errs = append(errs, "make can't be empty")
+	}
+	if v.Model == "" {
+		errs = append(errs, "model can't be empty")
+	}
+	if v.Capacity < 1 {
+		errs = append(errs, fmt.Sprintf("%d is not a valid capacity, must be at least 1", v.Capacity))
+	}
+	if !v.Type.Valid() {
+		types := []string{}
+		for _, t := range vehicleTypes() {
+			types = append(types, string(t))
+		}
+		errs = append(errs, fmt.Sprintf("%s is not a valid type, must be one of %s", v.Type, strings.Join(types, ", ")))
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Description is what rides referencing the vehicle are logged with as their
+// driverVehicle.
+func (v Vehicle) Description() string {
+	return fmt.Sprintf("%s %s (%s)", v.Make, v.Model, v.PlateNumber)
+}
+
+func (t VehicleType) Valid() bool {
+	for _, valid := range vehicleTypes() {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// validPhone tells whether the phone number is in E.164 format, a plus sign
+// followed by up to 15 digits.
+func validPhone(phone string) bool {
+	digits := strings.TrimPrefix(phone, "+")
+	if digits == phone || len(digits) < 2 || len(digits) > 15 || digits[0] == '0' {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Pagination) Validate() error {
+	errs := []string{}
+	if p.After != "" && p.Before != "" {
+		errs = append(errs, "after and before can't be used together")
+	}
+	if _, err := p.SortFields(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := p.validateInclude(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SortFields parses Sort. The ID is always the last field, descending unless
+// it's sorted by explicitly, so that rides are never tied.
+func (p Pagination) SortFields() ([]SortField, error) {
+	sortable := map[string]bool{}
+	for _, name := range sortableRideFields() {
+		sortable[name] = true
+	}
+
+	fields := []SortField{}
+	seen := map[string]bool{}
+	if strings.TrimSpace(p.Sort) != "" {
+		for _, part := range strings.Split(p.Sort, ",") {
+			part = strings.TrimSpace(part)
+			field := SortField{Name: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+			if field.Name == "" {
+				return nil, errors.New("sort fields can't be empty")
+			}
+			if !sortable[field.Name] {
+				return nil, fmt.Errorf("%s is not a sortable field, must be one of %s", field.Name, strings.Join(sortableRideFields(), ", "))
+			}
+			if seen[field.Name] {
+				return nil, fmt.Errorf("%s is sorted by more than once", field.Name)
+			}
+			seen[field.Name] = true
+			fields = append(fields, field)
+		}
+	}
+	if !seen["id"] {
+		fields = append(fields, SortField{Name: "id", Desc: true})
+	}
+
+	// NOTE: IDs are unique, fields after it never change the order.
+	for i, field := range fields {
+		if field.Name == "id" {
+			return fields[:i+1], nil
+		}
+	}
+	return fields, nil
+}
+
+// Includes tells whether the part is listed in Include.
+func (p Pagination) Includes(part string) bool {
+	for _, included := range strings.Split(p.Include, ",") {
+		if strings.TrimSpace(included) == part {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Pagination) validateInclude() error {
+	if strings.TrimSpace(p.Include) == "" {
+		return nil
+	}
+	includable := map[string]bool{}
+	for _, part := range pageIncludes() {
+		includable[part] = true
+	}
+	for _, part := range strings.Split(p.Include, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return errors.New("included parts can't be empty")
+		}
+		if !includable[part] {
+			return fmt.Errorf("%s can't be included, must be one of %s", part, strings.Join(pageIncludes(), ", "))
+		}
+	}
+	return nil
+}
+
+// String formats the fields the same way as Sort.
+func (f SortField) String() string {
+	if f.Desc {
+		return "-" + f.Name
+	}
+	return f.Name
+}
+
+func (q NearbyQuery) Center() geo.Point {
+	return geo.Point{Latitude: q.Latitude, Longitude: q.Longitude}
+}
+
+func (q NearbyQuery) Validate() error {
+	errs := []string{}
+	if q.Latitude < -90 || q.Latitude > 90 {
+		errs = append(errs, fmt.Sprintf("%f is not a valid latitude value", q.Latitude))
+	}
+	if q.Longitude < -180 || q.Longitude > 180 {
+		errs = append(errs, fmt.Sprintf("%f is not a valid longitude value", q.Longitude))
+	}
+	if q.Radius <= 0 {
+		errs = append(errs, "radius must be greater than 0")
+	}
+	if q.Radius > maxNearbyRadius {
+		errs = append(errs, fmt.Sprintf("radius must be at most %d", maxNearbyRadius))
+	}
+	if q.Endpoint != RideEndpointStart && q.Endpoint != RideEndpointEnd {
+		errs = append(errs, fmt.Sprintf("%s is not a valid endpoint, must be either start or end", q.Endpoint))
+	}
+	if q.Before != "" {
+		errs = append(errs, "nearby rides can only be paged forward with after")
+	}
+	if q.Sort != "" {
+		errs = append(errs, "nearby rides are always sorted by distance")
+	}
+	if q.Include != "" {
+		errs = append(errs, "nearby rides can't include counts")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (q SearchQuery) Validate() error {
+	errs := []string{}
+	if len(q.Words()) == 0 {
+		errs = append(errs, "q must have a word of at least 3 characters")
+	}
+	if utf8.RuneCountInString(q.Q) > maxSearchLength {
+		errs = append(errs, fmt.Sprintf("q must be at most %d characters", maxSearchLength))
+	}
+	if q.Before != "" {
+		errs = append(errs, "search results can only be paged forward with after")
+	}
+	if q.Sort != "" {
+		errs = append(errs, "search results are always sorted by rank")
+	}
+	if q.Include != "" {
+		errs = append(errs, "search results can't include counts")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Words returns the lower cased words of Q that are long enough to be searched
+// for, shorter ones can't share three characters with names.
+func (q SearchQuery) Words() []string {
+	words := []string{}
+	for _, word := range strings.Fields(strings.ToLower(q.Q)) {
+		if utf8.RuneCountInString(word) >= 3 {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func (q StatsQuery) Validate() error {
+	errs := []string{}
+	valid := false
+	for _, group := range rideStatsGroups() {
+		valid = valid || q.GroupBy == group
+	}
+	if !valid {
+		errs = append(errs, fmt.Sprintf("%s is not a valid groupBy, must be one of %s", q.GroupBy, strings.Join(rideStatsGroups(), ", ")))
+	}
+	if q.After != "" || q.Before != "" || q.Limit > 0 {
+		errs = append(errs, "stats can't be paged")
+	}
+	if q.Sort != "" {
+		errs = append(errs, "stats are always sorted by key")
+	}
+	if q.Include != "" {
+		errs = append(errs, "stats can't include counts")
+	}
 
 	if len(errs) > 0 {
 		return errors.New(strings.Join(errs, "; "))
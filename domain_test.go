@@ -1,17 +1,43 @@
 package domain_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	domain "github.com/hawarir/backend-coding-test"
 	"github.com/stretchr/testify/assert"
 )
 
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
 func TestRideValidation(t *testing.T) {
+	vehicles := func(id int64) (*domain.Vehicle, error) {
+		switch id {
+		case 7:
+			return &domain.Vehicle{ID: 7}, nil
+		case 8:
+			return &domain.Vehicle{ID: 8, DeactivatedAt: timePtr(time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC))}, nil
+		case 9:
+			return nil, errors.New("Select By ID error")
+		}
+		return nil, nil
+	}
 	testCases := []struct {
 		testName    string
 		ride        domain.Ride
+		current     *domain.Ride
 		expectedErr string
+		// lookupErr tells whether the error is returned by the vehicle
+		// lookup rather than rejecting the ride.
+		lookupErr bool
 	}{
 		{
 			testName: "When startLatitude and EndLatitude is incorrect",
@@ -23,6 +49,7 @@ func TestRideValidation(t *testing.T) {
 				RiderName:      "John Doe",
 				DriverName:     "Driver",
 				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(7),
 			},
 			expectedErr: "-91.000000 is not a valid latitude value; 91.000000 is not a valid latitude value",
 		},
@@ -36,11 +63,12 @@ func TestRideValidation(t *testing.T) {
 				RiderName:      "John Doe",
 				DriverName:     "Driver",
 				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(7),
 			},
 			expectedErr: "-181.000000 is not a valid longitude value; 181.000000 is not a valid longitude value",
 		},
 		{
-			testName: "When riderName, driverName, and driverVehicle is empty",
+			testName: "When riderName, driverName, driverVehicle and vehicleId is empty",
 			ride: domain.Ride{
 				StartLatitude:  -90,
 				StartLongitude: -180,
@@ -50,7 +78,108 @@ func TestRideValidation(t *testing.T) {
 				DriverName:     "",
 				DriverVehicle:  "",
 			},
-			expectedErr: "riderName can't be empty; driverName can't be empty; driverVehicle can't be empty",
+			expectedErr: "riderName can't be empty; driverName can't be empty; driverVehicle can't be empty; vehicleId can't be empty",
+		},
+		{
+			testName: "When ride ends before it starts",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(7),
+				StartedAt:      timePtr(time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC)),
+				EndedAt:        timePtr(time.Date(2021, 4, 1, 9, 0, 0, 0, time.UTC)),
+			},
+			expectedErr: "endedAt can't be before startedAt",
+		},
+		{
+			testName: "When status is unknown",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(7),
+				Status:         "teleported",
+			},
+			expectedErr: "teleported is not a valid status",
+		},
+		{
+			testName: "When vehicle is unknown",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				VehicleID:      int64Ptr(6),
+			},
+			expectedErr: "can't find vehicle with ID 6",
+		},
+		{
+			testName: "When vehicle is deactivated",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(8),
+			},
+			current:     &domain.Ride{VehicleID: int64Ptr(7)},
+			expectedErr: "vehicle with ID 8 is deactivated",
+		},
+		{
+			testName: "When edited ride keeps its deactivated vehicle",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(8),
+			},
+			current: &domain.Ride{VehicleID: int64Ptr(8)},
+		},
+		{
+			testName: "When edited ride has no vehicle",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+			},
+			current: &domain.Ride{},
+		},
+		{
+			testName: "When vehicle can't be looked up",
+			ride: domain.Ride{
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(9),
+			},
+			expectedErr: "Select By ID error",
+			lookupErr:   true,
 		},
 		{
 			testName: "When values are correct",
@@ -62,13 +191,303 @@ func TestRideValidation(t *testing.T) {
 				RiderName:      "John Doe",
 				DriverName:     "Driver",
 				DriverVehicle:  "Car",
+				VehicleID:      int64Ptr(7),
 			},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
-			err := tc.ride.Validate()
+			err := tc.ride.Validate(tc.current, vehicles)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				var invalid domain.InvalidRideError
+				assert.Equal(t, !tc.lookupErr, errors.As(err, &invalid))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDriverValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		driver      domain.Driver
+		expectedErr string
+	}{
+		{
+			testName:    "When name and vehicle are empty",
+			driver:      domain.Driver{Name: "", Vehicle: ""},
+			expectedErr: "name can't be empty; vehicle can't be empty",
+		},
+		{
+			testName: "When values are correct",
+			driver:   domain.Driver{Name: "Driver", Vehicle: "Car"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.driver.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRiderValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		rider       domain.Rider
+		expectedErr string
+	}{
+		{
+			testName:    "When name is empty",
+			rider:       domain.Rider{Name: ""},
+			expectedErr: "name can't be empty",
+		},
+		{
+			testName:    "When email and phone are malformed",
+			rider:       domain.Rider{Name: "John Doe", Email: "John <john@example.com>", Phone: "081234567890"},
+			expectedErr: "John <john@example.com> is not a valid email; 081234567890 is not a valid phone number, must be in E.164 format",
+		},
+		{
+			testName:    "When contacted by email without an email",
+			rider:       domain.Rider{Name: "John Doe", Phone: "+6281234567890", Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactEmail}},
+			expectedErr: "email can't be empty when contacted by email",
+		},
+		{
+			testName:    "When contacted by phone without a phone",
+			rider:       domain.Rider{Name: "John Doe", Email: "john@example.com", Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactPhone}},
+			expectedErr: "phone can't be empty when contacted by phone",
+		},
+		{
+			testName:    "When contactBy is unknown",
+			rider:       domain.Rider{Name: "John Doe", Preferences: domain.RiderPreferences{ContactBy: "pigeon"}},
+			expectedErr: "pigeon is not a valid contactBy, must be either email or phone",
+		},
+		{
+			testName: "When values are correct",
+			rider: domain.Rider{
+				Name:        "John Doe",
+				Email:       "john@example.com",
+				Phone:       "+6281234567890",
+				Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactPhone, Language: "id"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.rider.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVehicleValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		vehicle     domain.Vehicle
+		expectedErr string
+	}{
+		{
+			testName:    "When every value is missing",
+			vehicle:     domain.Vehicle{},
+			expectedErr: "plateNumber can't be empty; make can't be empty; model can't be empty; 0 is not a valid capacity, must be at least 1;  is not a valid type, must be one of car, motorcycle, van",
+		},
+		{
+			testName:    "When country has no plate validator",
+			vehicle:     domain.Vehicle{PlateNumber: "AB12 CDE", Country: "GB", Make: "Toyota", Model: "Avanza", Capacity: 6, Type: domain.VehicleTypeCar},
+			expectedErr: "GB is not a supported country, must be one of ID, SG",
+		},
+		{
+			testName:    "When plate number doesn't match the format of the country",
+			vehicle:     domain.Vehicle{PlateNumber: "SBA 1234 A", Country: "ID", Make: "Honda", Model: "Vario", Capacity: 1, Type: "scooter"},
+			expectedErr: "SBA 1234 A is not a valid plate number, must be formatted like B 1234 XYZ; scooter is not a valid type, must be one of car, motorcycle, van",
+		},
+		{
+			testName: "When values are correct",
+			vehicle:  domain.Vehicle{PlateNumber: "B 1234 XYZ", Country: "ID", Make: "Toyota", Model: "Avanza", Capacity: 6, Type: domain.VehicleTypeCar},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.vehicle.Validate(domain.DefaultPlateValidators())
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRideStatusTransition(t *testing.T) {
+	testCases := []struct {
+		testName string
+		from     domain.RideStatus
+		to       domain.RideStatus
+		allowed  bool
+	}{
+		{testName: "When requested ride is accepted", from: domain.RideStatusRequested, to: domain.RideStatusAccepted, allowed: true},
+		{testName: "When requested ride is started", from: domain.RideStatusRequested, to: domain.RideStatusStarted, allowed: false},
+		{testName: "When accepted ride is started", from: domain.RideStatusAccepted, to: domain.RideStatusStarted, allowed: true},
+		{testName: "When started ride is completed", from: domain.RideStatusStarted, to: domain.RideStatusCompleted, allowed: true},
+		{testName: "When started ride is cancelled", from: domain.RideStatusStarted, to: domain.RideStatusCancelled, allowed: true},
+		{testName: "When completed ride is cancelled", from: domain.RideStatusCompleted, to: domain.RideStatusCancelled, allowed: false},
+		{testName: "When cancelled ride is requested again", from: domain.RideStatusCancelled, to: domain.RideStatusRequested, allowed: false},
+		{testName: "When status is unknown", from: "teleported", to: domain.RideStatusCompleted, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.allowed, tc.from.CanTransitionTo(tc.to))
+		})
+	}
+}
+
+func TestRideStatusPredecessors(t *testing.T) {
+	assert.Equal(t, []domain.RideStatus{}, domain.RideStatusRequested.Predecessors())
+	assert.Equal(t, []domain.RideStatus{domain.RideStatusRequested}, domain.RideStatusAccepted.Predecessors())
+	assert.Equal(t,
+		[]domain.RideStatus{domain.RideStatusRequested, domain.RideStatusAccepted, domain.RideStatusStarted},
+		domain.RideStatusCancelled.Predecessors(),
+	)
+}
+
+func TestNearbyQueryValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		query       domain.NearbyQuery
+		expectedErr string
+	}{
+		{
+			testName:    "When center is out of range",
+			query:       domain.NearbyQuery{Latitude: -91, Longitude: 181, Radius: 1000, Endpoint: domain.RideEndpointStart},
+			expectedErr: "-91.000000 is not a valid latitude value; 181.000000 is not a valid longitude value",
+		},
+		{
+			testName:    "When radius and endpoint are invalid",
+			query:       domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 0, Endpoint: "middle"},
+			expectedErr: "radius must be greater than 0; middle is not a valid endpoint, must be either start or end",
+		},
+		{
+			testName:    "When radius is too large",
+			query:       domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 50001, Endpoint: domain.RideEndpointStart},
+			expectedErr: "radius must be at most 50000",
+		},
+		{
+			testName:    "When paged back",
+			query:       domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 1000, Endpoint: domain.RideEndpointEnd, Pagination: domain.Pagination{Before: "1"}},
+			expectedErr: "nearby rides can only be paged forward with after",
+		},
+		{
+			testName:    "When sorted",
+			query:       domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 1000, Endpoint: domain.RideEndpointEnd, Pagination: domain.Pagination{Sort: "riderName"}},
+			expectedErr: "nearby rides are always sorted by distance",
+		},
+		{
+			testName:    "When including counts",
+			query:       domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 1000, Endpoint: domain.RideEndpointEnd, Pagination: domain.Pagination{Include: "total"}},
+			expectedErr: "nearby rides can't include counts",
+		},
+		{
+			testName: "When values are correct",
+			query:    domain.NearbyQuery{Latitude: -6.2, Longitude: 106.8, Radius: 1000, Endpoint: domain.RideEndpointEnd},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.query.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSearchQueryValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		query       domain.SearchQuery
+		expectedErr string
+	}{
+		{
+			testName:    "When every word is too short",
+			query:       domain.SearchQuery{Q: " jo  d "},
+			expectedErr: "q must have a word of at least 3 characters",
+		},
+		{
+			testName:    "When q is too long",
+			query:       domain.SearchQuery{Q: strings.Repeat("john ", 21)},
+			expectedErr: "q must be at most 100 characters",
+		},
+		{
+			testName:    "When paged back, sorted and including counts",
+			query:       domain.SearchQuery{Q: "john", Pagination: domain.Pagination{Before: "1", Sort: "riderName", Include: "facets"}},
+			expectedErr: "search results can only be paged forward with after; search results are always sorted by rank; search results can't include counts",
+		},
+		{
+			testName: "When values are correct",
+			query:    domain.SearchQuery{Q: "jo doe", Pagination: domain.Pagination{After: "1", Limit: 10}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.query.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSearchQueryWords(t *testing.T) {
+	query := domain.SearchQuery{Q: "  Jo JOHN\tdoé  "}
+	assert.Equal(t, []string{"john", "doé"}, query.Words())
+}
+
+func TestStatsQueryValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		query       domain.StatsQuery
+		expectedErr string
+	}{
+		{
+			testName:    "When groupBy is empty",
+			query:       domain.StatsQuery{},
+			expectedErr: " is not a valid groupBy, must be one of day, week, driverName, driverVehicle",
+		},
+		{
+			testName:    "When paged, sorted and including counts",
+			query:       domain.StatsQuery{GroupBy: "week", Pagination: domain.Pagination{Limit: 10, Sort: "riderName", Include: "total"}},
+			expectedErr: "stats can't be paged; stats are always sorted by key; stats can't include counts",
+		},
+		{
+			testName: "When values are correct",
+			query:    domain.StatsQuery{GroupBy: "driverVehicle", Pagination: domain.Pagination{MinDistance: 1000, IncludeDeleted: true}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.query.Validate()
 			if tc.expectedErr != "" {
 				assert.EqualError(t, err, tc.expectedErr)
 			} else {
@@ -77,3 +496,120 @@ func TestRideValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestPaginationValidation(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		page        domain.Pagination
+		expectedErr string
+	}{
+		{
+			testName:    "When paged both ways",
+			page:        domain.Pagination{After: "1", Before: "2"},
+			expectedErr: "after and before can't be used together",
+		},
+		{
+			testName: "When paged forward",
+			page:     domain.Pagination{After: "1"},
+		},
+		{
+			testName: "When paged back",
+			page:     domain.Pagination{Before: "2"},
+		},
+		{
+			testName:    "When sorted by an unknown field and paged both ways",
+			page:        domain.Pagination{After: "1", Before: "2", Sort: "-distance,password"},
+			expectedErr: "after and before can't be used together; password is not a sortable field, must be one of id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, createdAt, updatedAt",
+		},
+		{
+			testName: "When sorted by known fields",
+			page:     domain.Pagination{Sort: "-distance,riderName"},
+		},
+		{
+			testName:    "When including an unknown part",
+			page:        domain.Pagination{Include: "total,rides"},
+			expectedErr: "rides can't be included, must be one of total, facets",
+		},
+		{
+			testName:    "When an included part is empty",
+			page:        domain.Pagination{Include: "total,"},
+			expectedErr: "included parts can't be empty",
+		},
+		{
+			testName: "When including the total and facets",
+			page:     domain.Pagination{Include: "facets, total"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := tc.page.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPaginationIncludes(t *testing.T) {
+	page := domain.Pagination{Include: "total, facets"}
+	assert.True(t, page.Includes("total"))
+	assert.True(t, page.Includes("facets"))
+	assert.False(t, domain.Pagination{Include: "total"}.Includes("facets"))
+	assert.False(t, domain.Pagination{}.Includes("total"))
+}
+
+func TestPaginationSortFields(t *testing.T) {
+	testCases := []struct {
+		testName       string
+		sort           string
+		expectedFields []domain.SortField
+		expectedErr    string
+	}{
+		{
+			testName:       "When sort is empty, return latest first",
+			expectedFields: []domain.SortField{{Name: "id", Desc: true}},
+		},
+		{
+			testName: "When sorted by several fields, return them followed by ID",
+			sort:     "-distance, riderName",
+			expectedFields: []domain.SortField{
+				{Name: "distance", Desc: true},
+				{Name: "riderName"},
+				{Name: "id", Desc: true},
+			},
+		},
+		{
+			testName: "When sorted by ID, return fields up to it",
+			sort:     "riderName,id,distance",
+			expectedFields: []domain.SortField{
+				{Name: "riderName"},
+				{Name: "id"},
+			},
+		},
+		{
+			testName:    "When a field is sorted by twice, return error",
+			sort:        "distance,-distance",
+			expectedErr: "distance is sorted by more than once",
+		},
+		{
+			testName:    "When a field is empty, return error",
+			sort:        "distance,",
+			expectedErr: "sort fields can't be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			fields, err := domain.Pagination{Sort: tc.sort}.SortFields()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedFields, fields)
+		})
+	}
+}
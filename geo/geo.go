@@ -0,0 +1,121 @@
+// Package geo provides great-circle calculations on a spherical earth model.
+package geo
+
+import "math"
+
+// EarthRadiusMeters is the mean earth radius as defined by the IUGG.
+const EarthRadiusMeters = 6371008.8
+
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Distance returns the great-circle distance between a and b in meters using
+// the haversine formula.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Latitude), toRadians(b.Latitude)
+	dLat := lat2 - lat1
+	dLong := toRadians(b.Longitude - a.Longitude)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLong/2), 2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InitialBearing returns the bearing to follow from a to reach b along the
+// great circle, in degrees clockwise from north within [0, 360).
+func InitialBearing(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Latitude), toRadians(b.Latitude)
+	dLong := toRadians(b.Longitude - a.Longitude)
+
+	y := math.Sin(dLong) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLong)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// BoundingBox is the smallest latitude/longitude rectangle containing a circle
+// on the earth surface. MinLongitude is greater than MaxLongitude when the box
+// crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// NewBoundingBox returns the bounding box of every point within radius meters
+// of center.
+func NewBoundingBox(center Point, radius float64) BoundingBox {
+	angular := radius / EarthRadiusMeters
+	lat := toRadians(center.Latitude)
+	minLat, maxLat := lat-angular, lat+angular
+
+	// NOTE: When the circle covers a pole every longitude is included.
+	if minLat <= -math.Pi/2 || maxLat >= math.Pi/2 || math.Sin(angular)/math.Cos(lat) >= 1 {
+		return BoundingBox{
+			MinLatitude:  toDegrees(math.Max(minLat, -math.Pi/2)),
+			MinLongitude: -180,
+			MaxLatitude:  toDegrees(math.Min(maxLat, math.Pi/2)),
+			MaxLongitude: 180,
+		}
+	}
+
+	dLong := toDegrees(math.Asin(math.Sin(angular) / math.Cos(lat)))
+	minLong, maxLong := center.Longitude-dLong, center.Longitude+dLong
+	if minLong < -180 {
+		minLong += 360
+	}
+	if maxLong > 180 {
+		maxLong -= 360
+	}
+	return BoundingBox{
+		MinLatitude:  toDegrees(minLat),
+		MinLongitude: minLong,
+		MaxLatitude:  toDegrees(maxLat),
+		MaxLongitude: maxLong,
+	}
+}
+
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLongitude > b.MaxLongitude
+}
+
+// Vector is where a point lies on the unit sphere. The straight line between
+// two vectors is shorter the closer their points are, so points can be ordered
+// by their distance with arithmetic alone.
+type Vector struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+func NewVector(p Point) Vector {
+	lat, long := toRadians(p.Latitude), toRadians(p.Longitude)
+	return Vector{
+		X: math.Cos(lat) * math.Cos(long),
+		Y: math.Cos(lat) * math.Sin(long),
+		Z: math.Sin(lat),
+	}
+}
+
+// SquaredChord returns the squared length of the straight line between v and
+// w, it's ordered the same as the distance between their points.
+func (v Vector) SquaredChord(w Vector) float64 {
+	dx, dy, dz := v.X-w.X, v.Y-w.Y, v.Z-w.Z
+	return dx*dx + dy*dy + dz*dz
+}
+
+// SquaredChordOf returns the squared chord between points distance meters
+// apart.
+func SquaredChordOf(distance float64) float64 {
+	chord := 2 * math.Sin(math.Min(distance/EarthRadiusMeters, math.Pi)/2)
+	return chord * chord
+}
@@ -0,0 +1,165 @@
+package geo_test
+
+import (
+	"testing"
+
+	"github.com/hawarir/backend-coding-test/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		testName string
+		a        geo.Point
+		b        geo.Point
+		expected float64
+	}{
+		{
+			testName: "When both points are the same, return zero",
+			a:        geo.Point{Latitude: -6.2, Longitude: 106.8},
+			b:        geo.Point{Latitude: -6.2, Longitude: 106.8},
+			expected: 0,
+		},
+		{
+			testName: "When points are a degree apart on the equator",
+			a:        geo.Point{Latitude: 0, Longitude: 0},
+			b:        geo.Point{Latitude: 0, Longitude: 1},
+			expected: 111195.08,
+		},
+		{
+			testName: "When points are on opposite poles",
+			a:        geo.Point{Latitude: -90, Longitude: 0},
+			b:        geo.Point{Latitude: 90, Longitude: 0},
+			expected: 20015114.44,
+		},
+		{
+			testName: "When points are Jakarta and Bandung",
+			a:        geo.Point{Latitude: -6.2088, Longitude: 106.8456},
+			b:        geo.Point{Latitude: -6.9175, Longitude: 107.6191},
+			expected: 116236.56,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, geo.Distance(tc.a, tc.b), 0.01)
+		})
+	}
+}
+
+func TestInitialBearing(t *testing.T) {
+	testCases := []struct {
+		testName string
+		a        geo.Point
+		b        geo.Point
+		expected float64
+	}{
+		{
+			testName: "When heading due north",
+			a:        geo.Point{Latitude: 0, Longitude: 0},
+			b:        geo.Point{Latitude: 1, Longitude: 0},
+			expected: 0,
+		},
+		{
+			testName: "When heading due east",
+			a:        geo.Point{Latitude: 0, Longitude: 0},
+			b:        geo.Point{Latitude: 0, Longitude: 1},
+			expected: 90,
+		},
+		{
+			testName: "When heading due south",
+			a:        geo.Point{Latitude: 1, Longitude: 0},
+			b:        geo.Point{Latitude: 0, Longitude: 0},
+			expected: 180,
+		},
+		{
+			testName: "When heading due west, return a positive bearing",
+			a:        geo.Point{Latitude: 0, Longitude: 1},
+			b:        geo.Point{Latitude: 0, Longitude: 0},
+			expected: 270,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, geo.InitialBearing(tc.a, tc.b), 0.000001)
+		})
+	}
+}
+
+func TestNewBoundingBox(t *testing.T) {
+	testCases := []struct {
+		testName string
+		center   geo.Point
+		radius   float64
+		expected geo.BoundingBox
+		crosses  bool
+	}{
+		{
+			testName: "When circle is on the equator",
+			center:   geo.Point{Latitude: 0, Longitude: 0},
+			radius:   111195.08,
+			expected: geo.BoundingBox{MinLatitude: -1, MinLongitude: -1, MaxLatitude: 1, MaxLongitude: 1},
+		},
+		{
+			testName: "When circle crosses the antimeridian, wrap longitude",
+			center:   geo.Point{Latitude: 0, Longitude: 179.5},
+			radius:   111195.08,
+			expected: geo.BoundingBox{MinLatitude: -1, MinLongitude: 178.5, MaxLatitude: 1, MaxLongitude: -179.5},
+			crosses:  true,
+		},
+		{
+			testName: "When circle covers a pole, include every longitude",
+			center:   geo.Point{Latitude: 89.5, Longitude: 10},
+			radius:   111195.08,
+			expected: geo.BoundingBox{MinLatitude: 88.5, MinLongitude: -180, MaxLatitude: 90, MaxLongitude: 180},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			box := geo.NewBoundingBox(tc.center, tc.radius)
+			assert.InDelta(t, tc.expected.MinLatitude, box.MinLatitude, 0.000001)
+			assert.InDelta(t, tc.expected.MinLongitude, box.MinLongitude, 0.000001)
+			assert.InDelta(t, tc.expected.MaxLatitude, box.MaxLatitude, 0.000001)
+			assert.InDelta(t, tc.expected.MaxLongitude, box.MaxLongitude, 0.000001)
+			assert.Equal(t, tc.crosses, box.CrossesAntimeridian())
+		})
+	}
+}
+
+func TestVector_SquaredChord(t *testing.T) {
+	testCases := []struct {
+		testName string
+		a        geo.Point
+		b        geo.Point
+	}{
+		{
+			testName: "When both points are the same",
+			a:        geo.Point{Latitude: -6.2, Longitude: 106.8},
+			b:        geo.Point{Latitude: -6.2, Longitude: 106.8},
+		},
+		{
+			testName: "When points are a degree apart on the equator",
+			a:        geo.Point{Latitude: 0, Longitude: 0},
+			b:        geo.Point{Latitude: 0, Longitude: 1},
+		},
+		{
+			testName: "When points are across the antimeridian",
+			a:        geo.Point{Latitude: 10, Longitude: 179.9},
+			b:        geo.Point{Latitude: 10.1, Longitude: -179.9},
+		},
+		{
+			testName: "When points are on opposite poles",
+			a:        geo.Point{Latitude: -90, Longitude: 0},
+			b:        geo.Point{Latitude: 90, Longitude: 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			chord := geo.NewVector(tc.a).SquaredChord(geo.NewVector(tc.b))
+			assert.InDelta(t, geo.SquaredChordOf(geo.Distance(tc.a, tc.b)), chord, 1e-12)
+		})
+	}
+}
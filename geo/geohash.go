@@ -0,0 +1,111 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	// GeohashPrecision is the number of characters stored for every point, which
+	// narrows a point down to a cell of a few centimeters.
+	GeohashPrecision = 12
+
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// EncodeGeohash returns the geohash of p with the given number of characters.
+func EncodeGeohash(p Point, precision int) string {
+	var hash strings.Builder
+	minLat, maxLat := -90.0, 90.0
+	minLong, maxLong := -180.0, 180.0
+	evenBit := true
+	bit, ch := 0, 0
+
+	for hash.Len() < precision {
+		if evenBit {
+			mid := (minLong + maxLong) / 2
+			if p.Longitude >= mid {
+				ch = ch<<1 | 1
+				minLong = mid
+			} else {
+				ch <<= 1
+				maxLong = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if p.Latitude >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		evenBit = !evenBit
+
+		if bit++; bit == 5 {
+			hash.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// GeohashCellSize returns the height and width in degrees of a geohash cell with
+// the given number of characters.
+func GeohashCellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	latBits := bits / 2
+	longBits := bits - latBits
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(longBits))
+}
+
+// GeohashPrefixes returns the geohash cells covering the bounding box, using the
+// longest prefix that needs no more than maxCells cells. Every point inside the
+// box has a geohash starting with one of the returned prefixes. It returns nil
+// when the box can't be covered, in which case every geohash may match.
+func GeohashPrefixes(box BoundingBox, maxCells int) []string {
+	longRanges := [][2]float64{{box.MinLongitude, box.MaxLongitude}}
+	if box.CrossesAntimeridian() {
+		longRanges = [][2]float64{{box.MinLongitude, 180}, {-180, box.MaxLongitude}}
+	}
+
+	for precision := GeohashPrecision; precision > 0; precision-- {
+		height, width := GeohashCellSize(precision)
+		latFrom, latTo := cellIndex(box.MinLatitude+90, height, 180), cellIndex(box.MaxLatitude+90, height, 180)
+		latCells := latTo - latFrom + 1
+
+		cells := 0
+		for _, longRange := range longRanges {
+			cells += latCells * (cellIndex(longRange[1]+180, width, 360) - cellIndex(longRange[0]+180, width, 360) + 1)
+		}
+		if cells > maxCells {
+			continue
+		}
+
+		prefixes := make([]string, 0, cells)
+		for _, longRange := range longRanges {
+			for i := latFrom; i <= latTo; i++ {
+				for j := cellIndex(longRange[0]+180, width, 360); j <= cellIndex(longRange[1]+180, width, 360); j++ {
+					center := Point{
+						Latitude:  -90 + (float64(i)+0.5)*height,
+						Longitude: -180 + (float64(j)+0.5)*width,
+					}
+					prefixes = append(prefixes, EncodeGeohash(center, precision))
+				}
+			}
+		}
+		return prefixes
+	}
+	return nil
+}
+
+// cellIndex returns which cell of the given size offset falls into when span is
+// divided into cells, the upper edge of the span belongs to the last cell.
+func cellIndex(offset, size, span float64) int {
+	index := int(math.Floor(offset / size))
+	if last := int(math.Round(span/size)) - 1; index > last {
+		return last
+	}
+	return index
+}
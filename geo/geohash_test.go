@@ -0,0 +1,107 @@
+package geo_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hawarir/backend-coding-test/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		point     geo.Point
+		precision int
+		expected  string
+	}{
+		{
+			testName:  "When encoding Jakarta",
+			point:     geo.Point{Latitude: -6.2088, Longitude: 106.8456},
+			precision: 7,
+			expected:  "qqguxmd",
+		},
+		{
+			testName:  "When encoding with full precision",
+			point:     geo.Point{Latitude: 57.64911, Longitude: 10.40744},
+			precision: 11,
+			expected:  "u4pruydqqvj",
+		},
+		{
+			testName:  "When encoding the north east corner",
+			point:     geo.Point{Latitude: 90, Longitude: 180},
+			precision: 3,
+			expected:  "zzz",
+		},
+		{
+			testName:  "When encoding the south west corner",
+			point:     geo.Point{Latitude: -90, Longitude: -180},
+			precision: 3,
+			expected:  "000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, geo.EncodeGeohash(tc.point, tc.precision))
+		})
+	}
+}
+
+func TestGeohashPrefixes(t *testing.T) {
+	hasPrefix := func(prefixes []string, hash string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(hash, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	testCases := []struct {
+		testName string
+		center   geo.Point
+		radius   float64
+		inside   []geo.Point
+	}{
+		{
+			testName: "When box is small",
+			center:   geo.Point{Latitude: -6.2088, Longitude: 106.8456},
+			radius:   2000,
+			inside: []geo.Point{
+				{Latitude: -6.2088, Longitude: 106.8456},
+				{Latitude: -6.22, Longitude: 106.83},
+				{Latitude: -6.195, Longitude: 106.86},
+			},
+		},
+		{
+			testName: "When box crosses the antimeridian",
+			center:   geo.Point{Latitude: 0, Longitude: 179.99},
+			radius:   5000,
+			inside: []geo.Point{
+				{Latitude: 0, Longitude: 179.98},
+				{Latitude: 0.01, Longitude: -179.99},
+			},
+		},
+		{
+			testName: "When box covers a pole",
+			center:   geo.Point{Latitude: 89.99, Longitude: 0},
+			radius:   5000,
+			inside: []geo.Point{
+				{Latitude: 89.99, Longitude: 0},
+				{Latitude: 89.98, Longitude: 179},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			prefixes := geo.GeohashPrefixes(geo.NewBoundingBox(tc.center, tc.radius), 16)
+			assert.NotEmpty(t, prefixes)
+			assert.LessOrEqual(t, len(prefixes), 16)
+			for _, p := range tc.inside {
+				assert.True(t, hasPrefix(prefixes, geo.EncodeGeohash(p, geo.GeohashPrecision)), "%v is not covered by %v", p, prefixes)
+			}
+		})
+	}
+}
@@ -3,10 +3,11 @@ module github.com/hawarir/backend-coding-test
 go 1.16
 
 require (
-	github.com/DATA-DOG/go-sqlmock v1.5.0 // indirect
-	github.com/Masterminds/squirrel v1.5.0 // indirect
-	github.com/golang/mock v1.5.0 // indirect
-	github.com/labstack/echo/v4 v4.2.2 // indirect
-	github.com/mattn/go-sqlite3 v1.14.6 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
+	github.com/DATA-DOG/go-sqlmock v1.5.0
+	github.com/Masterminds/squirrel v1.5.0
+	github.com/golang/mock v1.5.0
+	github.com/labstack/echo/v4 v4.2.2
+	github.com/lib/pq v1.10.0
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/stretchr/testify v1.7.0
 )
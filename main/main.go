@@ -1,34 +1,208 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	domain "github.com/hawarir/backend-coding-test"
 	"github.com/hawarir/backend-coding-test/controller"
 	"github.com/hawarir/backend-coding-test/repository"
 
 	"github.com/labstack/echo/v4"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// defaultQueryTimeout is used when QUERY_TIMEOUT isn't set.
+const defaultQueryTimeout = 30 * time.Second
+
+// defaultMaxImportSize is used when MAX_IMPORT_SIZE isn't set.
+const defaultMaxImportSize = 32 << 20
+
+// memoryDSN keeps rides in memory instead of a database.
+const memoryDSN = ":memory-go:"
+
+// Usage:
+//
+//	main [-memory]              migrates the database and serves the API
+//	main migrate [up]           applies every pending migration
+//	main migrate down [steps]   reverts the latest migrations, one by default
+//	main migrate status         lists the migrations and whether they're applied
+//
+// Rides are kept in memory and lost on exit with -memory or when DB_PATH is
+// :memory-go:, there is nothing to migrate then. Pagination cursors are encrypted
+// with CURSOR_KEY, a random key is used when it isn't set so cursors stop
+// working once the server restarts. Imports are limited to MAX_IMPORT_SIZE
+// bytes, 32 MiB by default.
 func main() {
-	db, err := sql.Open("sqlite3", os.Getenv("DB_PATH"))
-	if err != nil {
-		log.Fatalf("Failed to open connection to database: %s", err)
+	memory := flag.Bool("memory", false, "keep rides in memory instead of the DB_PATH database")
+	flag.Parse()
+
+	queryTimeout := defaultQueryTimeout
+	if value := os.Getenv("QUERY_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid query timeout: %s", err)
+		}
+		queryTimeout = timeout
+	}
+
+	maxImportSize := int64(defaultMaxImportSize)
+	if value := os.Getenv("MAX_IMPORT_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid max import size: %s must be a positive number of bytes", value)
+		}
+		maxImportSize = size
 	}
-	defer db.Close()
 
-	rideRepo := repository.NewRideRepository(db)
+	cursorKey := []byte(os.Getenv("CURSOR_KEY"))
+	if len(cursorKey) == 0 {
+		cursorKey = make([]byte, 32)
+		if _, err := rand.Read(cursorKey); err != nil {
+			log.Fatalf("Failed to generate cursor key: %s", err)
+		}
+	}
+
+	dsn := os.Getenv("DB_PATH")
+	if *memory {
+		dsn = memoryDSN
+	}
 
-	if err := rideRepo.InitTable(); err != nil {
-		log.Fatalf("Failed to initialize table: %s", err)
+	var repos repositories
+	if dsn == memoryDSN {
+		if flag.NArg() > 0 && flag.Arg(0) == "migrate" {
+			log.Fatal("Failed to migrate database: rides kept in memory have no migrations")
+		}
+		repos = repositories{
+			rides:    repository.NewMemoryRideRepository(),
+			drivers:  repository.NewMemoryDriverRepository(),
+			riders:   repository.NewMemoryRiderRepository(),
+			vehicles: repository.NewMemoryVehicleRepository(),
+		}
+	} else {
+		db, opened, migrator, err := openDatabase(dsn)
+		if err != nil {
+			log.Fatalf("Failed to open connection to database: %s", err)
+		}
+		defer db.Close()
+
+		if flag.NArg() > 0 && flag.Arg(0) == "migrate" {
+			if err := migrate(context.Background(), migrator, flag.Args()[1:]); err != nil {
+				log.Fatalf("Failed to migrate database: %s", err)
+			}
+			return
+		}
+
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Failed to migrate database: %s", err)
+		}
+		repos = opened
 	}
 
 	e := echo.New()
-	controller.SetupRideController(e, rideRepo)
+	controller.SetupRideController(e, controller.RideConfig{
+		Rides:         repos.rides,
+		Drivers:       repos.drivers,
+		Riders:        repos.riders,
+		Vehicles:      repos.vehicles,
+		QueryTimeout:  queryTimeout,
+		MaxImportSize: maxImportSize,
+		CursorKey:     cursorKey,
+	})
+	controller.SetupDriverController(e, repos.drivers, queryTimeout, cursorKey)
+	controller.SetupRiderController(e, repos.riders, repos.rides, queryTimeout)
+	controller.SetupVehicleController(e, repos.vehicles, domain.DefaultPlateValidators(), queryTimeout, cursorKey)
 
 	e.Logger.Fatal(e.Start(fmt.Sprintf(":%s", os.Getenv("PORT"))))
 }
+
+// repositories are the repositories of a database.
+type repositories struct {
+	rides    domain.RideRepository
+	drivers  domain.DriverRepository
+	riders   domain.RiderRepository
+	vehicles domain.VehicleRepository
+}
+
+// openDatabase picks the database from the scheme of the DSN, a DSN without a
+// scheme is the path of an SQLite database.
+func openDatabase(dsn string) (*sql.DB, repositories, *repository.Migrator, error) {
+	scheme := ""
+	if parts := strings.SplitN(dsn, "://", 2); len(parts) == 2 {
+		scheme = parts[0]
+	}
+
+	switch scheme {
+	case "postgres", "postgresql":
+		db, err := sql.Open("postgres", dsn)
+		repos := repositories{
+			rides:    repository.NewPostgresRideRepository(db),
+			drivers:  repository.NewPostgresDriverRepository(db),
+			riders:   repository.NewPostgresRiderRepository(db),
+			vehicles: repository.NewPostgresVehicleRepository(db),
+		}
+		return db, repos, repository.NewPostgresMigrator(db), err
+	case "", "sqlite", "sqlite3":
+		db, err := sql.Open("sqlite3", strings.TrimPrefix(dsn, scheme+"://"))
+		repos := repositories{
+			rides:    repository.NewRideRepository(db),
+			drivers:  repository.NewDriverRepository(db),
+			riders:   repository.NewRiderRepository(db),
+			vehicles: repository.NewVehicleRepository(db),
+		}
+		return db, repos, repository.NewMigrator(db), err
+	default:
+		return nil, repositories{}, nil, fmt.Errorf("%s is not a supported database", scheme)
+	}
+}
+
+func migrate(ctx context.Context, migrator *repository.Migrator, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		migrations, err := migrator.Up(ctx)
+		for _, migration := range migrations {
+			log.Printf("Applied %04d_%s", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("%s is not a valid number of steps", args[1])
+			}
+		}
+		migrations, err := migrator.Down(ctx, steps)
+		for _, migration := range migrations {
+			log.Printf("Reverted %04d_%s", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = fmt.Sprintf("applied at %s", status.AppliedAt.Format(time.RFC3339))
+			}
+			log.Printf("%04d_%s %s", status.Version, status.Name, state)
+		}
+		return err
+	default:
+		return fmt.Errorf("%s is not a migrate command, must be one of up, down or status", command)
+	}
+}
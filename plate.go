@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	// PlateValidator returns an error telling why the plate number isn't
+	// well-formed in the country the validator is for.
+	PlateValidator func(plate string) error
+
+	// PlateValidators validates plate numbers by the ISO 3166-1 alpha-2 code of
+	// the country they're issued in, another country is supported by adding its
+	// validator.
+	PlateValidators map[string]PlateValidator
+)
+
+// DefaultPlateValidators supports Indonesian and Singaporean plate numbers,
+// letters are upper case and groups are separated by a single space.
+func DefaultPlateValidators() PlateValidators {
+	return PlateValidators{
+		"ID": PatternPlateValidator(`^[A-Z]{1,2} [1-9][0-9]{0,3}( [A-Z]{1,3})?$`, "B 1234 XYZ"),
+		"SG": PatternPlateValidator(`^[A-Z]{1,3} [1-9][0-9]{0,3} [A-Z]$`, "SBA 1234 A"),
+	}
+}
+
+// PatternPlateValidator accepts the plate numbers matching pattern, the example
+// is given in the error for the ones that don't. It panics when pattern isn't a
+// valid regular expression.
+func PatternPlateValidator(pattern, example string) PlateValidator {
+	re := regexp.MustCompile(pattern)
+	return func(plate string) error {
+		if !re.MatchString(plate) {
+			return fmt.Errorf("%s is not a valid plate number, must be formatted like %s", plate, example)
+		}
+		return nil
+	}
+}
+
+// Validate validates the plate number with the validator of the country.
+func (v PlateValidators) Validate(country, plate string) error {
+	validate, ok := v[country]
+	if !ok {
+		return fmt.Errorf("%s is not a supported country, must be one of %s", country, strings.Join(v.Countries(), ", "))
+	}
+	return validate(plate)
+}
+
+// Countries lists the countries with a validator in alphabetical order.
+func (v PlateValidators) Countries() []string {
+	countries := make([]string, 0, len(v))
+	for country := range v {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	return countries
+}
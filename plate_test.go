@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPlateValidators(t *testing.T) {
+	testCases := []struct {
+		testName    string
+		country     string
+		plate       string
+		expectedErr string
+	}{
+		{testName: "When Indonesian plate has a suffix", country: "ID", plate: "B 1234 XYZ"},
+		{testName: "When Indonesian plate has no suffix", country: "ID", plate: "AB 1"},
+		{
+			testName:    "When Indonesian plate isn't upper case",
+			country:     "ID",
+			plate:       "b 1234 xyz",
+			expectedErr: "b 1234 xyz is not a valid plate number, must be formatted like B 1234 XYZ",
+		},
+		{
+			testName:    "When Indonesian plate number starts with 0",
+			country:     "ID",
+			plate:       "B 0123 XYZ",
+			expectedErr: "B 0123 XYZ is not a valid plate number, must be formatted like B 1234 XYZ",
+		},
+		{testName: "When Singaporean plate is correct", country: "SG", plate: "SBA 1234 A"},
+		{
+			testName:    "When Singaporean plate has no checksum letter",
+			country:     "SG",
+			plate:       "SBA 1234",
+			expectedErr: "SBA 1234 is not a valid plate number, must be formatted like SBA 1234 A",
+		},
+		{
+			testName:    "When country isn't supported",
+			country:     "id",
+			plate:       "B 1234 XYZ",
+			expectedErr: "id is not a supported country, must be one of ID, SG",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := domain.DefaultPlateValidators().Validate(tc.country, tc.plate)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCustomPlateValidator(t *testing.T) {
+	plates := domain.DefaultPlateValidators()
+	plates["XX"] = func(plate string) error {
+		if plate != "XX 1" {
+			return errors.New("only XX 1 is issued")
+		}
+		return nil
+	}
+
+	assert.NoError(t, plates.Validate("XX", "XX 1"))
+	assert.EqualError(t, plates.Validate("XX", "XX 2"), "only XX 1 is issued")
+	assert.Equal(t, []string{"ID", "SG", "XX"}, plates.Countries())
+}
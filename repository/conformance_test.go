@@ -0,0 +1,131 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/repository"
+	"github.com/hawarir/backend-coding-test/repository/repositorytest"
+
+	_ "github.com/lib/pq"
+)
+
+func TestConformance_SQLite(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) domain.RideRepository {
+		db := openSQLite(t)
+		_, err := repository.NewMigrator(db).Up(context.Background())
+		require.NoError(t, err)
+		return repository.NewRideRepository(db)
+	})
+}
+
+func TestDriverConformance_SQLite(t *testing.T) {
+	repositorytest.RunDrivers(t, func(t *testing.T) domain.DriverRepository {
+		db := openSQLite(t)
+		_, err := repository.NewMigrator(db).Up(context.Background())
+		require.NoError(t, err)
+		return repository.NewDriverRepository(db)
+	})
+}
+
+func TestRiderConformance_SQLite(t *testing.T) {
+	repositorytest.RunRiders(t, func(t *testing.T) domain.RiderRepository {
+		db := openSQLite(t)
+		_, err := repository.NewMigrator(db).Up(context.Background())
+		require.NoError(t, err)
+		return repository.NewRiderRepository(db)
+	})
+}
+
+func TestRiderRidesConformance_SQLite(t *testing.T) {
+	repositorytest.RunRiderRides(t, func(t *testing.T) (domain.RideRepository, domain.RiderRepository) {
+		db := openSQLite(t)
+		_, err := repository.NewMigrator(db).Up(context.Background())
+		require.NoError(t, err)
+		return repository.NewRideRepository(db), repository.NewRiderRepository(db)
+	})
+}
+
+func TestVehicleConformance_SQLite(t *testing.T) {
+	repositorytest.RunVehicles(t, func(t *testing.T) domain.VehicleRepository {
+		db := openSQLite(t)
+		_, err := repository.NewMigrator(db).Up(context.Background())
+		require.NoError(t, err)
+		return repository.NewVehicleRepository(db)
+	})
+}
+
+func TestConformance_Memory(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) domain.RideRepository {
+		return repository.NewMemoryRideRepository()
+	})
+}
+
+func TestDriverConformance_Memory(t *testing.T) {
+	repositorytest.RunDrivers(t, func(t *testing.T) domain.DriverRepository {
+		return repository.NewMemoryDriverRepository()
+	})
+}
+
+func TestRiderConformance_Memory(t *testing.T) {
+	repositorytest.RunRiders(t, func(t *testing.T) domain.RiderRepository {
+		return repository.NewMemoryRiderRepository()
+	})
+}
+
+func TestRiderRidesConformance_Memory(t *testing.T) {
+	repositorytest.RunRiderRides(t, func(t *testing.T) (domain.RideRepository, domain.RiderRepository) {
+		return repository.NewMemoryRideRepository(), repository.NewMemoryRiderRepository()
+	})
+}
+
+func TestVehicleConformance_Memory(t *testing.T) {
+	repositorytest.RunVehicles(t, func(t *testing.T) domain.VehicleRepository {
+		return repository.NewMemoryVehicleRepository()
+	})
+}
+
+// TestConformance_Postgres runs against the database in TEST_POSTGRES_DSN, its
+// rides, drivers, riders and vehicles are removed before every test.
+func TestConformance_Postgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN isn't set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = repository.NewPostgresMigrator(db).Up(context.Background())
+	require.NoError(t, err)
+
+	repositorytest.Run(t, func(t *testing.T) domain.RideRepository {
+		_, err := db.Exec("TRUNCATE rides, drivers, riders, vehicles RESTART IDENTITY")
+		require.NoError(t, err)
+		return repository.NewPostgresRideRepository(db)
+	})
+	repositorytest.RunDrivers(t, func(t *testing.T) domain.DriverRepository {
+		_, err := db.Exec("TRUNCATE rides, drivers, riders, vehicles RESTART IDENTITY")
+		require.NoError(t, err)
+		return repository.NewPostgresDriverRepository(db)
+	})
+	repositorytest.RunRiders(t, func(t *testing.T) domain.RiderRepository {
+		_, err := db.Exec("TRUNCATE rides, drivers, riders, vehicles RESTART IDENTITY")
+		require.NoError(t, err)
+		return repository.NewPostgresRiderRepository(db)
+	})
+	repositorytest.RunRiderRides(t, func(t *testing.T) (domain.RideRepository, domain.RiderRepository) {
+		_, err := db.Exec("TRUNCATE rides, drivers, riders, vehicles RESTART IDENTITY")
+		require.NoError(t, err)
+		return repository.NewPostgresRideRepository(db), repository.NewPostgresRiderRepository(db)
+	})
+	repositorytest.RunVehicles(t, func(t *testing.T) domain.VehicleRepository {
+		_, err := db.Exec("TRUNCATE rides, drivers, riders, vehicles RESTART IDENTITY")
+		require.NoError(t, err)
+		return repository.NewPostgresVehicleRepository(db)
+	})
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type driverRepository struct {
+	db      *sql.DB
+	dialect dialect
+	sql     sq.StatementBuilderType
+}
+
+// NewDriverRepository stores drivers in SQLite.
+func NewDriverRepository(db *sql.DB) domain.DriverRepository {
+	return newDriverRepository(db, sqliteDialect())
+}
+
+// newDriverRepository expects the schema to be migrated by the Migrator of the
+// same dialect.
+func newDriverRepository(db *sql.DB, d dialect) driverRepository {
+	return driverRepository{
+		db:      db,
+		dialect: d,
+		sql:     sq.StatementBuilder.PlaceholderFormat(d.placeholder),
+	}
+}
+
+func driverColumns() []string {
+	return []string{"id", "name", "vehicle", "createdAt", "updatedAt"}
+}
+
+func (r driverRepository) Insert(ctx context.Context, driver domain.Driver) (int64, error) {
+	builder := r.sql.Insert("drivers").
+		Columns("name", "vehicle", "createdAt", "updatedAt").
+		Values(driver.Name, driver.Vehicle, driver.CreatedAt, driver.UpdatedAt).
+		RunWith(r.db)
+
+	if r.dialect.returningID {
+		var id int64
+		if err := builder.Suffix("RETURNING id").QueryRowContext(ctx).Scan(&id); err != nil {
+			return -1, err
+		}
+		return id, nil
+	}
+	result, err := builder.ExecContext(ctx)
+	if err != nil {
+		return -1, err
+	}
+	return result.LastInsertId()
+}
+
+// SelectAll returns drivers oldest first, the cursor is the ID of the last
+// driver on the page.
+func (r driverRepository) SelectAll(ctx context.Context, page domain.DriverPagination) ([]domain.Driver, string, error) {
+	builder := r.sql.Select(driverColumns()...).
+		From("drivers").
+		Where(sq.Eq{"deletedAt": nil}).
+		OrderBy("id asc").
+		RunWith(r.db)
+	if page.After != "" {
+		after, err := strconv.ParseInt(page.After, 10, 64)
+		if err != nil {
+			return nil, "", err
+		}
+		builder = builder.Where(sq.Gt{"id": after})
+	}
+	if page.Limit > 0 {
+		// NOTE: This is so that we know whether there is another page, make sure
+		// to not return the extra element.
+		builder = builder.Limit(page.Limit + 1)
+	}
+
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	drivers := make([]domain.Driver, 0)
+	for rows.Next() {
+		driver, err := scanDriver(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		drivers = append(drivers, driver)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if page.Limit == 0 || uint64(len(drivers)) <= page.Limit {
+		return drivers, "", nil
+	}
+	drivers = drivers[:page.Limit]
+	return drivers, strconv.FormatInt(drivers[len(drivers)-1].ID, 10), nil
+}
+
+func (r driverRepository) SelectByID(ctx context.Context, id int64, includeDeleted bool) (*domain.Driver, error) {
+	builder := r.sql.Select(driverColumns()...).From("drivers").Where(sq.Eq{"id": id}).RunWith(r.db)
+	if !includeDeleted {
+		builder = builder.Where(sq.Eq{"deletedAt": nil})
+	}
+	driver, err := scanDriver(builder.QueryRowContext(ctx))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (r driverRepository) Update(ctx context.Context, driver domain.Driver) (*domain.Driver, error) {
+	result, err := r.sql.Update("drivers").
+		Set("name", driver.Name).
+		Set("vehicle", driver.Vehicle).
+		Set("updatedAt", time.Now().UTC()).
+		Where(sq.Eq{"id": driver.ID}).
+		Where(sq.Eq{"deletedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return nil, err
+	}
+	return r.SelectByID(ctx, driver.ID, false)
+}
+
+// Delete only marks the driver as deleted so the rides referencing it are kept
+// as they are, it returns false when there is no driver left to delete.
+func (r driverRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	now := time.Now().UTC()
+	result, err := r.sql.Update("drivers").
+		Set("deletedAt", now).
+		Set("updatedAt", now).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"deletedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func scanDriver(s rowScanner) (domain.Driver, error) {
+	var driver domain.Driver
+	err := s.Scan(
+		&driver.ID,
+		&driver.Name,
+		&driver.Vehicle,
+		&driver.CreatedAt,
+		&driver.UpdatedAt,
+	)
+	return driver, err
+}
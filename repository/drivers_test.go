@@ -0,0 +1,240 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+func newDriverRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "vehicle", "createdAt", "updatedAt"})
+}
+
+func TestDriverRepository_Insert(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		returnErr    error
+		lastInsertID int64
+		expectedErr  string
+	}{
+		{
+			testName:     "When exec returns error, return the error",
+			returnErr:    errors.New("Exec error"),
+			lastInsertID: -1,
+			expectedErr:  "Exec error",
+		},
+		{
+			testName:     "When successful, return the result",
+			lastInsertID: 123,
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, func(mock sqlmock.Sqlmock) {
+					query := "INSERT INTO drivers (name,vehicle,createdAt,updatedAt) VALUES (?,?,?,?)"
+					args := []driver.Value{"Driver", "Car", testTime(), testTime()}
+					switch {
+					case b.returningID && tc.returnErr != nil:
+						mock.ExpectQuery(query + " RETURNING id").WithArgs(args...).WillReturnError(tc.returnErr)
+					case b.returningID:
+						mock.ExpectQuery(query + " RETURNING id").WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tc.lastInsertID))
+					case tc.returnErr != nil:
+						mock.ExpectExec(query).WithArgs(args...).WillReturnError(tc.returnErr)
+					default:
+						mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(tc.lastInsertID, 1))
+					}
+				})
+				defer db.Close()
+
+				id, err := b.newDriverRepo(db).Insert(context.Background(), domain.Driver{Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()})
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tc.lastInsertID, id)
+			})
+		}
+	}
+}
+
+func TestDriverRepository_SelectAll(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		page         domain.DriverPagination
+		drivers      []domain.Driver
+		next         string
+		expectedErr  string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, vehicle, createdAt, updatedAt FROM drivers WHERE deletedAt IS NULL ORDER BY id asc").
+					WillReturnError(errors.New("Query error"))
+			},
+			expectedErr: "Query error",
+		},
+		{
+			testName:    "When cursor isn't an ID, return error",
+			page:        domain.DriverPagination{After: "abc"},
+			expectedErr: "strconv.ParseInt: parsing \"abc\": invalid syntax",
+		},
+		{
+			testName: "When there are more drivers than the limit, return the next cursor",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, vehicle, createdAt, updatedAt FROM drivers WHERE deletedAt IS NULL AND id > ? ORDER BY id asc LIMIT 2").
+					WithArgs(int64(1)).
+					WillReturnRows(newDriverRows().
+						AddRow(2, "Driver", "Car", testTime(), testTime()).
+						AddRow(3, "Other Driver", "Motorcycle", testTime(), testTime()))
+			},
+			page:    domain.DriverPagination{After: "1", Limit: 1},
+			drivers: []domain.Driver{{ID: 2, Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()}},
+			next:    "2",
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				drivers, next, err := b.newDriverRepo(db).SelectAll(context.Background(), tc.page)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.drivers, drivers)
+					assert.Equal(t, tc.next, next)
+				}
+			})
+		}
+	}
+}
+
+func TestDriverRepository_SelectByID(t *testing.T) {
+	testCases := []struct {
+		testName       string
+		setupSQLMock   setupSQLMock
+		includeDeleted bool
+		driver         *domain.Driver
+		expectedErr    string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, vehicle, createdAt, updatedAt FROM drivers WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnError(errors.New("Query error"))
+			},
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When driver doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, vehicle, createdAt, updatedAt FROM drivers WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newDriverRows())
+			},
+		},
+		{
+			testName: "When successful, return driver",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, vehicle, createdAt, updatedAt FROM drivers WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newDriverRows().AddRow(123, "Driver", "Car", testTime(), testTime()))
+			},
+			driver: &domain.Driver{ID: 123, Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()},
+		},
+		{
+			testName: "When including deleted drivers, don't filter them out",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, vehicle, createdAt, updatedAt FROM drivers WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newDriverRows().AddRow(123, "Driver", "Car", testTime(), testTime()))
+			},
+			includeDeleted: true,
+			driver:         &domain.Driver{ID: 123, Name: "Driver", Vehicle: "Car", CreatedAt: testTime(), UpdatedAt: testTime()},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				driver, err := b.newDriverRepo(db).SelectByID(context.Background(), 123, tc.includeDeleted)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.driver, driver)
+				}
+			})
+		}
+	}
+}
+
+func TestDriverRepository_Delete(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		deleted      bool
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE drivers SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnError(errors.New("Exec error"))
+			},
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When no row is affected, return false",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE drivers SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			testName: "When successful, return true",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE drivers SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			deleted: true,
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				deleted, err := b.newDriverRepo(db).Delete(context.Background(), 123)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tc.deleted, deleted)
+			})
+		}
+	}
+}
@@ -0,0 +1,518 @@
+package repository
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/geo"
+)
+
+// memoryRideRepository keeps rides sorted by ID, which only ever grows, so
+// they can be looked up with a binary search and paged through in order.
+type memoryRideRepository struct {
+	mu     sync.RWMutex
+	rides  []domain.Ride
+	lastID int64
+}
+
+// NewMemoryRideRepository keeps rides in memory, they're lost once the process
+// exits. It behaves the same as the SQL repositories and is safe to use from
+// multiple goroutines.
+func NewMemoryRideRepository() domain.RideRepository {
+	return &memoryRideRepository{rides: []domain.Ride{}}
+}
+
+func (r *memoryRideRepository) Insert(ctx context.Context, ride domain.Ride) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insertRide(ride), nil
+}
+
+// InsertBatch inserts the rides while holding the lock, so either all of them
+// are seen or none are.
+func (r *memoryRideRepository) InsertBatch(ctx context.Context, rides []domain.Ride) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int64, len(rides))
+	for i, ride := range rides {
+		ids[i] = r.insertRide(ride)
+	}
+	return ids, nil
+}
+
+func (r *memoryRideRepository) SelectAll(ctx context.Context, page domain.Pagination) ([]domain.Ride, domain.PageInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	fields, err := page.SortFields()
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	var after, before []interface{}
+	if page.After != "" {
+		if after, err = parsePosition(page.After, fields); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+	}
+	if page.Before != "" {
+		if before, err = parsePosition(page.Before, fields); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+	}
+
+	r.mu.RLock()
+	rides := make([]domain.Ride, 0)
+	var total int64
+	columns := sortColumns()
+	facets := map[string]map[string]int64{}
+	for _, column := range facetColumns() {
+		facets[column] = map[string]int64{}
+	}
+	for _, ride := range r.rides {
+		if !matchesPage(ride, page) {
+			continue
+		}
+		total++
+		for column, counts := range facets {
+			counts[columns[column].value(ride).(string)]++
+		}
+		key := sortKey(ride, fields)
+		if (after != nil && compareKeys(fields, key, after) <= 0) || (before != nil && compareKeys(fields, key, before) >= 0) {
+			continue
+		}
+		rides = append(rides, cloneRide(ride))
+	}
+	r.mu.RUnlock()
+
+	sortRides(rides, fields)
+
+	// NOTE: When paging back the limit applies to the rides nearest to the
+	// cursor, which are the last ones.
+	more := page.Limit > 0 && uint64(len(rides)) > page.Limit
+	if more && before != nil {
+		rides = rides[uint64(len(rides))-page.Limit:]
+	} else if more {
+		rides = rides[:page.Limit]
+	}
+
+	info := domain.PageInfo{PageCursors: pageCursors(rides, fields, page, more)}
+	if page.Includes("total") {
+		info.Total = &total
+	}
+	if page.Includes("facets") {
+		info.Facets = map[string][]domain.FacetCount{}
+		for column, counts := range facets {
+			info.Facets[column] = topFacetCounts(counts)
+		}
+	}
+	return rides, info, nil
+}
+
+// SelectNearby orders rides by the squared chord to the center the same as the
+// SQL repositories, and returns ErrTooManyNearbyRides the same way when there
+// is no limit and more than maxNearbyCandidates rides are within the radius.
+func (r *memoryRideRepository) SelectNearby(ctx context.Context, query domain.NearbyQuery) ([]domain.Ride, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	var after *rankCursor
+	if query.After != "" {
+		cursor, err := parseRankCursor(query.After)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &cursor
+	}
+
+	center := geo.NewVector(query.Center())
+	r.mu.RLock()
+	rides := make([]domain.Ride, 0)
+	ranks := map[int64]float64{}
+	for _, ride := range r.rides {
+		if !matchesPage(ride, query.Pagination) {
+			continue
+		}
+		key := rankCursor{rank: center.SquaredChord(vector(ride, query.Endpoint)), id: ride.ID}
+		if geo.Distance(query.Center(), ride.Point(query.Endpoint)) > query.Radius || (after != nil && !after.less(key)) {
+			continue
+		}
+		ranks[ride.ID] = key.rank
+		rides = append(rides, cloneRide(ride))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(rides, func(i, j int) bool {
+		a := rankCursor{rank: ranks[rides[i].ID], id: rides[i].ID}
+		b := rankCursor{rank: ranks[rides[j].ID], id: rides[j].ID}
+		return a.less(b)
+	})
+
+	if (query.Limit == 0 || query.Limit > maxNearbyCandidates) && len(rides) > maxNearbyCandidates {
+		return nil, "", domain.ErrTooManyNearbyRides
+	}
+	if query.Limit == 0 || uint64(len(rides)) <= query.Limit {
+		return rides, "", nil
+	}
+
+	rides = rides[:query.Limit]
+	last := rides[len(rides)-1]
+	next := rankCursor{rank: ranks[last.ID], id: last.ID}
+
+	return rides, next.String(), nil
+}
+
+// Search ranks rides by how many of the search terms their names contain, the
+// same as the Postgres repository.
+func (r *memoryRideRepository) Search(ctx context.Context, query domain.SearchQuery) ([]domain.Ride, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	var after *rankCursor
+	if query.After != "" {
+		cursor, err := parseRankCursor(query.After)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &cursor
+	}
+
+	terms := searchTerms(query)
+	r.mu.RLock()
+	rides := make([]domain.Ride, 0)
+	ranks := map[int64]float64{}
+	for _, ride := range r.rides {
+		count := countMatches(ride, terms)
+		if count == 0 || !matchesPage(ride, query.Pagination) {
+			continue
+		}
+		key := rankCursor{rank: -float64(count), id: ride.ID}
+		if after != nil && !after.less(key) {
+			continue
+		}
+		ranks[ride.ID] = key.rank
+		rides = append(rides, cloneRide(ride))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(rides, func(i, j int) bool {
+		a := rankCursor{rank: ranks[rides[i].ID], id: rides[i].ID}
+		b := rankCursor{rank: ranks[rides[j].ID], id: rides[j].ID}
+		return a.less(b)
+	})
+
+	if (query.Limit == 0 || query.Limit > maxNearbyCandidates) && len(rides) > maxNearbyCandidates {
+		return nil, "", domain.ErrTooManyNearbyRides
+	}
+	if query.Limit == 0 || uint64(len(rides)) <= query.Limit {
+		return rides, "", nil
+	}
+
+	rides = rides[:query.Limit]
+	last := rides[len(rides)-1]
+	next := rankCursor{rank: ranks[last.ID], id: last.ID}
+
+	return rides, next.String(), nil
+}
+
+// Stats groups the matching rides the same way as the SQL repositories, the
+// rides are summarized once the lock is released.
+func (r *memoryRideRepository) Stats(ctx context.Context, query domain.StatsQuery) ([]domain.RideStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key, ok := rideStatsKeys()[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a valid groupBy", query.GroupBy)
+	}
+
+	r.mu.RLock()
+	groups := map[string][]domain.Ride{}
+	for _, ride := range r.rides {
+		if matchesPage(ride, query.Pagination) {
+			groups[key(ride)] = append(groups[key(ride)], ride)
+		}
+	}
+	r.mu.RUnlock()
+
+	stats := make([]domain.RideStats, 0, len(groups))
+	for key, rides := range groups {
+		stats = append(stats, summarizeRides(key, rides))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Key < stats[j].Key
+	})
+	return stats, nil
+}
+
+// Export copies the matching rides before writing them, so a slow writer
+// doesn't hold the lock.
+func (r *memoryRideRepository) Export(ctx context.Context, page domain.Pagination, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fields, err := page.SortFields()
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	rides := make([]domain.Ride, 0)
+	for _, ride := range r.rides {
+		if matchesPage(ride, page) {
+			rides = append(rides, cloneRide(ride))
+		}
+	}
+	r.mu.RUnlock()
+	sortRides(rides, fields)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(rideColumns()); err != nil {
+		return err
+	}
+	for _, ride := range rides {
+		if err := writer.Write(rideRecord(ride)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *memoryRideRepository) SelectByID(ctx context.Context, id int64, includeDeleted bool) (*domain.Ride, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ride := r.find(id, includeDeleted)
+	if ride == nil {
+		return nil, nil
+	}
+	found := cloneRide(*ride)
+	return &found, nil
+}
+
+// Update overwrites every ride attribute except its status, which can only be
+// changed through UpdateStatus.
+func (r *memoryRideRepository) Update(ctx context.Context, ride domain.Ride) (*domain.Ride, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(ride.ID, false)
+	if stored == nil {
+		return nil, nil
+	}
+	ride = cloneRide(ride)
+	stored.StartLatitude = ride.StartLatitude
+	stored.StartLongitude = ride.StartLongitude
+	stored.EndLatitude = ride.EndLatitude
+	stored.EndLongitude = ride.EndLongitude
+	stored.RiderName = ride.RiderName
+	stored.DriverName = ride.DriverName
+	stored.DriverVehicle = ride.DriverVehicle
+	stored.DistanceMeters = ride.Distance()
+	stored.BearingDegrees = ride.Bearing()
+	stored.StartedAt = ride.StartedAt
+	stored.EndedAt = ride.EndedAt
+	stored.DriverID = ride.DriverID
+	stored.RiderID = ride.RiderID
+	stored.VehicleID = ride.VehicleID
+	stored.UpdatedAt = time.Now().UTC()
+	updated := cloneRide(*stored)
+	return &updated, nil
+}
+
+// UpdateStatus checks and changes the status while holding the lock, so two
+// concurrent transitions can't both succeed.
+func (r *memoryRideRepository) UpdateStatus(ctx context.Context, id int64, status domain.RideStatus) (*domain.Ride, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(id, false)
+	if stored == nil {
+		return nil, nil
+	}
+	if !stored.Status.CanTransitionTo(status) {
+		return nil, domain.RideTransitionError{From: stored.Status, To: status}
+	}
+	stored.Status = status
+	stored.UpdatedAt = time.Now().UTC()
+	updated := cloneRide(*stored)
+	return &updated, nil
+}
+
+// Delete only marks the ride as deleted, it returns false when there is no
+// ride left to delete.
+func (r *memoryRideRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(id, false)
+	if stored == nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	stored.DeletedAt = &now
+	stored.UpdatedAt = now
+	return true, nil
+}
+
+func (r *memoryRideRepository) Restore(ctx context.Context, id int64) (*domain.Ride, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(id, true)
+	if stored == nil {
+		return nil, nil
+	}
+	if stored.DeletedAt != nil {
+		stored.DeletedAt = nil
+		stored.UpdatedAt = time.Now().UTC()
+	}
+	restored := cloneRide(*stored)
+	return &restored, nil
+}
+
+func (r *memoryRideRepository) RenameRider(ctx context.Context, riderID int64, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.rides {
+		if r.rides[i].RiderID != nil && *r.rides[i].RiderID == riderID {
+			r.rides[i].RiderName = name
+		}
+	}
+	return nil
+}
+
+// insertRide stores the ride with the next ID and what's computed on insert by
+// the SQL repositories, the lock must be held.
+func (r *memoryRideRepository) insertRide(ride domain.Ride) int64 {
+	r.lastID++
+	ride = cloneRide(ride)
+	ride.ID = r.lastID
+	ride.DistanceMeters = ride.Distance()
+	ride.BearingDegrees = ride.Bearing()
+	ride.DeletedAt = nil
+	r.rides = append(r.rides, ride)
+	return ride.ID
+}
+
+// find returns the stored ride so it can be changed in place, the lock must be
+// held.
+func (r *memoryRideRepository) find(id int64, includeDeleted bool) *domain.Ride {
+	i := sort.Search(len(r.rides), func(i int) bool {
+		return r.rides[i].ID >= id
+	})
+	if i == len(r.rides) || r.rides[i].ID != id {
+		return nil
+	}
+	if !includeDeleted && r.rides[i].DeletedAt != nil {
+		return nil
+	}
+	return &r.rides[i]
+}
+
+// sortRides sorts the rides by the fields the same way as the database.
+func sortRides(rides []domain.Ride, fields []domain.SortField) {
+	sort.Slice(rides, func(i, j int) bool {
+		return compareKeys(fields, sortKey(rides[i], fields), sortKey(rides[j], fields)) < 0
+	})
+}
+
+// matchesPage filters rides the same way as filterRides.
+// topFacetCounts returns the most common values first, the same way as
+// rideRepository.countFacets.
+func topFacetCounts(counts map[string]int64) []domain.FacetCount {
+	facet := make([]domain.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facet = append(facet, domain.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(facet, func(i, j int) bool {
+		if facet[i].Count != facet[j].Count {
+			return facet[i].Count > facet[j].Count
+		}
+		return facet[i].Value < facet[j].Value
+	})
+	if len(facet) > maxFacetValues {
+		facet = facet[:maxFacetValues]
+	}
+	return facet
+}
+
+func matchesPage(ride domain.Ride, page domain.Pagination) bool {
+	if !page.IncludeDeleted && ride.DeletedAt != nil {
+		return false
+	}
+	if !page.From.IsZero() && ride.CreatedAt.Before(page.From) {
+		return false
+	}
+	if !page.To.IsZero() && !ride.CreatedAt.Before(page.To) {
+		return false
+	}
+	if page.MinDistance > 0 && ride.DistanceMeters < page.MinDistance {
+		return false
+	}
+	if page.MaxDistance > 0 && ride.DistanceMeters > page.MaxDistance {
+		return false
+	}
+	if page.RiderID != 0 && (ride.RiderID == nil || *ride.RiderID != page.RiderID) {
+		return false
+	}
+	for _, filter := range nameFilters(page) {
+		value := filter.value(ride)
+		if (filter.exact != "" && value != filter.exact) || !strings.HasPrefix(value, filter.prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// cloneRide copies the times and IDs the ride points to, so callers can't change
+// what's stored through them. The vehicle looked up for the ride is left out,
+// the same as it isn't stored in SQL.
+func cloneRide(ride domain.Ride) domain.Ride {
+	cloneTime := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		clone := *t
+		return &clone
+	}
+	cloneID := func(id *int64) *int64 {
+		if id == nil {
+			return nil
+		}
+		clone := *id
+		return &clone
+	}
+	ride.StartedAt = cloneTime(ride.StartedAt)
+	ride.EndedAt = cloneTime(ride.EndedAt)
+	ride.DeletedAt = cloneTime(ride.DeletedAt)
+	ride.DriverID = cloneID(ride.DriverID)
+	ride.RiderID = cloneID(ride.RiderID)
+	ride.VehicleID = cloneID(ride.VehicleID)
+	return ride
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+type (
+	// memoryDriverRepository keeps drivers sorted by ID the same way as
+	// memoryRideRepository keeps rides.
+	memoryDriverRepository struct {
+		mu      sync.RWMutex
+		drivers []memoryDriver
+		lastID  int64
+	}
+
+	memoryDriver struct {
+		domain.Driver
+		deleted bool
+	}
+)
+
+// NewMemoryDriverRepository keeps drivers in memory, they're lost once the
+// process exits. It behaves the same as the SQL repositories and is safe to
+// use from multiple goroutines.
+func NewMemoryDriverRepository() domain.DriverRepository {
+	return &memoryDriverRepository{drivers: []memoryDriver{}}
+}
+
+func (r *memoryDriverRepository) Insert(ctx context.Context, driver domain.Driver) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID++
+	driver.ID = r.lastID
+	r.drivers = append(r.drivers, memoryDriver{Driver: driver})
+	return driver.ID, nil
+}
+
+func (r *memoryDriverRepository) SelectAll(ctx context.Context, page domain.DriverPagination) ([]domain.Driver, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	var after int64
+	if page.After != "" {
+		var err error
+		if after, err = strconv.ParseInt(page.After, 10, 64); err != nil {
+			return nil, "", err
+		}
+	}
+
+	r.mu.RLock()
+	drivers := make([]domain.Driver, 0)
+	for _, driver := range r.drivers {
+		if driver.ID > after && !driver.deleted {
+			drivers = append(drivers, driver.Driver)
+		}
+	}
+	r.mu.RUnlock()
+
+	if page.Limit == 0 || uint64(len(drivers)) <= page.Limit {
+		return drivers, "", nil
+	}
+	drivers = drivers[:page.Limit]
+	return drivers, strconv.FormatInt(drivers[len(drivers)-1].ID, 10), nil
+}
+
+func (r *memoryDriverRepository) SelectByID(ctx context.Context, id int64, includeDeleted bool) (*domain.Driver, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stored := r.find(id, includeDeleted)
+	if stored == nil {
+		return nil, nil
+	}
+	driver := stored.Driver
+	return &driver, nil
+}
+
+func (r *memoryDriverRepository) Update(ctx context.Context, driver domain.Driver) (*domain.Driver, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(driver.ID, false)
+	if stored == nil {
+		return nil, nil
+	}
+	stored.Name = driver.Name
+	stored.Vehicle = driver.Vehicle
+	stored.UpdatedAt = time.Now().UTC()
+	updated := stored.Driver
+	return &updated, nil
+}
+
+// Delete only marks the driver as deleted, it returns false when there is no
+// driver left to delete.
+func (r *memoryDriverRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(id, false)
+	if stored == nil {
+		return false, nil
+	}
+	stored.deleted = true
+	stored.UpdatedAt = time.Now().UTC()
+	return true, nil
+}
+
+// find returns the driver with the ID, deleted ones only when included.
+func (r *memoryDriverRepository) find(id int64, includeDeleted bool) *memoryDriver {
+	i := sort.Search(len(r.drivers), func(i int) bool {
+		return r.drivers[i].ID >= id
+	})
+	if i == len(r.drivers) || r.drivers[i].ID != id || (r.drivers[i].deleted && !includeDeleted) {
+		return nil
+	}
+	return &r.drivers[i]
+}
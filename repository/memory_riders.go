@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// memoryRiderRepository keeps riders sorted by ID the same way as
+// memoryRideRepository keeps rides.
+type memoryRiderRepository struct {
+	mu     sync.RWMutex
+	riders []domain.Rider
+	lastID int64
+}
+
+// NewMemoryRiderRepository keeps riders in memory, they're lost once the
+// process exits. It behaves the same as the SQL repositories and is safe to
+// use from multiple goroutines.
+func NewMemoryRiderRepository() domain.RiderRepository {
+	return &memoryRiderRepository{riders: []domain.Rider{}}
+}
+
+func (r *memoryRiderRepository) Insert(ctx context.Context, rider domain.Rider) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID++
+	rider.ID = r.lastID
+	r.riders = append(r.riders, rider)
+	return rider.ID, nil
+}
+
+func (r *memoryRiderRepository) SelectByID(ctx context.Context, id int64) (*domain.Rider, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stored := r.find(id)
+	if stored == nil {
+		return nil, nil
+	}
+	rider := *stored
+	return &rider, nil
+}
+
+func (r *memoryRiderRepository) SelectByName(ctx context.Context, name string) (*domain.Rider, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, stored := range r.riders {
+		if stored.Name == name {
+			rider := stored
+			return &rider, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRiderRepository) Update(ctx context.Context, rider domain.Rider) (*domain.Rider, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(rider.ID)
+	if stored == nil {
+		return nil, nil
+	}
+	stored.Name = rider.Name
+	stored.Email = rider.Email
+	stored.Phone = rider.Phone
+	stored.Preferences = rider.Preferences
+	stored.UpdatedAt = time.Now().UTC()
+	updated := *stored
+	return &updated, nil
+}
+
+func (r *memoryRiderRepository) find(id int64) *domain.Rider {
+	i := sort.Search(len(r.riders), func(i int) bool {
+		return r.riders[i].ID >= id
+	})
+	if i == len(r.riders) || r.riders[i].ID != id {
+		return nil
+	}
+	return &r.riders[i]
+}
@@ -0,0 +1,212 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/repository"
+)
+
+func newMemoryRide(riderName string) domain.Ride {
+	return domain.Ride{
+		StartLatitude:  -6.2,
+		StartLongitude: 106.8,
+		EndLatitude:    -6.3,
+		EndLongitude:   106.9,
+		RiderName:      riderName,
+		DriverName:     "Driver",
+		DriverVehicle:  "Car",
+		Status:         domain.RideStatusRequested,
+		CreatedAt:      testTime(),
+		UpdatedAt:      testTime(),
+	}
+}
+
+func rideIDs(rides []domain.Ride) []int64 {
+	ids := []int64{}
+	for _, ride := range rides {
+		ids = append(ids, ride.ID)
+	}
+	return ids
+}
+
+func TestMemoryRideRepository_SelectAll(t *testing.T) {
+	testCases := []struct {
+		testName       string
+		deleted        []int64
+		page           domain.Pagination
+		expectedIDs    []int64
+		expectedCursor string
+		expectedErr    bool
+	}{
+		{
+			testName:    "When there is no limit, return every ride latest first",
+			expectedIDs: []int64{5, 4, 3, 2, 1},
+		},
+		{
+			testName:       "When there are more rides than the limit, return next cursor",
+			page:           domain.Pagination{Limit: 2},
+			expectedIDs:    []int64{5, 4},
+			expectedCursor: "4",
+		},
+		{
+			testName:       "When cursor is given, return rides after it",
+			page:           domain.Pagination{After: "4", Limit: 2},
+			expectedIDs:    []int64{3, 2},
+			expectedCursor: "2",
+		},
+		{
+			testName:    "When the last page is read, return no cursor",
+			page:        domain.Pagination{After: "2", Limit: 2},
+			expectedIDs: []int64{1},
+		},
+		{
+			testName:       "When rides are deleted, skip them",
+			deleted:        []int64{4, 2},
+			page:           domain.Pagination{Limit: 2},
+			expectedIDs:    []int64{5, 3},
+			expectedCursor: "3",
+		},
+		{
+			testName:    "When deleted rides are included, return them",
+			deleted:     []int64{4, 2},
+			page:        domain.Pagination{IncludeDeleted: true},
+			expectedIDs: []int64{5, 4, 3, 2, 1},
+		},
+		{
+			testName:       "When scoped to a rider, only return the rides referencing it",
+			page:           domain.Pagination{RiderID: 1, Limit: 2},
+			expectedIDs:    []int64{5, 3},
+			expectedCursor: "3",
+		},
+		{
+			testName:    "When cursor is invalid, return error",
+			page:        domain.Pagination{After: "abc"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewMemoryRideRepository()
+			for i := 0; i < 5; i++ {
+				ride := newMemoryRide("John Doe")
+				// NOTE: Every other ride references the rider, starting from the first.
+				if i%2 == 0 {
+					riderID := int64(1)
+					ride.RiderID = &riderID
+				}
+				_, err := repo.Insert(ctx, ride)
+				require.NoError(t, err)
+			}
+			for _, id := range tc.deleted {
+				_, err := repo.Delete(ctx, id)
+				require.NoError(t, err)
+			}
+
+			rides, cursors, err := repo.SelectAll(ctx, tc.page)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedIDs, rideIDs(rides))
+			assert.Equal(t, tc.expectedCursor, cursors.Next)
+		})
+	}
+}
+
+func TestMemoryRideRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRideRepository()
+
+	id, err := repo.Insert(ctx, newMemoryRide("John Doe"))
+	require.NoError(t, err)
+
+	ride, err := repo.SelectByID(ctx, id, false)
+	require.NoError(t, err)
+	assert.Equal(t, 15678.69455766412, ride.DistanceMeters)
+	assert.Equal(t, 135.17621283260632, ride.BearingDegrees)
+
+	ride, err = repo.UpdateStatus(ctx, id, domain.RideStatusStarted)
+	assert.Nil(t, ride)
+	assert.Equal(t, domain.RideTransitionError{From: domain.RideStatusRequested, To: domain.RideStatusStarted}, err)
+
+	ride, err = repo.UpdateStatus(ctx, id, domain.RideStatusAccepted)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RideStatusAccepted, ride.Status)
+
+	deleted, err := repo.Delete(ctx, id)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, id)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	ride, err = repo.SelectByID(ctx, id, false)
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+	ride, err = repo.Update(ctx, newMemoryRide("Jane Doe"))
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+
+	ride, err = repo.Restore(ctx, id)
+	assert.NoError(t, err)
+	if assert.NotNil(t, ride) {
+		assert.Nil(t, ride.DeletedAt)
+	}
+
+	update := newMemoryRide("Jane Doe")
+	update.ID = id
+	ride, err = repo.Update(ctx, update)
+	assert.NoError(t, err)
+	if assert.NotNil(t, ride) {
+		assert.Equal(t, "Jane Doe", ride.RiderName)
+		assert.Equal(t, domain.RideStatusAccepted, ride.Status)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, repo.Export(ctx, domain.Pagination{}, &buf))
+	assert.Contains(t, buf.String(), "id,startLat,startLong,endLat,endLong,riderName,driverName,driverVehicle,distance,bearing,status,startedAt,endedAt,createdAt,updatedAt,deletedAt,driverId,riderId,vehicleId\n1,-6.2,106.8,-6.3,106.9,Jane Doe,Driver,Car,")
+}
+
+func TestMemoryRideRepository_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRideRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = repo.InsertBatch(ctx, []domain.Ride{newMemoryRide("John Doe"), newMemoryRide("Jane Doe")})
+			_, _, _ = repo.SelectAll(ctx, domain.Pagination{Limit: 10})
+		}()
+	}
+	wg.Wait()
+
+	rides, _, err := repo.SelectAll(ctx, domain.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, rides, 100)
+	for i, ride := range rides {
+		assert.Equal(t, int64(100-i), ride.ID)
+	}
+}
+
+func TestMemoryRideRepository_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repo := repository.NewMemoryRideRepository()
+
+	_, err := repo.Insert(ctx, newMemoryRide("John Doe"))
+	assert.Equal(t, context.Canceled, err)
+	_, _, err = repo.SelectAll(ctx, domain.Pagination{})
+	assert.Equal(t, context.Canceled, err)
+}
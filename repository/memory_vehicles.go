@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// memoryVehicleRepository keeps vehicles sorted by ID the same way as
+// memoryRideRepository keeps rides.
+type memoryVehicleRepository struct {
+	mu       sync.RWMutex
+	vehicles []domain.Vehicle
+	lastID   int64
+}
+
+// NewMemoryVehicleRepository keeps vehicles in memory, they're lost once the
+// process exits. It behaves the same as the SQL repositories and is safe to
+// use from multiple goroutines.
+func NewMemoryVehicleRepository() domain.VehicleRepository {
+	return &memoryVehicleRepository{vehicles: []domain.Vehicle{}}
+}
+
+func (r *memoryVehicleRepository) Insert(ctx context.Context, vehicle domain.Vehicle) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.plateTaken(vehicle) {
+		return -1, domain.ErrDuplicatePlateNumber
+	}
+	r.lastID++
+	vehicle.ID = r.lastID
+	vehicle.DeactivatedAt = nil
+	r.vehicles = append(r.vehicles, vehicle)
+	return vehicle.ID, nil
+}
+
+func (r *memoryVehicleRepository) SelectAll(ctx context.Context, page domain.VehiclePagination) ([]domain.Vehicle, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	var after int64
+	if page.After != "" {
+		var err error
+		if after, err = strconv.ParseInt(page.After, 10, 64); err != nil {
+			return nil, "", err
+		}
+	}
+
+	r.mu.RLock()
+	vehicles := make([]domain.Vehicle, 0)
+	for _, vehicle := range r.vehicles {
+		if vehicle.ID > after {
+			vehicles = append(vehicles, cloneVehicle(vehicle))
+		}
+	}
+	r.mu.RUnlock()
+
+	if page.Limit == 0 || uint64(len(vehicles)) <= page.Limit {
+		return vehicles, "", nil
+	}
+	vehicles = vehicles[:page.Limit]
+	return vehicles, strconv.FormatInt(vehicles[len(vehicles)-1].ID, 10), nil
+}
+
+func (r *memoryVehicleRepository) SelectByID(ctx context.Context, id int64) (*domain.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stored := r.find(id)
+	if stored == nil {
+		return nil, nil
+	}
+	vehicle := cloneVehicle(*stored)
+	return &vehicle, nil
+}
+
+func (r *memoryVehicleRepository) Update(ctx context.Context, vehicle domain.Vehicle) (*domain.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(vehicle.ID)
+	if stored == nil {
+		return nil, nil
+	}
+	if r.plateTaken(vehicle) {
+		return nil, domain.ErrDuplicatePlateNumber
+	}
+	stored.PlateNumber = vehicle.PlateNumber
+	stored.Country = vehicle.Country
+	stored.Make = vehicle.Make
+	stored.Model = vehicle.Model
+	stored.Capacity = vehicle.Capacity
+	stored.Type = vehicle.Type
+	stored.UpdatedAt = time.Now().UTC()
+	updated := cloneVehicle(*stored)
+	return &updated, nil
+}
+
+func (r *memoryVehicleRepository) Deactivate(ctx context.Context, id int64) (*domain.Vehicle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.find(id)
+	if stored == nil {
+		return nil, nil
+	}
+	if stored.DeactivatedAt == nil {
+		now := time.Now().UTC()
+		stored.DeactivatedAt = &now
+		stored.UpdatedAt = now
+	}
+	deactivated := cloneVehicle(*stored)
+	return &deactivated, nil
+}
+
+func (r *memoryVehicleRepository) find(id int64) *domain.Vehicle {
+	i := sort.Search(len(r.vehicles), func(i int) bool {
+		return r.vehicles[i].ID >= id
+	})
+	if i == len(r.vehicles) || r.vehicles[i].ID != id {
+		return nil
+	}
+	return &r.vehicles[i]
+}
+
+// plateTaken tells whether another vehicle of the same country has the plate
+// number of the vehicle, the same as the unique index of the SQL repositories.
+func (r *memoryVehicleRepository) plateTaken(vehicle domain.Vehicle) bool {
+	for _, stored := range r.vehicles {
+		if stored.ID != vehicle.ID && stored.Country == vehicle.Country && stored.PlateNumber == vehicle.PlateNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneVehicle copies the time the vehicle was deactivated at, so callers can't
+// change what's stored through it.
+func cloneVehicle(vehicle domain.Vehicle) domain.Vehicle {
+	if vehicle.DeactivatedAt != nil {
+		deactivatedAt := *vehicle.DeactivatedAt
+		vehicle.DeactivatedAt = &deactivatedAt
+	}
+	return vehicle
+}
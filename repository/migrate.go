@@ -0,0 +1,462 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// legacyVersion is the schema InitTable used to create before migrations were
+// introduced, such databases are recognized by their startGeohash column.
+const legacyVersion = 2
+
+//go:embed migrations
+var migrationFiles embed.FS //nolint:gochecknoglobals // embedded files can only be package variables
+
+type (
+	// Migration is a versioned change to the schema. Its SQL is written for
+	// every dialect as migrations/<dialect>/<version>_<name>.up.sql along with
+	// a down.sql reverting it.
+	Migration struct {
+		Version int
+		Name    string
+
+		up   string
+		down string
+		// NOTE: Data that can't be computed in SQL is filled in by the hook, it
+		// runs in the same transaction right after the up migration.
+		hook migrationHook
+	}
+
+	// MigrationStatus tells whether a migration has been applied and when.
+	MigrationStatus struct {
+		Migration
+		AppliedAt *time.Time
+	}
+
+	// Migrator applies and reverts the migrations of a database. Every migration
+	// runs in its own transaction along with keeping track of it in the
+	// schema_migrations table, which also holds the checksum of the migration
+	// so that changes made after it's applied are caught.
+	Migrator struct {
+		db      *sql.DB
+		dialect dialect
+		sql     sq.StatementBuilderType
+	}
+
+	migrationHook func(context.Context, sq.StatementBuilderType, *sql.Tx) error
+
+	appliedMigration struct {
+		checksum  string
+		appliedAt time.Time
+	}
+)
+
+// NewMigrator migrates an SQLite database for NewRideRepository.
+func NewMigrator(db *sql.DB) *Migrator {
+	return newMigrator(db, sqliteDialect())
+}
+
+// NewPostgresMigrator migrates a Postgres database for NewPostgresRideRepository.
+func NewPostgresMigrator(db *sql.DB) *Migrator {
+	return newMigrator(db, postgresDialect())
+}
+
+func newMigrator(db *sql.DB, d dialect) *Migrator {
+	return &Migrator{db: db, dialect: d, sql: sq.StatementBuilder.PlaceholderFormat(d.placeholder)}
+}
+
+func migrationHooks() map[int]migrationHook {
+	return map[int]migrationHook{
+		2: backfillRideDetails,
+		9: backfillRideVectors,
+	}
+}
+
+// Up applies every pending migration in order and returns them, then sets up
+// what's derived from the migrated schema.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	migrations, applied, err := m.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		if applied, err = m.adoptLegacySchema(ctx, migrations); err != nil {
+			return nil, err
+		}
+	}
+
+	done := []Migration{}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, migration); err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	if m.dialect.setUpSearch != nil {
+		if err := m.dialect.setUpSearch(ctx, m.db); err != nil {
+			return done, fmt.Errorf("search setup failed: %w", err)
+		}
+	}
+	return done, nil
+}
+
+// Down reverts the given number of the latest applied migrations, latest first,
+// and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, applied, err := m.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.revert(ctx, migration); err != nil {
+			return done, fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lists every migration in order.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, applied, err := m.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i].Migration = migration
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.appliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// load reads the migrations of the dialect along with the applied ones, making
+// sure the applied ones haven't been changed since.
+func (m *Migrator) load(ctx context.Context) ([]Migration, map[int]appliedMigration, error) {
+	migrations, err := loadMigrations(m.dialect.name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := m.db.ExecContext(ctx, m.dialect.migrationsTable); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := m.sql.Select("version", "checksum", "appliedAt").
+		From("schema_migrations").
+		RunWith(m.db).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var (
+			version int
+			record  appliedMigration
+		)
+		if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+			return nil, nil, err
+		}
+		applied[version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	known := map[int]Migration{}
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+	for version, record := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return nil, nil, fmt.Errorf("migration %04d is applied but doesn't exist", version)
+		}
+		if record.checksum != migration.checksum() {
+			return nil, nil, fmt.Errorf("migration %04d_%s was changed after it was applied", version, migration.Name)
+		}
+	}
+	return migrations, applied, nil
+}
+
+// adoptLegacySchema records the migrations matching the schema of a database
+// created by InitTable as applied without running them.
+func (m *Migrator) adoptLegacySchema(ctx context.Context, migrations []Migration) (map[int]appliedMigration, error) {
+	rows, err := m.db.QueryContext(ctx, m.dialect.columnsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	legacy := false
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		// NOTE: Postgres reports unquoted names folded to lower case.
+		legacy = legacy || strings.EqualFold(name, "startGeohash")
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]appliedMigration{}
+	if !legacy {
+		return applied, nil
+	}
+	for _, migration := range migrations {
+		if migration.Version > legacyVersion {
+			break
+		}
+		record := appliedMigration{checksum: migration.checksum(), appliedAt: time.Now().UTC()}
+		if err := m.record(ctx, m.db, migration, record); err != nil {
+			return nil, err
+		}
+		applied[migration.Version] = record
+	}
+	return applied, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, migration.up); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if migration.hook != nil {
+		if err := migration.hook(ctx, m.sql, tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	record := appliedMigration{checksum: migration.checksum(), appliedAt: time.Now().UTC()}
+	if err := m.record(ctx, tx, migration, record); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(ctx context.Context, migration Migration) error {
+	if migration.down == "" {
+		return fmt.Errorf("migration %04d_%s can't be reverted", migration.Version, migration.Name)
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, migration.down); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	_, err = m.sql.Delete("schema_migrations").
+		Where(sq.Eq{"version": migration.Version}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) record(ctx context.Context, runner sq.BaseRunner, migration Migration, record appliedMigration) error {
+	_, err := m.sql.Insert("schema_migrations").
+		Columns("version", "name", "checksum", "appliedAt").
+		Values(migration.Version, migration.Name, record.checksum, record.appliedAt).
+		RunWith(runner).
+		ExecContext(ctx)
+	return err
+}
+
+func (migration Migration) checksum() string {
+	sum := sha256.Sum256([]byte(migration.up))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadMigrations reads the migrations of the dialect sorted by version.
+func loadMigrations(dialectName string) ([]Migration, error) {
+	dir := path.Join("migrations", dialectName)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	hooks := migrationHooks()
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s is not a valid migration file name", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2], hook: hooks[version]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %04d has more than one name", version)
+		}
+		if match[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up migration", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// backfillRideDetails computes what's stored along with rides logged before
+// they had a distance, bearing and geohashes. Their unknown creation and update
+// times are rewritten as the epoch bound by the driver, since the column
+// default isn't stored in the same format and wouldn't page along with them.
+func backfillRideDetails(ctx context.Context, builder sq.StatementBuilderType, tx *sql.Tx) error {
+	epoch := time.Unix(0, 0).UTC()
+	for _, column := range []string{"createdAt", "updatedAt"} {
+		_, err := builder.Update("rides").
+			Set(column, epoch).
+			Where(sq.Lt{column: epoch.Add(time.Second)}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := builder.Select("id", "startLat", "startLong", "endLat", "endLong").
+		From("rides").
+		Where(sq.Or{sq.Eq{"startGeohash": ""}, sq.Eq{"endGeohash": ""}}).
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rides := []domain.Ride{}
+	for rows.Next() {
+		var ride domain.Ride
+		if err := rows.Scan(&ride.ID, &ride.StartLatitude, &ride.StartLongitude, &ride.EndLatitude, &ride.EndLongitude); err != nil {
+			return err
+		}
+		rides = append(rides, ride)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, ride := range rides {
+		_, err := builder.Update("rides").
+			Set("distance", ride.Distance()).
+			Set("bearing", ride.Bearing()).
+			Set("startGeohash", geohash(ride, domain.RideEndpointStart)).
+			Set("endGeohash", geohash(ride, domain.RideEndpointEnd)).
+			Where(sq.Eq{"id": ride.ID}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillRideVectors computes where both ends of rides logged before they had
+// vectors lie on the unit sphere.
+func backfillRideVectors(ctx context.Context, builder sq.StatementBuilderType, tx *sql.Tx) error {
+	rows, err := builder.Select("id", "startLat", "startLong", "endLat", "endLong").
+		From("rides").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rides := []domain.Ride{}
+	for rows.Next() {
+		var ride domain.Ride
+		if err := rows.Scan(&ride.ID, &ride.StartLatitude, &ride.StartLongitude, &ride.EndLatitude, &ride.EndLongitude); err != nil {
+			return err
+		}
+		rides = append(rides, ride)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, ride := range rides {
+		start, end := vector(ride, domain.RideEndpointStart), vector(ride, domain.RideEndpointEnd)
+		_, err := builder.Update("rides").
+			Set("startX", start.X).
+			Set("startY", start.Y).
+			Set("startZ", start.Z).
+			Set("endX", end.X).
+			Set("endY", end.Y).
+			Set("endZ", end.Z).
+			Where(sq.Eq{"id": ride.ID}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
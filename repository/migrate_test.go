@@ -0,0 +1,283 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/repository"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rides.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func migrationVersions(migrations []repository.Migration) []int {
+	versions := []int{}
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	return versions
+}
+
+func TestMigrator_Up(t *testing.T) {
+	testCases := []struct {
+		testName         string
+		setup            []string
+		expectedVersions []int
+		expectedErr      string
+	}{
+		{
+			testName:         "When database is empty, apply every migration",
+			expectedVersions: []int{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			testName: "When database has rides table without details, apply every migration and backfill the details",
+			setup: []string{
+				"CREATE TABLE rides (id INTEGER PRIMARY KEY AUTOINCREMENT, startLat REAL NOT NULL, startLong REAL NOT NULL, endLat REAL NOT NULL, endLong REAL NOT NULL, riderName TEXT NOT NULL, driverName TEXT NOT NULL, driverVehicle TEXT NOT NULL)",
+				"INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle) VALUES (-6.2, 106.8, -6.3, 106.9, 'John Doe', 'Driver', 'Car')",
+			},
+			expectedVersions: []int{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			testName: "When database was created before migrations, record them and apply the later ones",
+			setup: []string{
+				"CREATE TABLE rides (id INTEGER PRIMARY KEY AUTOINCREMENT, startLat REAL NOT NULL, startLong REAL NOT NULL, endLat REAL NOT NULL, endLong REAL NOT NULL, riderName TEXT NOT NULL, driverName TEXT NOT NULL, driverVehicle TEXT NOT NULL, distance REAL NOT NULL, bearing REAL NOT NULL, status TEXT NOT NULL, startedAt DATETIME, endedAt DATETIME, createdAt DATETIME NOT NULL, updatedAt DATETIME NOT NULL, deletedAt DATETIME, startGeohash TEXT NOT NULL, endGeohash TEXT NOT NULL)",
+			},
+			expectedVersions: []int{3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			testName: "When applied migration was changed, return error",
+			setup: []string{
+				"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, checksum TEXT NOT NULL, appliedAt DATETIME NOT NULL)",
+				"INSERT INTO schema_migrations VALUES (1, 'create_rides', 'changed', '2021-04-01 10:00:00')",
+			},
+			expectedErr: "migration 0001_create_rides was changed after it was applied",
+		},
+		{
+			testName: "When applied migration doesn't exist, return error",
+			setup: []string{
+				"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, checksum TEXT NOT NULL, appliedAt DATETIME NOT NULL)",
+				"INSERT INTO schema_migrations VALUES (9999, 'unknown', 'unknown', '2021-04-01 10:00:00')",
+			},
+			expectedErr: "migration 9999 is applied but doesn't exist",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			db := openSQLite(t)
+			for _, query := range tc.setup {
+				_, err := db.Exec(query)
+				require.NoError(t, err)
+			}
+			migrator := repository.NewMigrator(db)
+
+			migrations, err := migrator.Up(context.Background())
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedVersions, migrationVersions(migrations))
+
+			migrations, err = migrator.Up(context.Background())
+			assert.NoError(t, err)
+			assert.Empty(t, migrations)
+
+			var count int
+			err = db.QueryRow("SELECT COUNT(*) FROM rides WHERE startGeohash = '' OR endGeohash = '' OR distance IS NULL OR riderId IS NULL OR startZ = 0 OR endZ = 0").Scan(&count)
+			assert.NoError(t, err)
+			assert.Zero(t, count)
+		})
+	}
+}
+
+func TestMigrator_UpRiders(t *testing.T) {
+	db := openSQLite(t)
+	migrator := repository.NewMigrator(db)
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	_, err = migrator.Down(context.Background(), 5)
+	require.NoError(t, err)
+	for _, riderName := range []string{"John Doe", "Jane Doe", "John Doe"} {
+		_, err = db.Exec("INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, startGeohash, endGeohash) VALUES (-6.2, 106.8, -6.3, 106.9, ?, 'Driver', 'Car', 'qqguw', 'qqgux')", riderName)
+		require.NoError(t, err)
+	}
+
+	migrations, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 6, 7, 8, 9}, migrationVersions(migrations))
+
+	riderIDs := []int64{}
+	rows, err := db.Query("SELECT riderId FROM rides ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var riderID int64
+		require.NoError(t, rows.Scan(&riderID))
+		riderIDs = append(riderIDs, riderID)
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, []int64{1, 2, 1}, riderIDs)
+
+	var riderName string
+	err = db.QueryRow("SELECT name FROM riders WHERE id = 2").Scan(&riderName)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Doe", riderName)
+}
+
+func TestMigrator_UpLegacyRides(t *testing.T) {
+	db := openSQLite(t)
+	for _, query := range []string{
+		"CREATE TABLE rides (id INTEGER PRIMARY KEY AUTOINCREMENT, startLat REAL NOT NULL, startLong REAL NOT NULL, endLat REAL NOT NULL, endLong REAL NOT NULL, riderName TEXT NOT NULL, driverName TEXT NOT NULL, driverVehicle TEXT NOT NULL)",
+		"INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle) VALUES (-6.2, 106.8, -6.3, 106.9, 'John Doe', 'Driver', 'Car')",
+		"INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle) VALUES (-6.2, 106.8, -6.3, 106.9, 'Jane Doe', 'Driver', 'Car')",
+		"INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle) VALUES (-6.2, 106.8, -6.3, 106.9, 'John Doe', 'Driver', 'Car')",
+	} {
+		_, err := db.Exec(query)
+		require.NoError(t, err)
+	}
+	_, err := repository.NewMigrator(db).Up(context.Background())
+	require.NoError(t, err)
+
+	for _, sort := range []string{"createdAt", "-createdAt", "updatedAt", "-updatedAt"} {
+		t.Run(sort, func(t *testing.T) {
+			ids := []int64{}
+			pagination := domain.Pagination{Limit: 1, Sort: sort}
+			for page := 0; page < 3; page++ {
+				rides, pageInfo, err := repository.NewRideRepository(db).SelectAll(context.Background(), pagination)
+				require.NoError(t, err)
+				for _, ride := range rides {
+					assert.Equal(t, time.Unix(0, 0).UTC(), ride.CreatedAt.UTC())
+					ids = append(ids, ride.ID)
+				}
+				if pageInfo.Next == "" {
+					break
+				}
+				pagination.After = pageInfo.Next
+			}
+			assert.ElementsMatch(t, []int64{1, 2, 3}, ids)
+		})
+	}
+}
+
+func TestMigrator_UpRiderLinks(t *testing.T) {
+	db := openSQLite(t)
+	migrator := repository.NewMigrator(db)
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	_, err = migrator.Down(context.Background(), 3)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO riders (name, createdAt, updatedAt) VALUES ('John Doe', '2021-04-01 10:00:00', '2021-04-01 10:00:00')")
+	require.NoError(t, err)
+	for _, riderName := range []string{"Jane Doe", "John Doe", "Jane Doe"} {
+		_, err = db.Exec("INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, startGeohash, endGeohash) VALUES (-6.2, 106.8, -6.3, 106.9, ?, 'Driver', 'Car', 'qqguw', 'qqgux')", riderName)
+		require.NoError(t, err)
+	}
+
+	migrations, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7, 8, 9}, migrationVersions(migrations))
+
+	riderIDs := []int64{}
+	rows, err := db.Query("SELECT riderId FROM rides ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var riderID int64
+		require.NoError(t, rows.Scan(&riderID))
+		riderIDs = append(riderIDs, riderID)
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, []int64{2, 1, 2}, riderIDs)
+
+	var riderName string
+	err = db.QueryRow("SELECT name FROM riders WHERE id = 2").Scan(&riderName)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Doe", riderName)
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db := openSQLite(t)
+	migrator := repository.NewMigrator(db)
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, startGeohash, endGeohash) VALUES (-6.2, 106.8, -6.3, 106.9, 'John Doe', 'Driver', 'Car', 'qqguw', 'qqgux')")
+	require.NoError(t, err)
+
+	migrations, err := migrator.Down(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{9, 8, 7, 6, 5, 4, 3, 2}, migrationVersions(migrations))
+
+	var riderName string
+	err = db.QueryRow("SELECT riderName FROM rides").Scan(&riderName)
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", riderName)
+
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 9) {
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Nil(t, statuses[1].AppliedAt)
+		assert.Nil(t, statuses[2].AppliedAt)
+		assert.Nil(t, statuses[3].AppliedAt)
+		assert.Nil(t, statuses[4].AppliedAt)
+		assert.Nil(t, statuses[5].AppliedAt)
+		assert.Nil(t, statuses[6].AppliedAt)
+		assert.Nil(t, statuses[7].AppliedAt)
+		assert.Nil(t, statuses[8].AppliedAt)
+	}
+
+	migrations, err = migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7, 8, 9}, migrationVersions(migrations))
+
+	migrations, err = migrator.Down(context.Background(), 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{9, 8, 7, 6, 5, 4, 3, 2, 1}, migrationVersions(migrations))
+}
+
+func TestMigrator_UpSearch(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator := repository.NewMigrator(db)
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	repo := repository.NewRideRepository(db)
+
+	_, _, err = repo.Search(ctx, domain.SearchQuery{Q: "john"})
+	if errors.Is(err, domain.ErrSearchUnavailable) {
+		t.Skip("SQLite is built without FTS5, build with the sqlite_fts5 tag to search")
+	}
+	require.NoError(t, err)
+
+	// NOTE: Without its triggers, as left by a build without FTS5, the index
+	// misses the ride until it's rebuilt.
+	_, err = db.Exec("DROP TRIGGER rides_search_insert")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO rides (startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle) VALUES (-6.2, 106.8, -6.3, 106.9, 'John Doe', 'Driver', 'Car')")
+	require.NoError(t, err)
+	_, _, err = repo.Search(ctx, domain.SearchQuery{Q: "john"})
+	assert.Equal(t, domain.ErrSearchUnavailable, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	rides, _, err := repo.Search(ctx, domain.SearchQuery{Q: "john"})
+	assert.NoError(t, err)
+	if assert.Len(t, rides, 1) {
+		assert.Equal(t, "John Doe", rides[0].RiderName)
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS rides (
+    id BIGSERIAL PRIMARY KEY,
+    startLat DOUBLE PRECISION NOT NULL,
+    startLong DOUBLE PRECISION NOT NULL,
+    endLat DOUBLE PRECISION NOT NULL,
+    endLong DOUBLE PRECISION NOT NULL,
+    riderName TEXT NOT NULL,
+    driverName TEXT NOT NULL,
+    driverVehicle TEXT NOT NULL
+);
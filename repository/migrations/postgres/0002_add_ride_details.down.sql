@@ -0,0 +1,16 @@
+DROP INDEX rides_createdAt;
+DROP INDEX rides_distance;
+DROP INDEX rides_startGeohash;
+DROP INDEX rides_endGeohash;
+
+ALTER TABLE rides
+    DROP COLUMN distance,
+    DROP COLUMN bearing,
+    DROP COLUMN status,
+    DROP COLUMN startedAt,
+    DROP COLUMN endedAt,
+    DROP COLUMN createdAt,
+    DROP COLUMN updatedAt,
+    DROP COLUMN deletedAt,
+    DROP COLUMN startGeohash,
+    DROP COLUMN endGeohash;
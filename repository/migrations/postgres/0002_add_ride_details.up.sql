@@ -0,0 +1,18 @@
+-- NOTE: Geohashes are compared byte by byte so that prefix matching works
+-- regardless of the database collation.
+ALTER TABLE rides
+    ADD COLUMN distance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN bearing DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'requested',
+    ADD COLUMN startedAt TIMESTAMPTZ,
+    ADD COLUMN endedAt TIMESTAMPTZ,
+    ADD COLUMN createdAt TIMESTAMPTZ NOT NULL DEFAULT '1970-01-01 00:00:00+00',
+    ADD COLUMN updatedAt TIMESTAMPTZ NOT NULL DEFAULT '1970-01-01 00:00:00+00',
+    ADD COLUMN deletedAt TIMESTAMPTZ,
+    ADD COLUMN startGeohash TEXT COLLATE "C" NOT NULL DEFAULT '',
+    ADD COLUMN endGeohash TEXT COLLATE "C" NOT NULL DEFAULT '';
+
+CREATE INDEX rides_createdAt ON rides (createdAt);
+CREATE INDEX rides_distance ON rides (distance);
+CREATE INDEX rides_startGeohash ON rides (startGeohash);
+CREATE INDEX rides_endGeohash ON rides (endGeohash);
@@ -0,0 +1,8 @@
+DROP INDEX rides_riderName;
+DROP INDEX rides_driverName;
+DROP INDEX rides_driverVehicle;
+
+ALTER TABLE rides
+    ALTER COLUMN riderName TYPE TEXT COLLATE "default",
+    ALTER COLUMN driverName TYPE TEXT COLLATE "default",
+    ALTER COLUMN driverVehicle TYPE TEXT COLLATE "default";
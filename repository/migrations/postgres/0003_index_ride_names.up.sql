@@ -0,0 +1,10 @@
+-- NOTE: Names are compared byte by byte the same way as in SQLite, so they're
+-- sorted the same and prefix filters can use the same indexes as exact ones.
+ALTER TABLE rides
+    ALTER COLUMN riderName TYPE TEXT COLLATE "C",
+    ALTER COLUMN driverName TYPE TEXT COLLATE "C",
+    ALTER COLUMN driverVehicle TYPE TEXT COLLATE "C";
+
+CREATE INDEX rides_riderName ON rides (riderName);
+CREATE INDEX rides_driverName ON rides (driverName);
+CREATE INDEX rides_driverVehicle ON rides (driverVehicle);
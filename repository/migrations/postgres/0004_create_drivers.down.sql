@@ -0,0 +1,5 @@
+DROP INDEX rides_driverId;
+
+ALTER TABLE rides DROP COLUMN driverId;
+
+DROP TABLE drivers;
@@ -0,0 +1,14 @@
+-- NOTE: Rides logged before this migration don't reference a driver, they
+-- keep the names they were logged with.
+CREATE TABLE drivers (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    vehicle TEXT NOT NULL,
+    createdAt TIMESTAMPTZ NOT NULL,
+    updatedAt TIMESTAMPTZ NOT NULL,
+    deletedAt TIMESTAMPTZ
+);
+
+ALTER TABLE rides ADD COLUMN driverId BIGINT REFERENCES drivers (id);
+
+CREATE INDEX rides_driverId ON rides (driverId);
@@ -0,0 +1,5 @@
+DROP INDEX rides_riderId;
+
+ALTER TABLE rides DROP COLUMN riderId;
+
+DROP TABLE riders;
@@ -0,0 +1,21 @@
+CREATE TABLE riders (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    contactBy TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    createdAt TIMESTAMPTZ NOT NULL,
+    updatedAt TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE rides ADD COLUMN riderId BIGINT REFERENCES riders (id);
+
+-- NOTE: Rides only had the name of their rider, so rides of riders sharing a
+-- name end up with the same rider. Riders are created in the order they first
+-- rode.
+INSERT INTO riders (name, createdAt, updatedAt)
+SELECT riderName, MIN(createdAt), MIN(createdAt) FROM rides GROUP BY riderName ORDER BY MIN(id);
+UPDATE rides SET riderId = riders.id FROM riders WHERE riders.name = rides.riderName;
+
+CREATE INDEX rides_riderId ON rides (riderId);
@@ -0,0 +1,5 @@
+DROP INDEX rides_vehicleId;
+
+ALTER TABLE rides DROP COLUMN vehicleId;
+
+DROP TABLE vehicles;
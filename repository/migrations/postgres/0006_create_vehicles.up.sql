@@ -0,0 +1,18 @@
+-- NOTE: Rides logged before this migration don't reference a vehicle, they
+-- keep the driverVehicle they were logged with.
+CREATE TABLE vehicles (
+    id BIGSERIAL PRIMARY KEY,
+    plateNumber TEXT NOT NULL,
+    country TEXT NOT NULL,
+    make TEXT NOT NULL,
+    model TEXT NOT NULL,
+    capacity INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    createdAt TIMESTAMPTZ NOT NULL,
+    updatedAt TIMESTAMPTZ NOT NULL,
+    deactivatedAt TIMESTAMPTZ
+);
+
+ALTER TABLE rides ADD COLUMN vehicleId BIGINT REFERENCES vehicles (id);
+
+CREATE INDEX rides_vehicleId ON rides (vehicleId);
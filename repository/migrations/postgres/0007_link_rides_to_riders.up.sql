@@ -0,0 +1,11 @@
+CREATE INDEX riders_name ON riders (name);
+
+-- NOTE: Rides created with only the name of their rider after riders were
+-- introduced weren't linked to one. They're linked the same way as before, to
+-- the oldest rider with the name, which is created if there's none.
+INSERT INTO riders (name, createdAt, updatedAt)
+SELECT riderName, MIN(createdAt), MIN(createdAt) FROM rides
+WHERE riderId IS NULL AND riderName NOT IN (SELECT name FROM riders)
+GROUP BY riderName ORDER BY MIN(id);
+UPDATE rides SET riderId = (SELECT MIN(riders.id) FROM riders WHERE riders.name = rides.riderName)
+WHERE riderId IS NULL;
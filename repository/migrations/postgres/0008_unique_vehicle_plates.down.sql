@@ -0,0 +1 @@
+DROP INDEX vehicles_country_plateNumber;
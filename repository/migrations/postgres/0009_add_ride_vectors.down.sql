@@ -0,0 +1,6 @@
+ALTER TABLE rides DROP COLUMN startX;
+ALTER TABLE rides DROP COLUMN startY;
+ALTER TABLE rides DROP COLUMN startZ;
+ALTER TABLE rides DROP COLUMN endX;
+ALTER TABLE rides DROP COLUMN endY;
+ALTER TABLE rides DROP COLUMN endZ;
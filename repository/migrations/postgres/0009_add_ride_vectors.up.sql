@@ -0,0 +1,9 @@
+-- NOTE: Where each end of a ride lies on the unit sphere, nearby rides are
+-- ordered by the straight line to it. Vectors of rides logged before this
+-- migration are computed right after it.
+ALTER TABLE rides ADD COLUMN startX DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN startY DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN startZ DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN endX DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN endY DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN endZ DOUBLE PRECISION NOT NULL DEFAULT 0;
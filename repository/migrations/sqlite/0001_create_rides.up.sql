@@ -0,0 +1,12 @@
+-- NOTE: Databases created before migrations were introduced already have this
+-- table, it's left as is.
+CREATE TABLE IF NOT EXISTS rides (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    startLat REAL NOT NULL,
+    startLong REAL NOT NULL,
+    endLat REAL NOT NULL,
+    endLong REAL NOT NULL,
+    riderName TEXT NOT NULL,
+    driverName TEXT NOT NULL,
+    driverVehicle TEXT NOT NULL
+);
@@ -0,0 +1,20 @@
+-- NOTE: The SQLite version we build with can't drop columns, so the table is
+-- copied over instead.
+DROP INDEX rides_createdAt;
+DROP INDEX rides_distance;
+DROP INDEX rides_startGeohash;
+DROP INDEX rides_endGeohash;
+
+CREATE TABLE rides_0001 (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    startLat REAL NOT NULL,
+    startLong REAL NOT NULL,
+    endLat REAL NOT NULL,
+    endLong REAL NOT NULL,
+    riderName TEXT NOT NULL,
+    driverName TEXT NOT NULL,
+    driverVehicle TEXT NOT NULL
+);
+INSERT INTO rides_0001 SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides;
+DROP TABLE rides;
+ALTER TABLE rides_0001 RENAME TO rides;
@@ -0,0 +1,17 @@
+-- NOTE: Rides logged before this migration get their distance, bearing and
+-- geohashes computed right after it, their creation time is unknown.
+ALTER TABLE rides ADD COLUMN distance REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN bearing REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN status TEXT NOT NULL DEFAULT 'requested';
+ALTER TABLE rides ADD COLUMN startedAt DATETIME;
+ALTER TABLE rides ADD COLUMN endedAt DATETIME;
+ALTER TABLE rides ADD COLUMN createdAt DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE rides ADD COLUMN updatedAt DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE rides ADD COLUMN deletedAt DATETIME;
+ALTER TABLE rides ADD COLUMN startGeohash TEXT NOT NULL DEFAULT '';
+ALTER TABLE rides ADD COLUMN endGeohash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX rides_createdAt ON rides (createdAt);
+CREATE INDEX rides_distance ON rides (distance);
+CREATE INDEX rides_startGeohash ON rides (startGeohash);
+CREATE INDEX rides_endGeohash ON rides (endGeohash);
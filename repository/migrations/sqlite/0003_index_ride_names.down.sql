@@ -0,0 +1,3 @@
+DROP INDEX rides_riderName;
+DROP INDEX rides_driverName;
+DROP INDEX rides_driverVehicle;
@@ -0,0 +1,5 @@
+-- NOTE: Names are compared byte by byte, so prefix filters can use the same
+-- indexes as exact ones.
+CREATE INDEX rides_riderName ON rides (riderName);
+CREATE INDEX rides_driverName ON rides (driverName);
+CREATE INDEX rides_driverVehicle ON rides (driverVehicle);
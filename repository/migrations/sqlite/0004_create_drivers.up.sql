@@ -0,0 +1,14 @@
+-- NOTE: Rides logged before this migration don't reference a driver, they
+-- keep the names they were logged with.
+CREATE TABLE drivers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    vehicle TEXT NOT NULL,
+    createdAt DATETIME NOT NULL,
+    updatedAt DATETIME NOT NULL,
+    deletedAt DATETIME
+);
+
+ALTER TABLE rides ADD COLUMN driverId INTEGER REFERENCES drivers (id);
+
+CREATE INDEX rides_driverId ON rides (driverId);
@@ -0,0 +1,37 @@
+-- NOTE: The SQLite version we build with can't drop columns, so the table is
+-- copied over instead and its indexes are created again.
+CREATE TABLE rides_0004 (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    startLat REAL NOT NULL,
+    startLong REAL NOT NULL,
+    endLat REAL NOT NULL,
+    endLong REAL NOT NULL,
+    riderName TEXT NOT NULL,
+    driverName TEXT NOT NULL,
+    driverVehicle TEXT NOT NULL,
+    distance REAL NOT NULL DEFAULT 0,
+    bearing REAL NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'requested',
+    startedAt DATETIME,
+    endedAt DATETIME,
+    createdAt DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+    updatedAt DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+    deletedAt DATETIME,
+    startGeohash TEXT NOT NULL DEFAULT '',
+    endGeohash TEXT NOT NULL DEFAULT '',
+    driverId INTEGER REFERENCES drivers (id)
+);
+INSERT INTO rides_0004 SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, startGeohash, endGeohash, driverId FROM rides;
+DROP TABLE rides;
+ALTER TABLE rides_0004 RENAME TO rides;
+
+CREATE INDEX rides_createdAt ON rides (createdAt);
+CREATE INDEX rides_distance ON rides (distance);
+CREATE INDEX rides_startGeohash ON rides (startGeohash);
+CREATE INDEX rides_endGeohash ON rides (endGeohash);
+CREATE INDEX rides_riderName ON rides (riderName);
+CREATE INDEX rides_driverName ON rides (driverName);
+CREATE INDEX rides_driverVehicle ON rides (driverVehicle);
+CREATE INDEX rides_driverId ON rides (driverId);
+
+DROP TABLE riders;
@@ -0,0 +1,18 @@
+-- NOTE: Rides logged before this migration don't reference a vehicle, they
+-- keep the driverVehicle they were logged with.
+CREATE TABLE vehicles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plateNumber TEXT NOT NULL,
+    country TEXT NOT NULL,
+    make TEXT NOT NULL,
+    model TEXT NOT NULL,
+    capacity INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    createdAt DATETIME NOT NULL,
+    updatedAt DATETIME NOT NULL,
+    deactivatedAt DATETIME
+);
+
+ALTER TABLE rides ADD COLUMN vehicleId INTEGER REFERENCES vehicles (id);
+
+CREATE INDEX rides_vehicleId ON rides (vehicleId);
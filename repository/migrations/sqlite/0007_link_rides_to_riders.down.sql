@@ -0,0 +1 @@
+DROP INDEX riders_name;
@@ -0,0 +1,3 @@
+-- NOTE: Vehicles registered twice with the same plate number in a country have
+-- to be merged by hand before this can be applied.
+CREATE UNIQUE INDEX vehicles_country_plateNumber ON vehicles (country, plateNumber);
@@ -0,0 +1,9 @@
+-- NOTE: Where each end of a ride lies on the unit sphere, nearby rides are
+-- ordered by the straight line to it. Vectors of rides logged before this
+-- migration are computed right after it.
+ALTER TABLE rides ADD COLUMN startX REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN startY REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN startZ REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN endX REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN endY REAL NOT NULL DEFAULT 0;
+ALTER TABLE rides ADD COLUMN endZ REAL NOT NULL DEFAULT 0;
@@ -5,6 +5,8 @@
 package mock
 
 import (
+	context "context"
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,62 +36,479 @@ func (m *MockRideRepository) EXPECT() *MockRideRepositoryMockRecorder {
 	return m.recorder
 }
 
-// InitTable mocks base method.
-func (m *MockRideRepository) InitTable() error {
+// Delete mocks base method.
+func (m *MockRideRepository) Delete(arg0 context.Context, arg1 int64) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InitTable")
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRideRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRideRepository)(nil).Delete), arg0, arg1)
+}
+
+// Export mocks base method.
+func (m *MockRideRepository) Export(arg0 context.Context, arg1 domain.Pagination, arg2 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// InitTable indicates an expected call of InitTable.
-func (mr *MockRideRepositoryMockRecorder) InitTable() *gomock.Call {
+// Export indicates an expected call of Export.
+func (mr *MockRideRepositoryMockRecorder) Export(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitTable", reflect.TypeOf((*MockRideRepository)(nil).InitTable))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockRideRepository)(nil).Export), arg0, arg1, arg2)
 }
 
 // Insert mocks base method.
-func (m *MockRideRepository) Insert(arg0 domain.Ride) (int64, error) {
+func (m *MockRideRepository) Insert(arg0 context.Context, arg1 domain.Ride) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Insert", arg0)
+	ret := m.ctrl.Call(m, "Insert", arg0, arg1)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Insert indicates an expected call of Insert.
-func (mr *MockRideRepositoryMockRecorder) Insert(arg0 interface{}) *gomock.Call {
+func (mr *MockRideRepositoryMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockRideRepository)(nil).Insert), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockRideRepository)(nil).Insert), arg0, arg1)
 }
 
-// SelectAll mocks base method.
-func (m *MockRideRepository) SelectAll(arg0 domain.Pagination) ([]domain.Ride, string, error) {
+// InsertBatch mocks base method.
+func (m *MockRideRepository) InsertBatch(arg0 context.Context, arg1 []domain.Ride) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertBatch", arg0, arg1)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertBatch indicates an expected call of InsertBatch.
+func (mr *MockRideRepositoryMockRecorder) InsertBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertBatch", reflect.TypeOf((*MockRideRepository)(nil).InsertBatch), arg0, arg1)
+}
+
+// RenameRider mocks base method.
+func (m *MockRideRepository) RenameRider(ctx context.Context, riderID int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameRider", ctx, riderID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameRider indicates an expected call of RenameRider.
+func (mr *MockRideRepositoryMockRecorder) RenameRider(ctx, riderID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameRider", reflect.TypeOf((*MockRideRepository)(nil).RenameRider), ctx, riderID, name)
+}
+
+// Restore mocks base method.
+func (m *MockRideRepository) Restore(arg0 context.Context, arg1 int64) (*domain.Ride, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectAll", arg0)
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Ride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockRideRepositoryMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockRideRepository)(nil).Restore), arg0, arg1)
+}
+
+// Search mocks base method.
+func (m *MockRideRepository) Search(arg0 context.Context, arg1 domain.SearchQuery) ([]domain.Ride, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1)
 	ret0, _ := ret[0].([]domain.Ride)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
 	return ret0, ret1, ret2
 }
 
+// Search indicates an expected call of Search.
+func (mr *MockRideRepositoryMockRecorder) Search(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockRideRepository)(nil).Search), arg0, arg1)
+}
+
+// SelectAll mocks base method.
+func (m *MockRideRepository) SelectAll(arg0 context.Context, arg1 domain.Pagination) ([]domain.Ride, domain.PageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectAll", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Ride)
+	ret1, _ := ret[1].(domain.PageInfo)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // SelectAll indicates an expected call of SelectAll.
-func (mr *MockRideRepositoryMockRecorder) SelectAll(arg0 interface{}) *gomock.Call {
+func (mr *MockRideRepositoryMockRecorder) SelectAll(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAll", reflect.TypeOf((*MockRideRepository)(nil).SelectAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAll", reflect.TypeOf((*MockRideRepository)(nil).SelectAll), arg0, arg1)
 }
 
 // SelectByID mocks base method.
-func (m *MockRideRepository) SelectByID(arg0 int64) (*domain.Ride, error) {
+func (m *MockRideRepository) SelectByID(ctx context.Context, id int64, includeDeleted bool) (*domain.Ride, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectByID", arg0)
+	ret := m.ctrl.Call(m, "SelectByID", ctx, id, includeDeleted)
 	ret0, _ := ret[0].(*domain.Ride)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectByID indicates an expected call of SelectByID.
-func (mr *MockRideRepositoryMockRecorder) SelectByID(arg0 interface{}) *gomock.Call {
+func (mr *MockRideRepositoryMockRecorder) SelectByID(ctx, id, includeDeleted interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByID", reflect.TypeOf((*MockRideRepository)(nil).SelectByID), ctx, id, includeDeleted)
+}
+
+// SelectNearby mocks base method.
+func (m *MockRideRepository) SelectNearby(arg0 context.Context, arg1 domain.NearbyQuery) ([]domain.Ride, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectNearby", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Ride)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SelectNearby indicates an expected call of SelectNearby.
+func (mr *MockRideRepositoryMockRecorder) SelectNearby(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectNearby", reflect.TypeOf((*MockRideRepository)(nil).SelectNearby), arg0, arg1)
+}
+
+// Stats mocks base method.
+func (m *MockRideRepository) Stats(arg0 context.Context, arg1 domain.StatsQuery) ([]domain.RideStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", arg0, arg1)
+	ret0, _ := ret[0].([]domain.RideStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockRideRepositoryMockRecorder) Stats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockRideRepository)(nil).Stats), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockRideRepository) Update(arg0 context.Context, arg1 domain.Ride) (*domain.Ride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Ride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRideRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRideRepository)(nil).Update), arg0, arg1)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockRideRepository) UpdateStatus(arg0 context.Context, arg1 int64, arg2 domain.RideStatus) (*domain.Ride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.Ride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockRideRepositoryMockRecorder) UpdateStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockRideRepository)(nil).UpdateStatus), arg0, arg1, arg2)
+}
+
+// MockDriverRepository is a mock of DriverRepository interface.
+type MockDriverRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDriverRepositoryMockRecorder
+}
+
+// MockDriverRepositoryMockRecorder is the mock recorder for MockDriverRepository.
+type MockDriverRepositoryMockRecorder struct {
+	mock *MockDriverRepository
+}
+
+// NewMockDriverRepository creates a new mock instance.
+func NewMockDriverRepository(ctrl *gomock.Controller) *MockDriverRepository {
+	mock := &MockDriverRepository{ctrl: ctrl}
+	mock.recorder = &MockDriverRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDriverRepository) EXPECT() *MockDriverRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockDriverRepository) Delete(arg0 context.Context, arg1 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDriverRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDriverRepository)(nil).Delete), arg0, arg1)
+}
+
+// Insert mocks base method.
+func (m *MockDriverRepository) Insert(arg0 context.Context, arg1 domain.Driver) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockDriverRepositoryMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockDriverRepository)(nil).Insert), arg0, arg1)
+}
+
+// SelectAll mocks base method.
+func (m *MockDriverRepository) SelectAll(arg0 context.Context, arg1 domain.DriverPagination) ([]domain.Driver, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectAll", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Driver)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SelectAll indicates an expected call of SelectAll.
+func (mr *MockDriverRepositoryMockRecorder) SelectAll(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAll", reflect.TypeOf((*MockDriverRepository)(nil).SelectAll), arg0, arg1)
+}
+
+// SelectByID mocks base method.
+func (m *MockDriverRepository) SelectByID(ctx context.Context, id int64, includeDeleted bool) (*domain.Driver, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectByID", ctx, id, includeDeleted)
+	ret0, _ := ret[0].(*domain.Driver)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectByID indicates an expected call of SelectByID.
+func (mr *MockDriverRepositoryMockRecorder) SelectByID(ctx, id, includeDeleted interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByID", reflect.TypeOf((*MockDriverRepository)(nil).SelectByID), ctx, id, includeDeleted)
+}
+
+// Update mocks base method.
+func (m *MockDriverRepository) Update(arg0 context.Context, arg1 domain.Driver) (*domain.Driver, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Driver)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockDriverRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDriverRepository)(nil).Update), arg0, arg1)
+}
+
+// MockRiderRepository is a mock of RiderRepository interface.
+type MockRiderRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRiderRepositoryMockRecorder
+}
+
+// MockRiderRepositoryMockRecorder is the mock recorder for MockRiderRepository.
+type MockRiderRepositoryMockRecorder struct {
+	mock *MockRiderRepository
+}
+
+// NewMockRiderRepository creates a new mock instance.
+func NewMockRiderRepository(ctrl *gomock.Controller) *MockRiderRepository {
+	mock := &MockRiderRepository{ctrl: ctrl}
+	mock.recorder = &MockRiderRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRiderRepository) EXPECT() *MockRiderRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Insert mocks base method.
+func (m *MockRiderRepository) Insert(arg0 context.Context, arg1 domain.Rider) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockRiderRepositoryMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockRiderRepository)(nil).Insert), arg0, arg1)
+}
+
+// SelectByID mocks base method.
+func (m *MockRiderRepository) SelectByID(arg0 context.Context, arg1 int64) (*domain.Rider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectByID", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Rider)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectByID indicates an expected call of SelectByID.
+func (mr *MockRiderRepositoryMockRecorder) SelectByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByID", reflect.TypeOf((*MockRiderRepository)(nil).SelectByID), arg0, arg1)
+}
+
+// SelectByName mocks base method.
+func (m *MockRiderRepository) SelectByName(arg0 context.Context, arg1 string) (*domain.Rider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectByName", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Rider)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectByName indicates an expected call of SelectByName.
+func (mr *MockRiderRepositoryMockRecorder) SelectByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByName", reflect.TypeOf((*MockRiderRepository)(nil).SelectByName), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockRiderRepository) Update(arg0 context.Context, arg1 domain.Rider) (*domain.Rider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Rider)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRiderRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRiderRepository)(nil).Update), arg0, arg1)
+}
+
+// MockVehicleRepository is a mock of VehicleRepository interface.
+type MockVehicleRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleRepositoryMockRecorder
+}
+
+// MockVehicleRepositoryMockRecorder is the mock recorder for MockVehicleRepository.
+type MockVehicleRepositoryMockRecorder struct {
+	mock *MockVehicleRepository
+}
+
+// NewMockVehicleRepository creates a new mock instance.
+func NewMockVehicleRepository(ctrl *gomock.Controller) *MockVehicleRepository {
+	mock := &MockVehicleRepository{ctrl: ctrl}
+	mock.recorder = &MockVehicleRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVehicleRepository) EXPECT() *MockVehicleRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Deactivate mocks base method.
+func (m *MockVehicleRepository) Deactivate(arg0 context.Context, arg1 int64) (*domain.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockVehicleRepositoryMockRecorder) Deactivate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockVehicleRepository)(nil).Deactivate), arg0, arg1)
+}
+
+// Insert mocks base method.
+func (m *MockVehicleRepository) Insert(arg0 context.Context, arg1 domain.Vehicle) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockVehicleRepositoryMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockVehicleRepository)(nil).Insert), arg0, arg1)
+}
+
+// SelectAll mocks base method.
+func (m *MockVehicleRepository) SelectAll(arg0 context.Context, arg1 domain.VehiclePagination) ([]domain.Vehicle, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectAll", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Vehicle)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SelectAll indicates an expected call of SelectAll.
+func (mr *MockVehicleRepositoryMockRecorder) SelectAll(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAll", reflect.TypeOf((*MockVehicleRepository)(nil).SelectAll), arg0, arg1)
+}
+
+// SelectByID mocks base method.
+func (m *MockVehicleRepository) SelectByID(arg0 context.Context, arg1 int64) (*domain.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectByID", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectByID indicates an expected call of SelectByID.
+func (mr *MockVehicleRepositoryMockRecorder) SelectByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByID", reflect.TypeOf((*MockVehicleRepository)(nil).SelectByID), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockVehicleRepository) Update(arg0 context.Context, arg1 domain.Vehicle) (*domain.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockVehicleRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByID", reflect.TypeOf((*MockRideRepository)(nil).SelectByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockVehicleRepository)(nil).Update), arg0, arg1)
 }
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// NewPostgresRideRepository stores rides in Postgres, the queries are the same
+// as for SQLite apart from the placeholders and how inserted IDs are read.
+func NewPostgresRideRepository(db *sql.DB) domain.RideRepository {
+	return newRideRepository(db, postgresDialect())
+}
+
+// NewPostgresDriverRepository stores drivers in Postgres.
+func NewPostgresDriverRepository(db *sql.DB) domain.DriverRepository {
+	return newDriverRepository(db, postgresDialect())
+}
+
+// NewPostgresRiderRepository stores riders in Postgres.
+func NewPostgresRiderRepository(db *sql.DB) domain.RiderRepository {
+	return newRiderRepository(db, postgresDialect())
+}
+
+// NewPostgresVehicleRepository stores vehicles in Postgres.
+func NewPostgresVehicleRepository(db *sql.DB) domain.VehicleRepository {
+	return newVehicleRepository(db, postgresDialect())
+}
+
+func postgresDialect() dialect {
+	return dialect{
+		name:            "postgres",
+		placeholder:     sq.Dollar,
+		migrationsTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, checksum TEXT NOT NULL, appliedAt TIMESTAMPTZ NOT NULL)",
+		columnsQuery:    "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'rides'",
+		returningID:     true,
+		searchMatches:   postgresSearchMatches,
+		dayKey:          "to_char(createdAt AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+		weekKey:         "to_char(date_trunc('week', createdAt AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+		uniqueViolation: postgresUniqueViolation,
+	}
+}
+
+func postgresUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
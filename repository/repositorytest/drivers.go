@@ -0,0 +1,130 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// DriverFactory returns an empty driver repository, every test gets its own.
+type DriverFactory func(t *testing.T) domain.DriverRepository
+
+// RunDrivers runs the driver suite against the repositories returned by the
+// factory.
+func RunDrivers(t *testing.T, factory DriverFactory) {
+	t.Run("Insert", func(t *testing.T) { testInsertDriver(t, factory) })
+	t.Run("SelectAll", func(t *testing.T) { testSelectAllDrivers(t, factory) })
+	t.Run("Update", func(t *testing.T) { testUpdateDriver(t, factory) })
+	t.Run("Delete", func(t *testing.T) { testDeleteDriver(t, factory) })
+}
+
+func newDriver(name string) domain.Driver {
+	return domain.Driver{Name: name, Vehicle: "Car", CreatedAt: baseTime(), UpdatedAt: baseTime()}
+}
+
+func insertDrivers(t *testing.T, repo domain.DriverRepository, names ...string) []int64 {
+	ids := make([]int64, len(names))
+	for i, name := range names {
+		id, err := repo.Insert(context.Background(), newDriver(name))
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	return ids
+}
+
+func testInsertDriver(t *testing.T, factory DriverFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertDrivers(t, repo, "Driver", "Other Driver")
+	assert.Less(t, ids[0], ids[1])
+
+	driver, err := repo.SelectByID(ctx, ids[1], false)
+	require.NoError(t, err)
+	require.NotNil(t, driver)
+	expected := newDriver("Other Driver")
+	expected.ID = ids[1]
+	assert.Equal(t, expected, *driver)
+
+	driver, err = repo.SelectByID(ctx, ids[1]+1, false)
+	assert.NoError(t, err)
+	assert.Nil(t, driver)
+}
+
+func testSelectAllDrivers(t *testing.T, factory DriverFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertDrivers(t, repo, "A", "B", "C")
+
+	drivers, next, err := repo.SelectAll(ctx, domain.DriverPagination{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, ids[:2], driverIDs(drivers))
+	require.NotEmpty(t, next)
+
+	drivers, next, err = repo.SelectAll(ctx, domain.DriverPagination{After: next, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, ids[2:], driverIDs(drivers))
+	assert.Empty(t, next)
+}
+
+func testUpdateDriver(t *testing.T, factory DriverFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertDrivers(t, repo, "Driver")
+
+	update := domain.Driver{ID: ids[0], Name: "New Driver", Vehicle: "Motorcycle"}
+	driver, err := repo.Update(ctx, update)
+	require.NoError(t, err)
+	require.NotNil(t, driver)
+	assert.Equal(t, "New Driver", driver.Name)
+	assert.Equal(t, "Motorcycle", driver.Vehicle)
+	assert.Equal(t, baseTime(), driver.CreatedAt)
+	assert.True(t, driver.UpdatedAt.After(baseTime()))
+
+	update.ID = ids[0] + 1
+	driver, err = repo.Update(ctx, update)
+	assert.NoError(t, err)
+	assert.Nil(t, driver)
+}
+
+func testDeleteDriver(t *testing.T, factory DriverFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertDrivers(t, repo, "A", "B")
+
+	deleted, err := repo.Delete(ctx, ids[0])
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.Delete(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	driver, err := repo.SelectByID(ctx, ids[0], false)
+	assert.NoError(t, err)
+	assert.Nil(t, driver)
+
+	driver, err = repo.SelectByID(ctx, ids[0], true)
+	require.NoError(t, err)
+	require.NotNil(t, driver)
+	assert.Equal(t, "A", driver.Name)
+
+	updated, err := repo.Update(ctx, domain.Driver{ID: ids[0], Name: "A", Vehicle: "Car"})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+
+	drivers, _, err := repo.SelectAll(ctx, domain.DriverPagination{})
+	assert.NoError(t, err)
+	assert.Equal(t, ids[1:], driverIDs(drivers))
+}
+
+func driverIDs(drivers []domain.Driver) []int64 {
+	ids := make([]int64, len(drivers))
+	for i, driver := range drivers {
+		ids[i] = driver.ID
+	}
+	return ids
+}
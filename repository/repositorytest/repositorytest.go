@@ -0,0 +1,1103 @@
+// Package repositorytest checks that implementations of domain.RideRepository,
+// domain.DriverRepository, domain.RiderRepository and domain.VehicleRepository
+// behave the same against a real store.
+package repositorytest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// Factory returns an empty repository, every test gets its own.
+type Factory func(t *testing.T) domain.RideRepository
+
+// Run runs the suite against the repositories returned by the factory. Cursors
+// are only ever passed back as they were returned, so their format is up to the
+// implementation. Search is skipped when the repository can't search.
+func Run(t *testing.T, factory Factory) {
+	t.Run("Insert", func(t *testing.T) { testInsert(t, factory) })
+	t.Run("InsertBatch", func(t *testing.T) { testInsertBatch(t, factory) })
+	t.Run("SelectByID", func(t *testing.T) { testSelectByID(t, factory) })
+	t.Run("SelectAll", func(t *testing.T) { testSelectAll(t, factory) })
+	t.Run("SelectAllCursor", func(t *testing.T) { testSelectAllCursor(t, factory) })
+	t.Run("SelectAllCounts", func(t *testing.T) { testSelectAllCounts(t, factory) })
+	t.Run("SelectNearby", func(t *testing.T) { testSelectNearby(t, factory) })
+	t.Run("Search", func(t *testing.T) { testSearch(t, factory) })
+	t.Run("Stats", func(t *testing.T) { testStats(t, factory) })
+	t.Run("Export", func(t *testing.T) { testExport(t, factory) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, factory) })
+	t.Run("UpdateStatus", func(t *testing.T) { testUpdateStatus(t, factory) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory) })
+	t.Run("Restore", func(t *testing.T) { testRestore(t, factory) })
+}
+
+func baseTime() time.Time {
+	return time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC)
+}
+
+// newRide returns the i-th ride of a set, rides start at the same point and
+// end further east the larger i is, each created an hour after the previous.
+func newRide(i int) domain.Ride {
+	createdAt := baseTime().Add(time.Duration(i) * time.Hour)
+	return domain.Ride{
+		StartLatitude:  0,
+		StartLongitude: 0,
+		EndLatitude:    0,
+		EndLongitude:   float64(i+1) / 100,
+		RiderName:      "John Doe",
+		DriverName:     "Driver",
+		DriverVehicle:  "Car",
+		Status:         domain.RideStatusRequested,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+	}
+}
+
+// insertRides inserts count rides made by newRide and returns their IDs. The
+// rider names, when given, are used in the same order instead of John Doe.
+func insertRides(t *testing.T, repo domain.RideRepository, count int, riders ...string) []int64 {
+	ids := make([]int64, count)
+	for i := range ids {
+		ride := newRide(i)
+		if i < len(riders) {
+			ride.RiderName = riders[i]
+		}
+		id, err := repo.Insert(context.Background(), ride)
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	return ids
+}
+
+func rideIDs(rides []domain.Ride) []int64 {
+	ids := []int64{}
+	for _, ride := range rides {
+		ids = append(ids, ride.ID)
+	}
+	return ids
+}
+
+// pick returns the IDs at the given indexes.
+func pick(ids []int64, indexes ...int) []int64 {
+	picked := []int64{}
+	for _, i := range indexes {
+		picked = append(picked, ids[i])
+	}
+	return picked
+}
+
+// assertRide compares the stored ride with the one it was made from, times are
+// compared as instants as stores may return them in another location.
+func assertRide(t *testing.T, expected domain.Ride, actual *domain.Ride) {
+	if !assert.NotNil(t, actual) {
+		return
+	}
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.StartLatitude, actual.StartLatitude)
+	assert.Equal(t, expected.StartLongitude, actual.StartLongitude)
+	assert.Equal(t, expected.EndLatitude, actual.EndLatitude)
+	assert.Equal(t, expected.EndLongitude, actual.EndLongitude)
+	assert.Equal(t, expected.RiderName, actual.RiderName)
+	assert.Equal(t, expected.DriverName, actual.DriverName)
+	assert.Equal(t, expected.DriverVehicle, actual.DriverVehicle)
+	assert.InDelta(t, expected.Distance(), actual.DistanceMeters, 1e-6)
+	assert.InDelta(t, expected.Bearing(), actual.BearingDegrees, 1e-6)
+	assert.Equal(t, expected.Status, actual.Status)
+	assertTime(t, expected.StartedAt, actual.StartedAt)
+	assertTime(t, expected.EndedAt, actual.EndedAt)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "createdAt %s, expected %s", actual.CreatedAt, expected.CreatedAt)
+	assert.Nil(t, actual.DeletedAt)
+}
+
+func assertTime(t *testing.T, expected, actual *time.Time) {
+	if expected == nil {
+		assert.Nil(t, actual)
+		return
+	}
+	if assert.NotNil(t, actual) {
+		assert.True(t, expected.Equal(*actual), "%s, expected %s", *actual, *expected)
+	}
+}
+
+func testInsert(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+
+	startedAt := baseTime().Add(time.Minute)
+	ride := newRide(0)
+	ride.Status = domain.RideStatusStarted
+	ride.StartedAt = &startedAt
+
+	id, err := repo.Insert(ctx, ride)
+	require.NoError(t, err)
+	otherID, err := repo.Insert(ctx, newRide(1))
+	require.NoError(t, err)
+	assert.Greater(t, otherID, id)
+
+	stored, err := repo.SelectByID(ctx, id, false)
+	assert.NoError(t, err)
+	ride.ID = id
+	assertRide(t, ride, stored)
+}
+
+func testInsertBatch(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+
+	ids, err := repo.InsertBatch(ctx, []domain.Ride{newRide(0), newRide(1), newRide(2)})
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
+
+	for i, id := range ids {
+		stored, err := repo.SelectByID(ctx, id, false)
+		assert.NoError(t, err)
+		ride := newRide(i)
+		ride.ID = id
+		assertRide(t, ride, stored)
+	}
+
+	rides, _, err := repo.SelectAll(ctx, domain.Pagination{})
+	assert.NoError(t, err)
+	assert.Equal(t, pick(ids, 2, 1, 0), rideIDs(rides))
+}
+
+func testSelectByID(t *testing.T, factory Factory) {
+	testCases := []struct {
+		testName       string
+		id             func(ids []int64) int64
+		deleted        bool
+		includeDeleted bool
+		expectedFound  bool
+	}{
+		{
+			testName:      "When ride exists, return it",
+			id:            func(ids []int64) int64 { return ids[1] },
+			expectedFound: true,
+		},
+		{
+			testName: "When ride doesn't exist, return nothing",
+			id:       func(ids []int64) int64 { return ids[2] + 1000 },
+		},
+		{
+			testName: "When ride is deleted, return nothing",
+			id:       func(ids []int64) int64 { return ids[1] },
+			deleted:  true,
+		},
+		{
+			testName:       "When ride is deleted and deleted rides are included, return it",
+			id:             func(ids []int64) int64 { return ids[1] },
+			deleted:        true,
+			includeDeleted: true,
+			expectedFound:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := factory(t)
+			ids := insertRides(t, repo, 3)
+			if tc.deleted {
+				_, err := repo.Delete(ctx, ids[1])
+				require.NoError(t, err)
+			}
+
+			ride, err := repo.SelectByID(ctx, tc.id(ids), tc.includeDeleted)
+			assert.NoError(t, err)
+			if !tc.expectedFound {
+				assert.Nil(t, ride)
+				return
+			}
+			if assert.NotNil(t, ride) {
+				assert.Equal(t, tc.id(ids), ride.ID)
+				assert.Equal(t, tc.deleted, ride.DeletedAt != nil)
+			}
+		})
+	}
+}
+
+func testSelectAll(t *testing.T, factory Factory) {
+	distance := func(i int) float64 {
+		return newRide(i).Distance()
+	}
+
+	testCases := []struct {
+		testName string
+		count    int
+		deleted  []int
+		riders   []string
+		page     domain.Pagination
+		// expectedPages lists the indexes of the rides on every page.
+		expectedPages [][]int
+	}{
+		{
+			testName:      "When there are no rides, return an empty page",
+			page:          domain.Pagination{Limit: 2},
+			expectedPages: [][]int{{}},
+		},
+		{
+			testName:      "When there is no limit, return every ride latest first",
+			count:         5,
+			expectedPages: [][]int{{4, 3, 2, 1, 0}},
+		},
+		{
+			testName:      "When limit is one, return a ride per page",
+			count:         5,
+			page:          domain.Pagination{Limit: 1},
+			expectedPages: [][]int{{4}, {3}, {2}, {1}, {0}},
+		},
+		{
+			testName:      "When limit doesn't divide the rides, return a shorter last page",
+			count:         5,
+			page:          domain.Pagination{Limit: 2},
+			expectedPages: [][]int{{4, 3}, {2, 1}, {0}},
+		},
+		{
+			testName:      "When limit is one less than the rides, return a single ride on the last page",
+			count:         5,
+			page:          domain.Pagination{Limit: 4},
+			expectedPages: [][]int{{4, 3, 2, 1}, {0}},
+		},
+		{
+			testName:      "When limit is the same as the rides, return a single page",
+			count:         5,
+			page:          domain.Pagination{Limit: 5},
+			expectedPages: [][]int{{4, 3, 2, 1, 0}},
+		},
+		{
+			testName:      "When limit is more than the rides, return a single page",
+			count:         5,
+			page:          domain.Pagination{Limit: 10},
+			expectedPages: [][]int{{4, 3, 2, 1, 0}},
+		},
+		{
+			testName:      "When rides are deleted, skip them",
+			count:         5,
+			deleted:       []int{3, 1},
+			page:          domain.Pagination{Limit: 2},
+			expectedPages: [][]int{{4, 2}, {0}},
+		},
+		{
+			testName:      "When every ride is deleted, return an empty page",
+			count:         2,
+			deleted:       []int{0, 1},
+			page:          domain.Pagination{Limit: 2},
+			expectedPages: [][]int{{}},
+		},
+		{
+			testName:      "When deleted rides are included, return them",
+			count:         5,
+			deleted:       []int{3},
+			page:          domain.Pagination{Limit: 2, IncludeDeleted: true},
+			expectedPages: [][]int{{4, 3}, {2, 1}, {0}},
+		},
+		{
+			testName:      "When filtered by creation time, include from and exclude to",
+			count:         5,
+			page:          domain.Pagination{Limit: 2, From: newRide(1).CreatedAt, To: newRide(4).CreatedAt},
+			expectedPages: [][]int{{3, 2}, {1}},
+		},
+		{
+			testName:      "When filtered by distance, include both bounds",
+			count:         5,
+			page:          domain.Pagination{Limit: 1, MinDistance: distance(2), MaxDistance: distance(3)},
+			expectedPages: [][]int{{3}, {2}},
+		},
+		{
+			testName:      "When filters match nothing, return an empty page",
+			count:         5,
+			page:          domain.Pagination{Limit: 2, From: newRide(5).CreatedAt},
+			expectedPages: [][]int{{}},
+		},
+		{
+			testName:      "When filtered by rider name, only return rides with exactly that name",
+			count:         5,
+			riders:        []string{"Bob", "Al", "Bobby", "Alice", "Bo"},
+			page:          domain.Pagination{Limit: 1, RiderName: "Al"},
+			expectedPages: [][]int{{1}},
+		},
+		{
+			testName:      "When filtered by rider name prefix, return rides with names starting with it",
+			count:         5,
+			riders:        []string{"Bob", "Al", "Bobby", "Alice", "Bo"},
+			page:          domain.Pagination{Limit: 2, RiderNamePrefix: "Bob"},
+			expectedPages: [][]int{{2, 0}},
+		},
+		{
+			testName:      "When filtered by names of every kind, return rides matching all of them",
+			count:         5,
+			riders:        []string{"Bob", "Al", "Bobby", "Alice", "bob"},
+			page:          domain.Pagination{Limit: 1, RiderNamePrefix: "B", DriverName: "Driver", DriverVehiclePrefix: "Ca"},
+			expectedPages: [][]int{{2}, {0}},
+		},
+		{
+			testName:      "When filtered by a name nobody has, return an empty page",
+			count:         5,
+			page:          domain.Pagination{Limit: 2, DriverVehicle: "Ca"},
+			expectedPages: [][]int{{}},
+		},
+		{
+			testName:      "When sorted by ID, return earliest first",
+			count:         5,
+			page:          domain.Pagination{Limit: 2, Sort: "id"},
+			expectedPages: [][]int{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			testName:      "When sorted by distance, return shortest first",
+			count:         5,
+			page:          domain.Pagination{Limit: 2, Sort: "distance"},
+			expectedPages: [][]int{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			testName:      "When sorted by distance descending, return longest first",
+			count:         5,
+			page:          domain.Pagination{Limit: 2, Sort: "-distance"},
+			expectedPages: [][]int{{4, 3}, {2, 1}, {0}},
+		},
+		{
+			testName:      "When sorted by a tied field, return ties latest first",
+			count:         5,
+			riders:        []string{"B", "A", "B", "A", "B"},
+			page:          domain.Pagination{Limit: 2, Sort: "-riderName"},
+			expectedPages: [][]int{{4, 2}, {0, 3}, {1}},
+		},
+		{
+			testName:      "When sorted by several fields, sort by the next one on ties",
+			count:         5,
+			riders:        []string{"B", "A", "B", "A", "B"},
+			page:          domain.Pagination{Limit: 2, Sort: "riderName,createdAt"},
+			expectedPages: [][]int{{1, 3}, {0, 2}, {4}},
+		},
+		{
+			testName:      "When sorted and filtered, page through the matching rides",
+			count:         5,
+			deleted:       []int{2},
+			page:          domain.Pagination{Limit: 1, Sort: "createdAt", From: newRide(1).CreatedAt},
+			expectedPages: [][]int{{1}, {3}, {4}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := factory(t)
+			ids := insertRides(t, repo, tc.count, tc.riders...)
+			for _, i := range tc.deleted {
+				_, err := repo.Delete(ctx, ids[i])
+				require.NoError(t, err)
+			}
+
+			// NOTE: The pages are walked forward to the last one and back again,
+			// which returns the same pages.
+			page := tc.page
+			last := len(tc.expectedPages) - 1
+			var cursors domain.PageCursors
+			for i, expected := range tc.expectedPages {
+				rides, pageCursors, err := repo.SelectAll(ctx, page)
+				require.NoError(t, err)
+				assert.NotNil(t, rides)
+				assert.Equal(t, pick(ids, expected...), rideIDs(rides), "page %d", i)
+				assert.Equal(t, i == 0, pageCursors.Prev == "", "previous cursor of page %d", i)
+				cursors = pageCursors.PageCursors
+
+				if i == last {
+					assert.Empty(t, cursors.Next, "page %d", i)
+					break
+				}
+				require.NotEmpty(t, cursors.Next, "page %d", i)
+				page.After = cursors.Next
+			}
+
+			page.After = ""
+			for i := last - 1; i >= 0; i-- {
+				page.Before = cursors.Prev
+				rides, pageCursors, err := repo.SelectAll(ctx, page)
+				require.NoError(t, err)
+				assert.Equal(t, pick(ids, tc.expectedPages[i]...), rideIDs(rides), "page %d back", i)
+				assert.NotEmpty(t, pageCursors.Next, "page %d back", i)
+				assert.Equal(t, i == 0, pageCursors.Prev == "", "previous cursor of page %d back", i)
+				cursors = pageCursors.PageCursors
+			}
+		})
+	}
+}
+
+func testSelectAllCursor(t *testing.T, factory Factory) {
+	t.Run("When the ride the cursor points at is deleted, continue after it", func(t *testing.T) {
+		ctx := context.Background()
+		repo := factory(t)
+		ids := insertRides(t, repo, 4)
+
+		rides, cursors, err := repo.SelectAll(ctx, domain.Pagination{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, pick(ids, 3, 2), rideIDs(rides))
+		_, err = repo.Delete(ctx, ids[2])
+		require.NoError(t, err)
+
+		rides, cursors, err = repo.SelectAll(ctx, domain.Pagination{Limit: 2, After: cursors.Next})
+		require.NoError(t, err)
+		assert.Equal(t, pick(ids, 1, 0), rideIDs(rides))
+		assert.Empty(t, cursors.Next)
+
+		rides, _, err = repo.SelectAll(ctx, domain.Pagination{Limit: 2, Before: cursors.Prev})
+		assert.NoError(t, err)
+		assert.Equal(t, pick(ids, 3), rideIDs(rides))
+	})
+
+	t.Run("When rides are inserted between pages, don't return them", func(t *testing.T) {
+		ctx := context.Background()
+		repo := factory(t)
+		ids := insertRides(t, repo, 3)
+
+		rides, cursors, err := repo.SelectAll(ctx, domain.Pagination{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, pick(ids, 2, 1), rideIDs(rides))
+		insertRides(t, repo, 1)
+
+		rides, cursors, err = repo.SelectAll(ctx, domain.Pagination{Limit: 2, After: cursors.Next})
+		assert.NoError(t, err)
+		assert.Equal(t, pick(ids, 0), rideIDs(rides))
+		assert.Empty(t, cursors.Next)
+	})
+
+	t.Run("When rides are inserted while paging back, return them", func(t *testing.T) {
+		ctx := context.Background()
+		repo := factory(t)
+		ids := insertRides(t, repo, 3)
+
+		_, cursors, err := repo.SelectAll(ctx, domain.Pagination{Limit: 2})
+		require.NoError(t, err)
+		_, cursors, err = repo.SelectAll(ctx, domain.Pagination{Limit: 2, After: cursors.Next})
+		require.NoError(t, err)
+		ids = append(ids, insertRides(t, repo, 1)...)
+
+		rides, cursors, err := repo.SelectAll(ctx, domain.Pagination{Limit: 2, Before: cursors.Prev})
+		assert.NoError(t, err)
+		assert.Equal(t, pick(ids, 2, 1), rideIDs(rides))
+		assert.NotEmpty(t, cursors.Prev)
+
+		rides, cursors, err = repo.SelectAll(ctx, domain.Pagination{Limit: 2, Before: cursors.Prev})
+		assert.NoError(t, err)
+		assert.Equal(t, pick(ids, 3), rideIDs(rides))
+		assert.Empty(t, cursors.Prev)
+	})
+
+	t.Run("When cursor is invalid, return error", func(t *testing.T) {
+		repo := factory(t)
+		insertRides(t, repo, 1)
+
+		_, _, err := repo.SelectAll(context.Background(), domain.Pagination{Limit: 2, After: "abc"})
+		assert.Error(t, err)
+		_, _, err = repo.SelectAll(context.Background(), domain.Pagination{Limit: 2, Before: "abc"})
+		assert.Error(t, err)
+	})
+}
+
+func testSelectAllCounts(t *testing.T, factory Factory) {
+	// insertDrivers inserts a ride for every driver name and vehicle pair.
+	insertDrivers := func(t *testing.T, repo domain.RideRepository, drivers [][2]string) []int64 {
+		ids := make([]int64, len(drivers))
+		for i, driver := range drivers {
+			ride := newRide(i)
+			ride.DriverName, ride.DriverVehicle = driver[0], driver[1]
+			id, err := repo.Insert(context.Background(), ride)
+			require.NoError(t, err)
+			ids[i] = id
+		}
+		return ids
+	}
+
+	t.Run("When including the total and facets, count every ride matching the filters on any page", func(t *testing.T) {
+		ctx := context.Background()
+		repo := factory(t)
+		ids := insertDrivers(t, repo, [][2]string{
+			{"Driver", "Car"},
+			{"Driver", "Car"},
+			{"Other Driver", "Car"},
+			{"Driver", "Van"},
+			{"Other Driver", "Bike"},
+		})
+		_, err := repo.Delete(ctx, ids[4])
+		require.NoError(t, err)
+
+		page := domain.Pagination{Limit: 1, Include: "total,facets"}
+		for i := 0; i < 2; i++ {
+			rides, info, err := repo.SelectAll(ctx, page)
+			require.NoError(t, err)
+			assert.Len(t, rides, 1, "page %d", i)
+			if assert.NotNil(t, info.Total, "page %d", i) {
+				assert.Equal(t, int64(4), *info.Total, "page %d", i)
+			}
+			assert.Equal(t, map[string][]domain.FacetCount{
+				"driverName":    {{Value: "Driver", Count: 3}, {Value: "Other Driver", Count: 1}},
+				"driverVehicle": {{Value: "Car", Count: 3}, {Value: "Van", Count: 1}},
+			}, info.Facets, "page %d", i)
+			page.After = info.Next
+		}
+
+		_, info, err := repo.SelectAll(ctx, domain.Pagination{DriverName: "Driver", Include: "total,facets"})
+		require.NoError(t, err)
+		if assert.NotNil(t, info.Total) {
+			assert.Equal(t, int64(3), *info.Total)
+		}
+		assert.Equal(t, map[string][]domain.FacetCount{
+			"driverName":    {{Value: "Driver", Count: 3}},
+			"driverVehicle": {{Value: "Car", Count: 2}, {Value: "Van", Count: 1}},
+		}, info.Facets)
+	})
+
+	t.Run("When including only the total, don't count facets", func(t *testing.T) {
+		repo := factory(t)
+		insertRides(t, repo, 2)
+
+		_, info, err := repo.SelectAll(context.Background(), domain.Pagination{Include: "total"})
+		require.NoError(t, err)
+		if assert.NotNil(t, info.Total) {
+			assert.Equal(t, int64(2), *info.Total)
+		}
+		assert.Nil(t, info.Facets)
+	})
+
+	t.Run("When nothing is included, don't count", func(t *testing.T) {
+		repo := factory(t)
+		insertRides(t, repo, 2)
+
+		_, info, err := repo.SelectAll(context.Background(), domain.Pagination{})
+		require.NoError(t, err)
+		assert.Nil(t, info.Total)
+		assert.Nil(t, info.Facets)
+	})
+
+	t.Run("When there are many values, return the most common ones first", func(t *testing.T) {
+		repo := factory(t)
+		drivers := [][2]string{{"Driver", "Car 11"}}
+		for i := 0; i < 12; i++ {
+			drivers = append(drivers, [2]string{"Driver", "Car " + strconv.Itoa(10+i)})
+		}
+		insertDrivers(t, repo, drivers)
+
+		_, info, err := repo.SelectAll(context.Background(), domain.Pagination{Include: "facets"})
+		require.NoError(t, err)
+		expected := []domain.FacetCount{{Value: "Car 11", Count: 2}}
+		for i := 0; i < 10; i++ {
+			if i != 1 {
+				expected = append(expected, domain.FacetCount{Value: "Car " + strconv.Itoa(10+i), Count: 1})
+			}
+		}
+		assert.Nil(t, info.Total)
+		assert.Equal(t, expected, info.Facets["driverVehicle"])
+	})
+
+	t.Run("When there are no rides, return empty facets", func(t *testing.T) {
+		repo := factory(t)
+
+		_, info, err := repo.SelectAll(context.Background(), domain.Pagination{Include: "total,facets"})
+		require.NoError(t, err)
+		if assert.NotNil(t, info.Total) {
+			assert.Equal(t, int64(0), *info.Total)
+		}
+		assert.Equal(t, map[string][]domain.FacetCount{
+			"driverName":    {},
+			"driverVehicle": {},
+		}, info.Facets)
+	})
+}
+
+func testSelectNearby(t *testing.T, factory Factory) {
+	// NOTE: Rides end about 1.1 km apart from each other going east.
+	query := func(radius float64, limit uint64) domain.NearbyQuery {
+		return domain.NearbyQuery{
+			Latitude:   0,
+			Longitude:  0,
+			Radius:     radius,
+			Endpoint:   domain.RideEndpointEnd,
+			Pagination: domain.Pagination{Limit: limit},
+		}
+	}
+
+	testCases := []struct {
+		testName      string
+		query         domain.NearbyQuery
+		deleted       []int
+		expectedPages [][]int
+	}{
+		{
+			testName:      "When no ride is within the radius, return an empty page",
+			query:         query(500, 2),
+			expectedPages: [][]int{{}},
+		},
+		{
+			testName:      "When there is no limit, return every ride within the radius nearest first",
+			query:         query(3500, 0),
+			expectedPages: [][]int{{0, 1, 2}},
+		},
+		{
+			testName:      "When there are more rides than the limit, return them across pages",
+			query:         query(3500, 2),
+			expectedPages: [][]int{{0, 1}, {2}},
+		},
+		{
+			testName:      "When rides start at the center, return every ride",
+			query:         domain.NearbyQuery{Radius: 100, Endpoint: domain.RideEndpointStart, Pagination: domain.Pagination{Limit: 3}},
+			expectedPages: [][]int{{0, 1, 2}, {3, 4}},
+		},
+		{
+			testName:      "When rides are deleted, skip them",
+			query:         query(3500, 2),
+			deleted:       []int{1},
+			expectedPages: [][]int{{0, 2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := factory(t)
+			ids := insertRides(t, repo, 5)
+			for _, i := range tc.deleted {
+				_, err := repo.Delete(ctx, ids[i])
+				require.NoError(t, err)
+			}
+
+			q := tc.query
+			for i, expected := range tc.expectedPages {
+				rides, cursor, err := repo.SelectNearby(ctx, q)
+				require.NoError(t, err)
+				assert.NotNil(t, rides)
+				assert.Equal(t, pick(ids, expected...), rideIDs(rides), "page %d", i)
+
+				if i == len(tc.expectedPages)-1 {
+					assert.Empty(t, cursor, "page %d", i)
+					break
+				}
+				require.NotEmpty(t, cursor, "page %d", i)
+				q.After = cursor
+			}
+		})
+	}
+
+	t.Run("When more rides than are read at once are within the radius, return error without a limit", func(t *testing.T) {
+		ctx := context.Background()
+		repo := factory(t)
+		// NOTE: Repositories read at most 10000 rides of a search without a
+		// limit.
+		rides := make([]domain.Ride, 10001)
+		for i := range rides {
+			rides[i] = newRide(i)
+		}
+		_, err := repo.InsertBatch(ctx, rides)
+		require.NoError(t, err)
+
+		_, _, err = repo.SelectNearby(ctx, domain.NearbyQuery{Radius: 100, Endpoint: domain.RideEndpointStart})
+		assert.Equal(t, domain.ErrTooManyNearbyRides, err)
+
+		page, cursor, err := repo.SelectNearby(ctx, domain.NearbyQuery{Radius: 100, Endpoint: domain.RideEndpointStart, Pagination: domain.Pagination{Limit: 10}})
+		assert.NoError(t, err)
+		assert.Len(t, page, 10)
+		assert.NotEmpty(t, cursor)
+	})
+}
+
+func testSearch(t *testing.T, factory Factory) {
+	riders := []string{"John Doe", "Jane Roe", "Johnny Cash", "Bob"}
+	query := func(q string, limit uint64) domain.SearchQuery {
+		return domain.SearchQuery{Q: q, Pagination: domain.Pagination{Limit: limit}}
+	}
+
+	testCases := []struct {
+		testName      string
+		query         domain.SearchQuery
+		deleted       []int
+		expectedPages [][]int
+	}{
+		{
+			testName:      "When nothing matches, return an empty page",
+			query:         query("xyz", 2),
+			expectedPages: [][]int{{}},
+		},
+		{
+			testName:      "When several rides match, return all of them",
+			query:         query("john", 0),
+			expectedPages: [][]int{{0, 2}},
+		},
+		{
+			testName:      "When searching part of a name, return the best match first",
+			query:         query("ohnn", 0),
+			expectedPages: [][]int{{2, 0}},
+		},
+		{
+			testName:      "When searching a misspelled name, return rides sharing part of it",
+			query:         query("Jhon DOE", 0),
+			expectedPages: [][]int{{0}},
+		},
+		{
+			testName:      "When there are more rides than the limit, return them across pages",
+			query:         query("john", 1),
+			expectedPages: [][]int{{0}, {2}},
+		},
+		{
+			testName:      "When rides are deleted, skip them",
+			query:         query("john", 2),
+			deleted:       []int{0},
+			expectedPages: [][]int{{2}},
+		},
+		{
+			testName: "When filtered, only return matching rides",
+			query: domain.SearchQuery{
+				Q:          "john",
+				Pagination: domain.Pagination{Limit: 2, From: newRide(1).CreatedAt},
+			},
+			expectedPages: [][]int{{2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := factory(t)
+			ids := insertRides(t, repo, len(riders), riders...)
+			for _, i := range tc.deleted {
+				_, err := repo.Delete(ctx, ids[i])
+				require.NoError(t, err)
+			}
+
+			q := tc.query
+			for i, expected := range tc.expectedPages {
+				rides, cursor, err := repo.Search(ctx, q)
+				if errors.Is(err, domain.ErrSearchUnavailable) {
+					t.Skip(err)
+				}
+				require.NoError(t, err)
+				assert.NotNil(t, rides)
+				assert.Equal(t, pick(ids, expected...), rideIDs(rides), "page %d", i)
+
+				if i == len(tc.expectedPages)-1 {
+					assert.Empty(t, cursor, "page %d", i)
+					break
+				}
+				require.NotEmpty(t, cursor, "page %d", i)
+				q.After = cursor
+			}
+		})
+	}
+
+	t.Run("When names are updated, search the new ones", func(t *testing.T) {
+		ctx := context.Background()
+		repo := factory(t)
+		ids := insertRides(t, repo, len(riders), riders...)
+
+		ride := newRide(3)
+		ride.ID = ids[3]
+		ride.DriverName = "Johanna"
+		_, err := repo.Update(ctx, ride)
+		require.NoError(t, err)
+
+		rides, _, err := repo.Search(ctx, domain.SearchQuery{Q: "johanna"})
+		if errors.Is(err, domain.ErrSearchUnavailable) {
+			t.Skip(err)
+		}
+		require.NoError(t, err)
+		assert.Equal(t, pick(ids, 3), rideIDs(rides)[:1])
+		rides, _, err = repo.Search(ctx, domain.SearchQuery{Q: "driver"})
+		require.NoError(t, err)
+		assert.Equal(t, pick(ids, 0, 1, 2), rideIDs(rides))
+	})
+}
+
+func testStats(t *testing.T, factory Factory) {
+	// statsRide returns the i-th ride of the set, each created 20 hours after
+	// the previous from Thursday on so that they span two weeks.
+	statsRide := func(i int) domain.Ride {
+		ride := newRide(i)
+		ride.CreatedAt = baseTime().Add(time.Duration(i*20) * time.Hour)
+		ride.UpdatedAt = ride.CreatedAt
+		ride.RiderName = []string{"John Doe", "Jane Roe", "Bob"}[i%3]
+		ride.DriverName = []string{"Driver A", "Driver B"}[i%2]
+		if i >= 7 {
+			ride.DriverVehicle = "Van"
+		}
+		return ride
+	}
+	// expectedStats summarizes the rides at the indexes, which are sorted by
+	// distance as rides end further east the larger i is. The rides aren't
+	// linked to riders, so no rider is counted, RunRiderRides counts them.
+	expectedStats := func(key string, indexes ...int) domain.RideStats {
+		stats := domain.RideStats{Key: key, Count: int64(len(indexes))}
+		for _, i := range indexes {
+			stats.TotalDistanceMeters += statsRide(i).Distance()
+		}
+		stats.AvgDistanceMeters = stats.TotalDistanceMeters / float64(len(indexes))
+		stats.P50DistanceMeters = statsRide(indexes[(len(indexes)*50+99)/100-1]).Distance()
+		stats.P95DistanceMeters = statsRide(indexes[(len(indexes)*95+99)/100-1]).Distance()
+		return stats
+	}
+
+	testCases := []struct {
+		testName      string
+		query         domain.StatsQuery
+		deleted       []int
+		expectedStats []domain.RideStats
+	}{
+		{
+			testName: "When grouped by day, summarize the rides created on each UTC day",
+			query:    domain.StatsQuery{GroupBy: "day"},
+			expectedStats: []domain.RideStats{
+				expectedStats("2021-04-01", 0),
+				expectedStats("2021-04-02", 1),
+				expectedStats("2021-04-03", 2, 3),
+				expectedStats("2021-04-04", 4),
+				expectedStats("2021-04-05", 5),
+				expectedStats("2021-04-06", 6),
+				expectedStats("2021-04-07", 7),
+				expectedStats("2021-04-08", 8, 9),
+			},
+		},
+		{
+			testName: "When grouped by week, summarize the rides created from each Monday",
+			query:    domain.StatsQuery{GroupBy: "week"},
+			expectedStats: []domain.RideStats{
+				expectedStats("2021-03-29", 0, 1, 2, 3, 4),
+				expectedStats("2021-04-05", 5, 6, 7, 8, 9),
+			},
+		},
+		{
+			testName: "When grouped by driver name, summarize the rides of each driver",
+			query:    domain.StatsQuery{GroupBy: "driverName"},
+			expectedStats: []domain.RideStats{
+				expectedStats("Driver A", 0, 2, 4, 6, 8),
+				expectedStats("Driver B", 1, 3, 5, 7, 9),
+			},
+		},
+		{
+			testName: "When filtered and rides are deleted, only summarize the matching rides",
+			query:    domain.StatsQuery{GroupBy: "driverVehicle", Pagination: domain.Pagination{From: statsRide(5).CreatedAt}},
+			deleted:  []int{9},
+			expectedStats: []domain.RideStats{
+				expectedStats("Car", 5, 6),
+				expectedStats("Van", 7, 8),
+			},
+		},
+		{
+			testName:      "When no ride matches, return no stats",
+			query:         domain.StatsQuery{GroupBy: "day", Pagination: domain.Pagination{DriverName: "Nobody"}},
+			expectedStats: []domain.RideStats{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := factory(t)
+			ids := make([]int64, 10)
+			for i := range ids {
+				id, err := repo.Insert(ctx, statsRide(i))
+				require.NoError(t, err)
+				ids[i] = id
+			}
+			for _, i := range tc.deleted {
+				_, err := repo.Delete(ctx, ids[i])
+				require.NoError(t, err)
+			}
+
+			stats, err := repo.Stats(ctx, tc.query)
+			require.NoError(t, err)
+			require.Len(t, stats, len(tc.expectedStats))
+			for i, expected := range tc.expectedStats {
+				actual := stats[i]
+				assert.Equal(t, expected.Key, actual.Key)
+				assert.Equal(t, expected.Count, actual.Count, expected.Key)
+				assert.InDelta(t, expected.TotalDistanceMeters, actual.TotalDistanceMeters, 1e-6, expected.Key)
+				assert.InDelta(t, expected.AvgDistanceMeters, actual.AvgDistanceMeters, 1e-6, expected.Key)
+				assert.InDelta(t, expected.P50DistanceMeters, actual.P50DistanceMeters, 1e-6, expected.Key)
+				assert.InDelta(t, expected.P95DistanceMeters, actual.P95DistanceMeters, 1e-6, expected.Key)
+				assert.Equal(t, expected.UniqueRiders, actual.UniqueRiders, expected.Key)
+			}
+		})
+	}
+
+	t.Run("When groupBy is unknown, return error", func(t *testing.T) {
+		_, err := factory(t).Stats(context.Background(), domain.StatsQuery{GroupBy: "rider"})
+		assert.Error(t, err)
+	})
+}
+
+func testExport(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertRides(t, repo, 3)
+	_, err := repo.Delete(ctx, ids[1])
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Export(ctx, domain.Pagination{Limit: 1}, &buf))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{
+		"id",
+		"startLat",
+		"startLong",
+		"endLat",
+		"endLong",
+		"riderName",
+		"driverName",
+		"driverVehicle",
+		"distance",
+		"bearing",
+		"status",
+		"startedAt",
+		"endedAt",
+		"createdAt",
+		"updatedAt",
+		"deletedAt",
+		"driverId",
+		"riderId",
+		"vehicleId",
+	}, records[0])
+	// NOTE: The deleted ride is skipped and the limit is ignored.
+	for i, index := range []int{2, 0} {
+		record := records[i+1]
+		assert.Equal(t, strconv.FormatInt(ids[index], 10), record[0])
+		assert.Equal(t, []string{"0", "0", "0", strconv.FormatFloat(newRide(index).EndLongitude, 'f', -1, 64), "John Doe", "Driver", "Car"}, record[1:8])
+		assert.Equal(t, "requested", record[10])
+		assert.Empty(t, record[15])
+	}
+
+	buf.Reset()
+	require.NoError(t, repo.Export(ctx, domain.Pagination{Sort: "distance"}, &buf))
+	records, err = csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, strconv.FormatInt(ids[0], 10), records[1][0])
+	assert.Equal(t, strconv.FormatInt(ids[2], 10), records[2][0])
+}
+
+func testUpdate(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertRides(t, repo, 2)
+	_, err := repo.UpdateStatus(ctx, ids[0], domain.RideStatusAccepted)
+	require.NoError(t, err)
+
+	update := newRide(3)
+	update.ID = ids[0]
+	update.RiderName = "Jane Doe"
+	update.Status = domain.RideStatusCompleted
+
+	ride, err := repo.Update(ctx, update)
+	assert.NoError(t, err)
+	// NOTE: The status and creation time are kept.
+	update.Status = domain.RideStatusAccepted
+	update.CreatedAt = newRide(0).CreatedAt
+	assertRide(t, update, ride)
+	if ride != nil {
+		assert.True(t, ride.UpdatedAt.After(update.UpdatedAt))
+	}
+
+	other, err := repo.SelectByID(ctx, ids[1], false)
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", other.RiderName)
+
+	update.ID = ids[1] + 1000
+	ride, err = repo.Update(ctx, update)
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+
+	_, err = repo.Delete(ctx, ids[1])
+	require.NoError(t, err)
+	update.ID = ids[1]
+	ride, err = repo.Update(ctx, update)
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+}
+
+func testUpdateStatus(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertRides(t, repo, 2)
+
+	ride, err := repo.UpdateStatus(ctx, ids[0], domain.RideStatusStarted)
+	assert.Nil(t, ride)
+	assert.Equal(t, domain.RideTransitionError{From: domain.RideStatusRequested, To: domain.RideStatusStarted}, err)
+
+	for _, status := range []domain.RideStatus{domain.RideStatusAccepted, domain.RideStatusStarted, domain.RideStatusCompleted} {
+		ride, err = repo.UpdateStatus(ctx, ids[0], status)
+		assert.NoError(t, err)
+		if assert.NotNil(t, ride) {
+			assert.Equal(t, status, ride.Status)
+		}
+	}
+
+	ride, err = repo.UpdateStatus(ctx, ids[0], domain.RideStatusCancelled)
+	assert.Nil(t, ride)
+	assert.Equal(t, domain.RideTransitionError{From: domain.RideStatusCompleted, To: domain.RideStatusCancelled}, err)
+
+	ride, err = repo.UpdateStatus(ctx, ids[1]+1000, domain.RideStatusAccepted)
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+
+	_, err = repo.Delete(ctx, ids[1])
+	require.NoError(t, err)
+	ride, err = repo.UpdateStatus(ctx, ids[1], domain.RideStatusAccepted)
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+}
+
+func testDelete(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertRides(t, repo, 1)
+
+	deleted, err := repo.Delete(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.Delete(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	deleted, err = repo.Delete(ctx, ids[0]+1000)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	ride, err := repo.SelectByID(ctx, ids[0], true)
+	assert.NoError(t, err)
+	if assert.NotNil(t, ride) {
+		assert.NotNil(t, ride.DeletedAt)
+	}
+}
+
+func testRestore(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := insertRides(t, repo, 1)
+
+	_, err := repo.Delete(ctx, ids[0])
+	require.NoError(t, err)
+
+	ride, err := repo.Restore(ctx, ids[0])
+	assert.NoError(t, err)
+	if assert.NotNil(t, ride) {
+		assert.Nil(t, ride.DeletedAt)
+	}
+
+	// NOTE: Restoring a ride that isn't deleted returns it as is.
+	ride, err = repo.Restore(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.NotNil(t, ride)
+
+	ride, err = repo.Restore(ctx, ids[0]+1000)
+	assert.NoError(t, err)
+	assert.Nil(t, ride)
+}
@@ -0,0 +1,180 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// RiderFactory returns an empty rider repository, every test gets its own.
+type RiderFactory func(t *testing.T) domain.RiderRepository
+
+// RunRiders runs the rider suite against the repositories returned by the
+// factory.
+func RunRiders(t *testing.T, factory RiderFactory) {
+	t.Run("Insert", func(t *testing.T) { testInsertRider(t, factory) })
+	t.Run("SelectByName", func(t *testing.T) { testSelectRiderByName(t, factory) })
+	t.Run("Update", func(t *testing.T) { testUpdateRider(t, factory) })
+}
+
+// RiderRidesFactory returns an empty ride repository along with the rider
+// repository its rides reference, every test gets its own.
+type RiderRidesFactory func(t *testing.T) (domain.RideRepository, domain.RiderRepository)
+
+// RunRiderRides runs the suite of what rides linked to riders are read and
+// changed by against the repositories returned by the factory.
+func RunRiderRides(t *testing.T, factory RiderRidesFactory) {
+	t.Run("Stats", func(t *testing.T) { testStatsByRider(t, factory) })
+	t.Run("RenameRider", func(t *testing.T) { testRenameRider(t, factory) })
+}
+
+func newRider(name string) domain.Rider {
+	return domain.Rider{
+		Name:        name,
+		Email:       "john@example.com",
+		Phone:       "+6281234567890",
+		Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactEmail, Language: "id"},
+		CreatedAt:   baseTime(),
+		UpdatedAt:   baseTime(),
+	}
+}
+
+func testInsertRider(t *testing.T, factory RiderFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	first, err := repo.Insert(ctx, newRider("John Doe"))
+	require.NoError(t, err)
+	second, err := repo.Insert(ctx, domain.Rider{Name: "Jane Doe", CreatedAt: baseTime(), UpdatedAt: baseTime()})
+	require.NoError(t, err)
+	assert.Less(t, first, second)
+
+	rider, err := repo.SelectByID(ctx, first)
+	require.NoError(t, err)
+	require.NotNil(t, rider)
+	expected := newRider("John Doe")
+	expected.ID = first
+	assert.Equal(t, expected, *rider)
+
+	rider, err = repo.SelectByID(ctx, second)
+	require.NoError(t, err)
+	require.NotNil(t, rider)
+	assert.Empty(t, rider.Email)
+	assert.Equal(t, domain.RiderPreferences{}, rider.Preferences)
+
+	rider, err = repo.SelectByID(ctx, second+1)
+	assert.NoError(t, err)
+	assert.Nil(t, rider)
+}
+
+func testSelectRiderByName(t *testing.T, factory RiderFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	first, err := repo.Insert(ctx, newRider("John Doe"))
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newRider("John Doe"))
+	require.NoError(t, err)
+
+	rider, err := repo.SelectByName(ctx, "John Doe")
+	require.NoError(t, err)
+	require.NotNil(t, rider)
+	assert.Equal(t, first, rider.ID)
+
+	rider, err = repo.SelectByName(ctx, "john doe")
+	assert.NoError(t, err)
+	assert.Nil(t, rider)
+}
+
+func testUpdateRider(t *testing.T, factory RiderFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	id, err := repo.Insert(ctx, newRider("John Doe"))
+	require.NoError(t, err)
+
+	update := domain.Rider{ID: id, Name: "Johnny Doe", Phone: "+6281234567891", Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactPhone}}
+	rider, err := repo.Update(ctx, update)
+	require.NoError(t, err)
+	require.NotNil(t, rider)
+	assert.Equal(t, "Johnny Doe", rider.Name)
+	assert.Empty(t, rider.Email)
+	assert.Equal(t, "+6281234567891", rider.Phone)
+	assert.Equal(t, domain.RiderPreferences{ContactBy: domain.RiderContactPhone}, rider.Preferences)
+	assert.Equal(t, baseTime(), rider.CreatedAt)
+	assert.True(t, rider.UpdatedAt.After(baseTime()))
+
+	update.ID = id + 1
+	rider, err = repo.Update(ctx, update)
+	assert.NoError(t, err)
+	assert.Nil(t, rider)
+}
+
+// insertRiderRides inserts a ride made by newRide for each rider and returns
+// their IDs.
+func insertRiderRides(t *testing.T, repo domain.RideRepository, riders ...domain.Rider) []int64 {
+	ids := make([]int64, len(riders))
+	for i, rider := range riders {
+		ride := newRide(i)
+		ride.RiderName = rider.Name
+		ride.RiderID = &riders[i].ID
+		id, err := repo.Insert(context.Background(), ride)
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	return ids
+}
+
+func insertStoredRiders(t *testing.T, repo domain.RiderRepository, names ...string) []domain.Rider {
+	riders := make([]domain.Rider, len(names))
+	for i, name := range names {
+		rider := newRider(name)
+		id, err := repo.Insert(context.Background(), rider)
+		require.NoError(t, err)
+		rider.ID = id
+		riders[i] = rider
+	}
+	return riders
+}
+
+func testStatsByRider(t *testing.T, factory RiderRidesFactory) {
+	ctx := context.Background()
+	rides, riders := factory(t)
+	stored := insertStoredRiders(t, riders, "John Doe", "John Doe", "Jane Roe")
+	insertRiderRides(t, rides, stored[0], stored[1], stored[0], stored[2])
+
+	stats, err := rides.Stats(ctx, domain.StatsQuery{GroupBy: "driverName"})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(4), stats[0].Count)
+	assert.Equal(t, int64(3), stats[0].UniqueRiders, "riders sharing a name are counted apart")
+}
+
+func testRenameRider(t *testing.T, factory RiderRidesFactory) {
+	ctx := context.Background()
+	rides, riders := factory(t)
+	stored := insertStoredRiders(t, riders, "John Doe", "Jane Roe")
+	ids := insertRiderRides(t, rides, stored[0], stored[1], stored[0])
+	_, err := rides.Delete(ctx, ids[2])
+	require.NoError(t, err)
+	before := make([]*domain.Ride, len(ids))
+	for i, id := range ids {
+		before[i], err = rides.SelectByID(ctx, id, true)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, rides.RenameRider(ctx, stored[0].ID, "Johnny Doe"))
+
+	for i, expected := range []string{"Johnny Doe", "Jane Roe", "Johnny Doe"} {
+		ride, err := rides.SelectByID(ctx, ids[i], true)
+		require.NoError(t, err)
+		require.NotNil(t, ride)
+		assert.Equal(t, expected, ride.RiderName, "ride %d", i)
+		assert.True(t, before[i].UpdatedAt.Equal(ride.UpdatedAt), "ride %d", i)
+	}
+
+	page, _, err := rides.SelectAll(ctx, domain.Pagination{RiderName: "Johnny Doe"})
+	require.NoError(t, err)
+	assert.Equal(t, ids[:1], rideIDs(page))
+}
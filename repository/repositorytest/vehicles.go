@@ -0,0 +1,180 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// VehicleFactory returns an empty vehicle repository, every test gets its own.
+type VehicleFactory func(t *testing.T) domain.VehicleRepository
+
+// RunVehicles runs the vehicle suite against the repositories returned by the
+// factory.
+func RunVehicles(t *testing.T, factory VehicleFactory) {
+	t.Run("Insert", func(t *testing.T) { testInsertVehicle(t, factory) })
+	t.Run("SelectAll", func(t *testing.T) { testSelectAllVehicles(t, factory) })
+	t.Run("Update", func(t *testing.T) { testUpdateVehicle(t, factory) })
+	t.Run("DuplicatePlateNumber", func(t *testing.T) { testDuplicatePlateNumber(t, factory) })
+	t.Run("Deactivate", func(t *testing.T) { testDeactivateVehicle(t, factory) })
+}
+
+func newVehicle(plateNumber string) domain.Vehicle {
+	return domain.Vehicle{
+		PlateNumber: plateNumber,
+		Country:     "ID",
+		Make:        "Toyota",
+		Model:       "Avanza",
+		Capacity:    6,
+		Type:        domain.VehicleTypeCar,
+		CreatedAt:   baseTime(),
+		UpdatedAt:   baseTime(),
+	}
+}
+
+func testInsertVehicle(t *testing.T, factory VehicleFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	first, err := repo.Insert(ctx, newVehicle("B 1234 XYZ"))
+	require.NoError(t, err)
+	second, err := repo.Insert(ctx, newVehicle("B 5678 XYZ"))
+	require.NoError(t, err)
+	assert.Less(t, first, second)
+
+	vehicle, err := repo.SelectByID(ctx, first)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+	expected := newVehicle("B 1234 XYZ")
+	expected.ID = first
+	assert.Equal(t, expected, *vehicle)
+
+	vehicle, err = repo.SelectByID(ctx, second+1)
+	assert.NoError(t, err)
+	assert.Nil(t, vehicle)
+}
+
+func testSelectAllVehicles(t *testing.T, factory VehicleFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	ids := make([]int64, 3)
+	for i, plateNumber := range []string{"B 1 A", "B 2 A", "B 3 A"} {
+		id, err := repo.Insert(ctx, newVehicle(plateNumber))
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	_, err := repo.Deactivate(ctx, ids[1])
+	require.NoError(t, err)
+
+	// NOTE: Deactivated vehicles are listed along with the others.
+	vehicles, next, err := repo.SelectAll(ctx, domain.VehiclePagination{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, ids[:2], vehicleIDs(vehicles))
+	assert.NotNil(t, vehicles[1].DeactivatedAt)
+	require.NotEmpty(t, next)
+
+	vehicles, next, err = repo.SelectAll(ctx, domain.VehiclePagination{After: next, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, ids[2:], vehicleIDs(vehicles))
+	assert.Empty(t, next)
+}
+
+func testUpdateVehicle(t *testing.T, factory VehicleFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	id, err := repo.Insert(ctx, newVehicle("B 1234 XYZ"))
+	require.NoError(t, err)
+
+	update := domain.Vehicle{ID: id, PlateNumber: "SBA 1234 A", Country: "SG", Make: "Honda", Model: "Vario", Capacity: 1, Type: domain.VehicleTypeMotorcycle}
+	vehicle, err := repo.Update(ctx, update)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+	assert.Equal(t, "SBA 1234 A", vehicle.PlateNumber)
+	assert.Equal(t, "SG", vehicle.Country)
+	assert.Equal(t, "Honda", vehicle.Make)
+	assert.Equal(t, "Vario", vehicle.Model)
+	assert.Equal(t, 1, vehicle.Capacity)
+	assert.Equal(t, domain.VehicleTypeMotorcycle, vehicle.Type)
+	assert.Equal(t, baseTime(), vehicle.CreatedAt)
+	assert.True(t, vehicle.UpdatedAt.After(baseTime()))
+
+	update.ID = id + 1
+	vehicle, err = repo.Update(ctx, update)
+	assert.NoError(t, err)
+	assert.Nil(t, vehicle)
+}
+
+func testDuplicatePlateNumber(t *testing.T, factory VehicleFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	id, err := repo.Insert(ctx, newVehicle("B 1234 XYZ"))
+	require.NoError(t, err)
+	_, err = repo.Deactivate(ctx, id)
+	require.NoError(t, err)
+
+	// NOTE: Plate numbers of deactivated vehicles are still taken.
+	_, err = repo.Insert(ctx, newVehicle("B 1234 XYZ"))
+	assert.Equal(t, domain.ErrDuplicatePlateNumber, err)
+
+	other := newVehicle("B 1234 XYZ")
+	other.Country = "MY"
+	otherID, err := repo.Insert(ctx, other)
+	require.NoError(t, err)
+
+	// NOTE: Saving a vehicle with its own plate number isn't a duplicate.
+	other.ID = otherID
+	other.Model = "Innova"
+	vehicle, err := repo.Update(ctx, other)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+
+	other.Country = "ID"
+	vehicle, err = repo.Update(ctx, other)
+	assert.Equal(t, domain.ErrDuplicatePlateNumber, err)
+	assert.Nil(t, vehicle)
+
+	vehicle, err = repo.SelectByID(ctx, otherID)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+	assert.Equal(t, "MY", vehicle.Country)
+}
+
+func testDeactivateVehicle(t *testing.T, factory VehicleFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+	id, err := repo.Insert(ctx, newVehicle("B 1234 XYZ"))
+	require.NoError(t, err)
+
+	vehicle, err := repo.Deactivate(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+	require.NotNil(t, vehicle.DeactivatedAt)
+	deactivatedAt := *vehicle.DeactivatedAt
+
+	// NOTE: Deactivating again keeps the time it was first deactivated at.
+	vehicle, err = repo.Deactivate(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+	require.NotNil(t, vehicle.DeactivatedAt)
+	assert.True(t, deactivatedAt.Equal(*vehicle.DeactivatedAt))
+
+	vehicle, err = repo.SelectByID(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, vehicle)
+	assert.NotNil(t, vehicle.DeactivatedAt)
+
+	vehicle, err = repo.Deactivate(ctx, id+1)
+	assert.NoError(t, err)
+	assert.Nil(t, vehicle)
+}
+
+func vehicleIDs(vehicles []domain.Vehicle) []int64 {
+	ids := make([]int64, len(vehicles))
+	for i, vehicle := range vehicles {
+		ids[i] = vehicle.ID
+	}
+	return ids
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type riderRepository struct {
+	db      *sql.DB
+	dialect dialect
+	sql     sq.StatementBuilderType
+}
+
+// NewRiderRepository stores riders in SQLite.
+func NewRiderRepository(db *sql.DB) domain.RiderRepository {
+	return newRiderRepository(db, sqliteDialect())
+}
+
+// newRiderRepository expects the schema to be migrated by the Migrator of the
+// same dialect.
+func newRiderRepository(db *sql.DB, d dialect) riderRepository {
+	return riderRepository{
+		db:      db,
+		dialect: d,
+		sql:     sq.StatementBuilder.PlaceholderFormat(d.placeholder),
+	}
+}
+
+func riderColumns() []string {
+	return []string{"id", "name", "email", "phone", "contactBy", "language", "createdAt", "updatedAt"}
+}
+
+func (r riderRepository) Insert(ctx context.Context, rider domain.Rider) (int64, error) {
+	builder := r.sql.Insert("riders").
+		Columns("name", "email", "phone", "contactBy", "language", "createdAt", "updatedAt").
+		Values(
+			rider.Name,
+			rider.Email,
+			rider.Phone,
+			rider.Preferences.ContactBy,
+			rider.Preferences.Language,
+			rider.CreatedAt,
+			rider.UpdatedAt,
+		).
+		RunWith(r.db)
+
+	if r.dialect.returningID {
+		var id int64
+		if err := builder.Suffix("RETURNING id").QueryRowContext(ctx).Scan(&id); err != nil {
+			return -1, err
+		}
+		return id, nil
+	}
+	result, err := builder.ExecContext(ctx)
+	if err != nil {
+		return -1, err
+	}
+	return result.LastInsertId()
+}
+
+func (r riderRepository) SelectByID(ctx context.Context, id int64) (*domain.Rider, error) {
+	row := r.sql.Select(riderColumns()...).
+		From("riders").
+		Where(sq.Eq{"id": id}).
+		RunWith(r.db).
+		QueryRowContext(ctx)
+	rider, err := scanRider(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rider, nil
+}
+
+func (r riderRepository) SelectByName(ctx context.Context, name string) (*domain.Rider, error) {
+	row := r.sql.Select(riderColumns()...).
+		From("riders").
+		Where(sq.Eq{"name": name}).
+		OrderBy("id").
+		Limit(1).
+		RunWith(r.db).
+		QueryRowContext(ctx)
+	rider, err := scanRider(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rider, nil
+}
+
+// Update replaces the details of the rider, RideRepository.RenameRider carries
+// a new name over to its rides.
+func (r riderRepository) Update(ctx context.Context, rider domain.Rider) (*domain.Rider, error) {
+	result, err := r.sql.Update("riders").
+		Set("name", rider.Name).
+		Set("email", rider.Email).
+		Set("phone", rider.Phone).
+		Set("contactBy", rider.Preferences.ContactBy).
+		Set("language", rider.Preferences.Language).
+		Set("updatedAt", time.Now().UTC()).
+		Where(sq.Eq{"id": rider.ID}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return nil, err
+	}
+	return r.SelectByID(ctx, rider.ID)
+}
+
+func scanRider(s rowScanner) (domain.Rider, error) {
+	var rider domain.Rider
+	err := s.Scan(
+		&rider.ID,
+		&rider.Name,
+		&rider.Email,
+		&rider.Phone,
+		&rider.Preferences.ContactBy,
+		&rider.Preferences.Language,
+		&rider.CreatedAt,
+		&rider.UpdatedAt,
+	)
+	return rider, err
+}
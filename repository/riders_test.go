@@ -0,0 +1,192 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+func newRiderRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "email", "phone", "contactBy", "language", "createdAt", "updatedAt"})
+}
+
+func TestRiderRepository_Insert(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		returnErr    error
+		lastInsertID int64
+		expectedErr  string
+	}{
+		{
+			testName:     "When exec returns error, return the error",
+			returnErr:    errors.New("Exec error"),
+			lastInsertID: -1,
+			expectedErr:  "Exec error",
+		},
+		{
+			testName:     "When successful, return the result",
+			lastInsertID: 123,
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, func(mock sqlmock.Sqlmock) {
+					query := "INSERT INTO riders (name,email,phone,contactBy,language,createdAt,updatedAt) VALUES (?,?,?,?,?,?,?)"
+					args := []driver.Value{"John Doe", "john@example.com", "", "email", "en", testTime(), testTime()}
+					switch {
+					case b.returningID && tc.returnErr != nil:
+						mock.ExpectQuery(query + " RETURNING id").WithArgs(args...).WillReturnError(tc.returnErr)
+					case b.returningID:
+						mock.ExpectQuery(query + " RETURNING id").WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tc.lastInsertID))
+					case tc.returnErr != nil:
+						mock.ExpectExec(query).WithArgs(args...).WillReturnError(tc.returnErr)
+					default:
+						mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(tc.lastInsertID, 1))
+					}
+				})
+				defer db.Close()
+
+				id, err := b.newRiderRepo(db).Insert(context.Background(), domain.Rider{
+					Name:        "John Doe",
+					Email:       "john@example.com",
+					Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactEmail, Language: "en"},
+					CreatedAt:   testTime(),
+					UpdatedAt:   testTime(),
+				})
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tc.lastInsertID, id)
+			})
+		}
+	}
+}
+
+func TestRiderRepository_SelectByID(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		rider        *domain.Rider
+		expectedErr  string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, email, phone, contactBy, language, createdAt, updatedAt FROM riders WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnError(errors.New("Query error"))
+			},
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When rider doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, email, phone, contactBy, language, createdAt, updatedAt FROM riders WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newRiderRows())
+			},
+		},
+		{
+			testName: "When successful, return rider",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, email, phone, contactBy, language, createdAt, updatedAt FROM riders WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newRiderRows().AddRow(123, "John Doe", "", "+6281234567890", "phone", "", testTime(), testTime()))
+			},
+			rider: &domain.Rider{
+				ID:          123,
+				Name:        "John Doe",
+				Phone:       "+6281234567890",
+				Preferences: domain.RiderPreferences{ContactBy: domain.RiderContactPhone},
+				CreatedAt:   testTime(),
+				UpdatedAt:   testTime(),
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				rider, err := b.newRiderRepo(db).SelectByID(context.Background(), 123)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.rider, rider)
+				}
+			})
+		}
+	}
+}
+
+func TestRiderRepository_SelectByName(t *testing.T) {
+	query := "SELECT id, name, email, phone, contactBy, language, createdAt, updatedAt FROM riders WHERE name = ? ORDER BY id LIMIT 1"
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		rider        *domain.Rider
+		expectedErr  string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("John Doe").
+					WillReturnError(errors.New("Query error"))
+			},
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When rider doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("John Doe").
+					WillReturnRows(newRiderRows())
+			},
+		},
+		{
+			testName: "When successful, return rider",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("John Doe").
+					WillReturnRows(newRiderRows().AddRow(123, "John Doe", "", "", "", "", testTime(), testTime()))
+			},
+			rider: &domain.Rider{
+				ID:        123,
+				Name:      "John Doe",
+				CreatedAt: testTime(),
+				UpdatedAt: testTime(),
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				rider, err := b.newRiderRepo(db).SelectByName(context.Background(), "John Doe")
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.rider, rider)
+				}
+			})
+		}
+	}
+}
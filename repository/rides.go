@@ -1,126 +1,751 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	domain "github.com/hawarir/backend-coding-test"
+	"github.com/hawarir/backend-coding-test/geo"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/mattn/go-sqlite3"
 )
 
-type rideRepository struct {
-	db              *sql.DB
-	tableColumns    []string
-	tableDefinition []string
-}
+// maxGeohashCells caps how many geohash ranges a spatial query matches against.
+const maxGeohashCells = 16
+
+// maxNearbyCandidates caps how many rides a nearby search without a limit reads.
+const maxNearbyCandidates = 10000
+
+// maxFacetValues caps how many of the most common values are counted for each
+// faceted column.
+const maxFacetValues = 10
+
+type (
+	rideRepository struct {
+		db           *sql.DB
+		dialect      dialect
+		sql          sq.StatementBuilderType
+		tableColumns []string
+	}
+
+	// dialect holds what differs between the databases rides can be stored in.
+	dialect struct {
+		// name is the directory the migrations of the database are in.
+		name        string
+		placeholder sq.PlaceholderFormat
+		// migrationsTable creates the table keeping track of applied migrations.
+		migrationsTable string
+		// columnsQuery returns the name of every column of the rides table.
+		columnsQuery string
+		// returningID is set when inserted IDs must be read from a RETURNING
+		// clause as the driver doesn't support LastInsertId.
+		returningID bool
+		// searchMatches selects the searchID and searchRank of the rides
+		// matching any of the terms, lower ranks match better.
+		searchMatches func(terms []string) sq.SelectBuilder
+		// setUpSearch, when set, prepares the database for searchMatches once
+		// it's migrated. searchAvailable tells whether it could.
+		setUpSearch     func(context.Context, *sql.DB) error
+		searchAvailable func(context.Context, *sql.DB) (bool, error)
+		// dayKey and weekKey format createdAt as the UTC date of its day and
+		// of the Monday of its week.
+		dayKey  string
+		weekKey string
+		// uniqueViolation tells whether err is returned for a row breaking a
+		// unique index.
+		uniqueViolation func(err error) bool
+	}
+
+	rowScanner interface {
+		Scan(dest ...interface{}) error
+	}
+
+	// rankCursor is the position of a ride in results ranked by a number, lower
+	// first. Nearby rides are ranked by their distance.
+	rankCursor struct {
+		rank float64
+		id   int64
+	}
+
+	// nameFilter is the exact and prefix filter of a name column, either can be
+	// empty.
+	nameFilter struct {
+		column string
+		value  func(domain.Ride) string
+		exact  string
+		prefix string
+	}
+)
 
+// NewRideRepository stores rides in SQLite.
 func NewRideRepository(db *sql.DB) domain.RideRepository {
-	tableSchema := [][2]string{
-		{"id", "INTEGER PRIMARY KEY AUTOINCREMENT"},
-		{"startLat", "REAL NOT NULL"},
-		{"startLong", "REAL NOT NULL"},
-		{"endLat", "REAL NOT NULL"},
-		{"endLong", "REAL NOT NULL"},
-		{"riderName", "TEXT NOT NULL"},
-		{"driverName", "TEXT NOT NULL"},
-		{"driverVehicle", "TEXT NOT NULL"},
+	return newRideRepository(db, sqliteDialect())
+}
+
+func sqliteDialect() dialect {
+	return dialect{
+		name:            "sqlite",
+		placeholder:     sq.Question,
+		migrationsTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, checksum TEXT NOT NULL, appliedAt DATETIME NOT NULL)",
+		columnsQuery:    "SELECT name FROM pragma_table_info('rides')",
+		searchMatches:   sqliteSearchMatches,
+		setUpSearch:     setUpSQLiteSearch,
+		searchAvailable: sqliteSearchAvailable,
+		dayKey:          "date(createdAt)",
+		weekKey:         "date(createdAt, '-6 days', 'weekday 1')",
+		uniqueViolation: sqliteUniqueViolation,
 	}
+}
 
-	tableColumns := make([]string, len(tableSchema))
-	tableDefinition := make([]string, len(tableSchema))
+func sqliteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
 
-	for i, tuple := range tableSchema {
-		tableColumns[i] = tuple[0]
-		tableDefinition[i] = fmt.Sprintf("%s %s", tuple[0], tuple[1])
+// newRideRepository expects the schema to be migrated by the Migrator of the
+// same dialect.
+func newRideRepository(db *sql.DB, d dialect) rideRepository {
+	return rideRepository{
+		db:           db,
+		dialect:      d,
+		sql:          sq.StatementBuilder.PlaceholderFormat(d.placeholder),
+		tableColumns: rideColumns(),
 	}
-	return rideRepository{db: db, tableColumns: tableColumns, tableDefinition: tableDefinition}
 }
 
-// NOTE: This shouldn't be needed in production environment
-func (r rideRepository) InitTable() error {
-	_, err := r.db.Exec("CREATE TABLE IF NOT EXISTS rides (" + strings.Join(r.tableDefinition, ",") + ")")
-	return err
+// rideColumns lists the columns rides are read from and exported with.
+func rideColumns() []string {
+	return []string{
+		"id",
+		"startLat",
+		"startLong",
+		"endLat",
+		"endLong",
+		"riderName",
+		"driverName",
+		"driverVehicle",
+		"distance",
+		"bearing",
+		"status",
+		"startedAt",
+		"endedAt",
+		"createdAt",
+		"updatedAt",
+		"deletedAt",
+		"driverId",
+		"riderId",
+		"vehicleId",
+	}
 }
 
-func (r rideRepository) Insert(ride domain.Ride) (int64, error) {
-	result, err := sq.Insert("rides").
-		Columns(r.tableColumns[1:]...).
-		Values(
-			ride.StartLatitude,
-			ride.StartLongitude,
-			ride.EndLatitude,
-			ride.EndLongitude,
-			ride.RiderName,
-			ride.DriverName,
-			ride.DriverVehicle,
-		).
-		RunWith(r.db).
-		Exec()
+func (r rideRepository) Insert(ctx context.Context, ride domain.Ride) (int64, error) {
+	return r.insertRide(ctx, r.db, ride)
+}
 
+// InsertBatch inserts the rides in a single transaction, so either all of them
+// are stored or none are. The IDs are returned in the same order as the rides.
+func (r rideRepository) InsertBatch(ctx context.Context, rides []domain.Ride) ([]int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
-	return result.LastInsertId()
+	ids := make([]int64, len(rides))
+	for i, ride := range rides {
+		if ids[i], err = r.insertRide(ctx, tx, ride); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
-func (r rideRepository) SelectAll(page domain.Pagination) ([]domain.Ride, string, error) {
-	builder := sq.Select(r.tableColumns...).From("rides").OrderBy("id desc").RunWith(r.db)
+// SelectAll returns rides sorted by the page sort fields, latest first by
+// default. Paging back reads the rides before the Before cursor in the opposite
+// order, so the limit applies to the ones nearest to it, and reverses them. The
+// total and facets are counted by separate queries only when included.
+func (r rideRepository) SelectAll(ctx context.Context, page domain.Pagination) ([]domain.Ride, domain.PageInfo, error) {
+	fields, err := page.SortFields()
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	builder := filterRides(r.sql.Select(r.tableColumns...).From("rides"), page).RunWith(r.db)
 
-	if page.Cursor != "" {
-		cursor, err := strconv.ParseInt(page.Cursor, 10, 64)
+	switch {
+	case page.Before != "":
+		key, err := parsePosition(page.Before, fields)
 		if err != nil {
-			return nil, "", err
+			return nil, domain.PageInfo{}, err
+		}
+		builder = builder.Where(keysetPredicate(fields, key, true)).OrderBy(orderBy(fields, true)...)
+	case page.After != "":
+		key, err := parsePosition(page.After, fields)
+		if err != nil {
+			return nil, domain.PageInfo{}, err
 		}
-		builder = builder.Where(sq.LtOrEq{"id": cursor})
+		builder = builder.Where(keysetPredicate(fields, key, false)).OrderBy(orderBy(fields, false)...)
+	default:
+		builder = builder.OrderBy(orderBy(fields, false)...)
 	}
 
 	if page.Limit > 0 {
-		// NOTE: This is so that we can get the next cursor from the last element,
-		// make sure to not return it.
+		// NOTE: This is so that we know whether there is another page, make sure
+		// to not return the extra element.
 		limit := page.Limit + 1
 		builder = builder.Limit(limit)
 	}
 
-	rows, err := builder.Query()
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	rides := make([]domain.Ride, 0)
+	for rows.Next() {
+		ride, err := scanRide(rows)
+		if err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		rides = append(rides, ride)
+	}
+
+	more := page.Limit > 0 && uint64(len(rides)) > page.Limit
+	if more {
+		rides = rides[:page.Limit]
+	}
+	if page.Before != "" {
+		for i, j := 0, len(rides)-1; i < j; i, j = i+1, j-1 {
+			rides[i], rides[j] = rides[j], rides[i]
+		}
+	}
+
+	info := domain.PageInfo{PageCursors: pageCursors(rides, fields, page, more)}
+	if page.Includes("total") {
+		var total int64
+		err := filterRides(r.sql.Select("COUNT(*)").From("rides"), page).
+			RunWith(r.db).
+			QueryRowContext(ctx).
+			Scan(&total)
+		if err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		info.Total = &total
+	}
+	if page.Includes("facets") {
+		if info.Facets, err = r.countFacets(ctx, page); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+	}
+	return rides, info, nil
+}
+
+// countFacets counts the most common values of every faceted column among the
+// rides matching the filters of the page.
+func (r rideRepository) countFacets(ctx context.Context, page domain.Pagination) (map[string][]domain.FacetCount, error) {
+	facets := map[string][]domain.FacetCount{}
+	for _, column := range facetColumns() {
+		rows, err := filterRides(r.sql.Select(column, "COUNT(*) AS facetCount").From("rides"), page).
+			GroupBy(column).
+			OrderBy("facetCount desc", column+" asc").
+			Limit(maxFacetValues).
+			RunWith(r.db).
+			QueryContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		counts := make([]domain.FacetCount, 0)
+		for rows.Next() {
+			var count domain.FacetCount
+			if err := rows.Scan(&count.Value, &count.Count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts = append(counts, count)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		facets[column] = counts
+	}
+	return facets, nil
+}
+
+// SelectNearby prefilters rides in SQL by the geohash cells and the bounding box
+// covering the search circle, then orders them by the squared chord between
+// their endpoint and the center, which grows with the distance but needs no
+// trigonometry. The cursor is the squared chord and ID of the last ride on the
+// page, so every page reads at most limit rides. Without a limit it returns
+// ErrTooManyNearbyRides instead of reading more than maxNearbyCandidates.
+func (r rideRepository) SelectNearby(ctx context.Context, query domain.NearbyQuery) ([]domain.Ride, string, error) {
+	latColumn, longColumn, geohashColumn, vectorColumn := "startLat", "startLong", "startGeohash", "start"
+	if query.Endpoint == domain.RideEndpointEnd {
+		latColumn, longColumn, geohashColumn, vectorColumn = "endLat", "endLong", "endGeohash", "end"
+	}
+
+	center := geo.NewVector(query.Center())
+	rank := fmt.Sprintf("(%[1]sX - ?)*(%[1]sX - ?) + (%[1]sY - ?)*(%[1]sY - ?) + (%[1]sZ - ?)*(%[1]sZ - ?)", vectorColumn)
+	rankArgs := []interface{}{center.X, center.X, center.Y, center.Y, center.Z, center.Z}
+
+	box := geo.NewBoundingBox(query.Center(), query.Radius)
+	builder := filterRides(r.sql.Select(r.tableColumns...).Column(rank+" AS nearbyRank", rankArgs...).From("rides"), query.Pagination)
+
+	if prefixes := geo.GeohashPrefixes(box, maxGeohashCells); prefixes != nil {
+		cells := sq.Or{}
+		for _, prefix := range prefixes {
+			// NOTE: Geohashes only use characters lower than "{", so this is
+			// the same as a prefix match but can always use the index.
+			cells = append(cells, sq.And{sq.GtOrEq{geohashColumn: prefix}, sq.Lt{geohashColumn: prefix + "{"}})
+		}
+		builder = builder.Where(cells)
+	}
+
+	var longitude sq.Sqlizer = sq.And{sq.GtOrEq{longColumn: box.MinLongitude}, sq.LtOrEq{longColumn: box.MaxLongitude}}
+	if box.CrossesAntimeridian() {
+		longitude = sq.Or{sq.GtOrEq{longColumn: box.MinLongitude}, sq.LtOrEq{longColumn: box.MaxLongitude}}
+	}
+	builder = builder.
+		Where(sq.And{sq.GtOrEq{latColumn: box.MinLatitude}, sq.LtOrEq{latColumn: box.MaxLatitude}}).
+		Where(longitude).
+		Where(sq.Expr(rank+" <= ?", append(rankArgs, geo.SquaredChordOf(query.Radius))...)).
+		OrderBy("nearbyRank asc", "id asc").
+		RunWith(r.db)
+
+	if query.After != "" {
+		cursor, err := parseRankCursor(query.After)
+		if err != nil {
+			return nil, "", err
+		}
+		builder = builder.Where(sq.Or{
+			sq.Expr(rank+" > ?", append(rankArgs, cursor.rank)...),
+			sq.And{sq.Expr(rank+" = ?", append(rankArgs, cursor.rank)...), sq.Gt{"id": cursor.id}},
+		})
+	}
+
+	limit := query.Limit
+	if limit == 0 || limit > maxNearbyCandidates {
+		limit = maxNearbyCandidates
+	}
+	// NOTE: This is so that we know whether there is another page, make sure
+	// to not return the extra element.
+	builder = builder.Limit(limit + 1)
+
+	rows, err := builder.QueryContext(ctx)
 	if err != nil {
 		return nil, "", err
 	}
 	defer rows.Close()
 
 	rides := make([]domain.Ride, 0)
+	ranks := map[int64]float64{}
+	more := false
 	for rows.Next() {
-		var ride domain.Ride
-		if err := rows.Scan(
-			&ride.ID,
-			&ride.StartLatitude,
-			&ride.StartLongitude,
-			&ride.EndLatitude,
-			&ride.EndLongitude,
-			&ride.RiderName,
-			&ride.DriverName,
-			&ride.DriverVehicle,
-		); err != nil {
+		var rank float64
+		ride, err := scanRide(rankedRows{Rows: rows, rank: &rank})
+		if err != nil {
 			return nil, "", err
 		}
+		// NOTE: Rides are ordered by distance, so once one is out of the
+		// radius past rounding so are the ones after it.
+		if geo.Distance(query.Center(), ride.Point(query.Endpoint)) > query.Radius {
+			break
+		}
+		if uint64(len(rides)) == limit {
+			more = true
+			break
+		}
+		ranks[ride.ID] = rank
 		rides = append(rides, ride)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
 
-	if page.Limit == 0 || uint64(len(rides)) <= page.Limit {
+	if !more {
 		return rides, "", nil
 	}
+	if query.Limit == 0 || query.Limit > maxNearbyCandidates {
+		return nil, "", domain.ErrTooManyNearbyRides
+	}
+	last := rides[len(rides)-1]
+	next := rankCursor{rank: ranks[last.ID], id: last.ID}
+	return rides, next.String(), nil
+}
+
+// Export writes every ride matching the page filters as CSV with the table
+// columns as header, sorted the same way as the page. Rows are written as they
+// are read from the database, so the result is never held in memory. The cursor
+// and limit of the page are ignored.
+func (r rideRepository) Export(ctx context.Context, page domain.Pagination, w io.Writer) error {
+	fields, err := page.SortFields()
+	if err != nil {
+		return err
+	}
+	rows, err := filterRides(r.sql.Select(r.tableColumns...).From("rides"), page).
+		OrderBy(orderBy(fields, false)...).
+		RunWith(r.db).
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(r.tableColumns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		ride, err := scanRide(rows)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(rideRecord(ride)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r rideRepository) SelectByID(ctx context.Context, id int64, includeDeleted bool) (*domain.Ride, error) {
+	builder := r.sql.Select(r.tableColumns...).From("rides").Where(sq.Eq{"id": id}).RunWith(r.db)
+	if !includeDeleted {
+		builder = builder.Where(sq.Eq{"deletedAt": nil})
+	}
+	ride, err := scanRide(builder.QueryRowContext(ctx))
+	if err != nil && err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &ride, err
+}
+
+// Update overwrites every ride attribute except its status, which can only be
+// changed through UpdateStatus.
+func (r rideRepository) Update(ctx context.Context, ride domain.Ride) (*domain.Ride, error) {
+	start, end := vector(ride, domain.RideEndpointStart), vector(ride, domain.RideEndpointEnd)
+	result, err := r.sql.Update("rides").
+		Set("startLat", ride.StartLatitude).
+		Set("startLong", ride.StartLongitude).
+		Set("endLat", ride.EndLatitude).
+		Set("endLong", ride.EndLongitude).
+		Set("riderName", ride.RiderName).
+		Set("driverName", ride.DriverName).
+		Set("driverVehicle", ride.DriverVehicle).
+		Set("distance", ride.Distance()).
+		Set("bearing", ride.Bearing()).
+		Set("startedAt", ride.StartedAt).
+		Set("endedAt", ride.EndedAt).
+		Set("updatedAt", time.Now().UTC()).
+		Set("startGeohash", geohash(ride, domain.RideEndpointStart)).
+		Set("endGeohash", geohash(ride, domain.RideEndpointEnd)).
+		Set("driverId", ride.DriverID).
+		Set("riderId", ride.RiderID).
+		Set("vehicleId", ride.VehicleID).
+		Set("startX", start.X).
+		Set("startY", start.Y).
+		Set("startZ", start.Z).
+		Set("endX", end.X).
+		Set("endY", end.Y).
+		Set("endZ", end.Z).
+		Where(sq.Eq{"id": ride.ID}).
+		Where(sq.Eq{"deletedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return nil, err
+	}
+	return r.SelectByID(ctx, ride.ID, false)
+}
+
+// UpdateStatus moves a ride to the given status in a single statement that only
+// matches rows whose current status is allowed to transition into it, so two
+// concurrent transitions can't both succeed.
+func (r rideRepository) UpdateStatus(ctx context.Context, id int64, status domain.RideStatus) (*domain.Ride, error) {
+	result, err := r.sql.Update("rides").
+		Set("status", status).
+		Set("updatedAt", time.Now().UTC()).
+		Where(sq.Eq{"id": id, "status": status.Predecessors()}).
+		Where(sq.Eq{"deletedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	ride, err := r.SelectByID(ctx, id, false)
+	if err != nil || ride == nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, domain.RideTransitionError{From: ride.Status, To: status}
+	}
+	return ride, nil
+}
+
+// Delete only marks the ride as deleted, it returns false when there is no
+// ride left to delete.
+func (r rideRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	now := time.Now().UTC()
+	result, err := r.sql.Update("rides").
+		Set("deletedAt", now).
+		Set("updatedAt", now).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"deletedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r rideRepository) Restore(ctx context.Context, id int64) (*domain.Ride, error) {
+	_, err := r.sql.Update("rides").
+		Set("deletedAt", nil).
+		Set("updatedAt", time.Now().UTC()).
+		Where(sq.Eq{"id": id}).
+		Where(sq.NotEq{"deletedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.SelectByID(ctx, id, false)
+}
+
+// RenameRider leaves updatedAt as it is, the rides themselves weren't edited.
+func (r rideRepository) RenameRider(ctx context.Context, riderID int64, name string) error {
+	_, err := r.sql.Update("rides").
+		Set("riderName", name).
+		Where(sq.Eq{"riderId": riderID}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	return err
+}
+
+// insertRide inserts the ride through the given runner, so it can also be part
+// of a transaction, and returns its ID.
+func (r rideRepository) insertRide(ctx context.Context, runner sq.BaseRunner, ride domain.Ride) (int64, error) {
+	start, end := vector(ride, domain.RideEndpointStart), vector(ride, domain.RideEndpointEnd)
+	builder := r.sql.Insert("rides").
+		Columns(
+			"startLat",
+			"startLong",
+			"endLat",
+			"endLong",
+			"riderName",
+			"driverName",
+			"driverVehicle",
+			"distance",
+			"bearing",
+			"status",
+			"startedAt",
+			"endedAt",
+			"createdAt",
+			"updatedAt",
+			"startGeohash",
+			"endGeohash",
+			"driverId",
+			"riderId",
+			"vehicleId",
+			"startX",
+			"startY",
+			"startZ",
+			"endX",
+			"endY",
+			"endZ",
+		).
+		Values(
+			ride.StartLatitude,
+			ride.StartLongitude,
+			ride.EndLatitude,
+			ride.EndLongitude,
+			ride.RiderName,
+			ride.DriverName,
+			ride.DriverVehicle,
+			ride.Distance(),
+			ride.Bearing(),
+			ride.Status,
+			ride.StartedAt,
+			ride.EndedAt,
+			ride.CreatedAt,
+			ride.UpdatedAt,
+			geohash(ride, domain.RideEndpointStart),
+			geohash(ride, domain.RideEndpointEnd),
+			ride.DriverID,
+			ride.RiderID,
+			ride.VehicleID,
+			start.X,
+			start.Y,
+			start.Z,
+			end.X,
+			end.Y,
+			end.Z,
+		).
+		RunWith(runner)
+
+	if r.dialect.returningID {
+		var id int64
+		if err := builder.Suffix("RETURNING id").QueryRowContext(ctx).Scan(&id); err != nil {
+			return -1, err
+		}
+		return id, nil
+	}
+	result, err := builder.ExecContext(ctx)
+	if err != nil {
+		return -1, err
+	}
+	return result.LastInsertId()
+}
+
+// pageCursors points at the pages around the rides, which are sorted by the
+// fields. more tells whether there are rides past the limit in the direction
+// the page was read, the page it was read from is always assumed to be there.
+func pageCursors(rides []domain.Ride, fields []domain.SortField, page domain.Pagination, more bool) domain.PageCursors {
+	cursors := domain.PageCursors{}
+	if len(rides) == 0 {
+		return cursors
+	}
+	first := formatPosition(rides[0], fields)
+	last := formatPosition(rides[len(rides)-1], fields)
+	if page.Before != "" {
+		cursors.Next = last
+		if more {
+			cursors.Prev = first
+		}
+		return cursors
+	}
+	if more {
+		cursors.Next = last
+	}
+	if page.After != "" {
+		cursors.Prev = first
+	}
+	return cursors
+}
+
+// facetColumns lists the columns whose most common values are counted.
+func facetColumns() []string {
+	return []string{"driverName", "driverVehicle"}
+}
+
+func geohash(ride domain.Ride, endpoint domain.RideEndpoint) string {
+	return geo.EncodeGeohash(ride.Point(endpoint), geo.GeohashPrecision)
+}
+
+// vector is where the endpoint of the ride lies on the unit sphere, nearby
+// rides are ordered by the chord to it.
+func vector(ride domain.Ride, endpoint domain.RideEndpoint) geo.Vector {
+	return geo.NewVector(ride.Point(endpoint))
+}
+
+func filterRides(builder sq.SelectBuilder, page domain.Pagination) sq.SelectBuilder {
+	if !page.IncludeDeleted {
+		builder = builder.Where(sq.Eq{"deletedAt": nil})
+	}
+	if !page.From.IsZero() {
+		builder = builder.Where(sq.GtOrEq{"createdAt": page.From.UTC()})
+	}
+	if !page.To.IsZero() {
+		builder = builder.Where(sq.Lt{"createdAt": page.To.UTC()})
+	}
+	if page.MinDistance > 0 {
+		builder = builder.Where(sq.GtOrEq{"distance": page.MinDistance})
+	}
+	if page.MaxDistance > 0 {
+		builder = builder.Where(sq.LtOrEq{"distance": page.MaxDistance})
+	}
+	if page.RiderID != 0 {
+		builder = builder.Where(sq.Eq{"riderId": page.RiderID})
+	}
+	for _, filter := range nameFilters(page) {
+		if filter.exact != "" {
+			builder = builder.Where(sq.Eq{filter.column: filter.exact})
+		}
+		if filter.prefix != "" {
+			// NOTE: Names are compared byte by byte, so this is the same as a
+			// prefix match but can always use the index.
+			builder = builder.Where(sq.GtOrEq{filter.column: filter.prefix})
+			if end, ok := prefixEnd(filter.prefix); ok {
+				builder = builder.Where(sq.Lt{filter.column: end})
+			}
+		}
+	}
+	return builder
+}
 
-	lastIndex := page.Limit
-	nextCursor := strconv.FormatInt(rides[lastIndex].ID, 10)
+func nameFilters(page domain.Pagination) []nameFilter {
+	return []nameFilter{
+		{
+			column: "riderName",
+			value:  func(r domain.Ride) string { return r.RiderName },
+			exact:  page.RiderName,
+			prefix: page.RiderNamePrefix,
+		},
+		{
+			column: "driverName",
+			value:  func(r domain.Ride) string { return r.DriverName },
+			exact:  page.DriverName,
+			prefix: page.DriverNamePrefix,
+		},
+		{
+			column: "driverVehicle",
+			value:  func(r domain.Ride) string { return r.DriverVehicle },
+			exact:  page.DriverVehicle,
+			prefix: page.DriverVehiclePrefix,
+		},
+	}
+}
 
-	return rides[:lastIndex], nextCursor, nil
+// prefixEnd returns the first string after every string starting with the
+// prefix, which is the prefix with its last character incremented. UTF-8
+// strings are sorted the same way byte by byte and character by character. It
+// returns false when there is no such string.
+func prefixEnd(prefix string) (string, bool) {
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		switch runes[i] {
+		case utf8.MaxRune:
+			continue
+		case 0xD7FF:
+			// NOTE: Surrogates can't be encoded in UTF-8.
+			runes[i] = 0xE000
+		default:
+			runes[i]++
+		}
+		return string(runes[:i+1]), true
+	}
+	return "", false
 }
 
-func (r rideRepository) SelectByID(id int64) (*domain.Ride, error) {
+func scanRide(s rowScanner) (domain.Ride, error) {
 	var ride domain.Ride
-	err := sq.Select(r.tableColumns...).From("rides").Where(sq.Eq{"id": id}).RunWith(r.db).QueryRow().Scan(
+	err := s.Scan(
 		&ride.ID,
 		&ride.StartLatitude,
 		&ride.StartLongitude,
@@ -129,9 +754,85 @@ func (r rideRepository) SelectByID(id int64) (*domain.Ride, error) {
 		&ride.RiderName,
 		&ride.DriverName,
 		&ride.DriverVehicle,
+		&ride.DistanceMeters,
+		&ride.BearingDegrees,
+		&ride.Status,
+		&ride.StartedAt,
+		&ride.EndedAt,
+		&ride.CreatedAt,
+		&ride.UpdatedAt,
+		&ride.DeletedAt,
+		&ride.DriverID,
+		&ride.RiderID,
+		&ride.VehicleID,
 	)
-	if err != nil && err == sql.ErrNoRows {
-		return nil, nil
+	return ride, err
+}
+
+// rideRecord formats the ride in the same order as the table columns.
+func rideRecord(ride domain.Ride) []string {
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
 	}
-	return &ride, err
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	formatID := func(id *int64) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatInt(*id, 10)
+	}
+	return []string{
+		strconv.FormatInt(ride.ID, 10),
+		formatFloat(ride.StartLatitude),
+		formatFloat(ride.StartLongitude),
+		formatFloat(ride.EndLatitude),
+		formatFloat(ride.EndLongitude),
+		ride.RiderName,
+		ride.DriverName,
+		ride.DriverVehicle,
+		formatFloat(ride.DistanceMeters),
+		formatFloat(ride.BearingDegrees),
+		string(ride.Status),
+		formatTime(ride.StartedAt),
+		formatTime(ride.EndedAt),
+		formatTime(&ride.CreatedAt),
+		formatTime(&ride.UpdatedAt),
+		formatTime(ride.DeletedAt),
+		formatID(ride.DriverID),
+		formatID(ride.RiderID),
+		formatID(ride.VehicleID),
+	}
+}
+
+func parseRankCursor(s string) (rankCursor, error) {
+	parts := strings.SplitN(s, "_", 2)
+	if len(parts) != 2 {
+		return rankCursor{}, fmt.Errorf("%s is not a valid cursor", s)
+	}
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return rankCursor{}, err
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return rankCursor{}, err
+	}
+	return rankCursor{rank: rank, id: id}, nil
+}
+
+func (c rankCursor) String() string {
+	return fmt.Sprintf("%s_%d", strconv.FormatFloat(c.rank, 'g', -1, 64), c.id)
+}
+
+// less orders by rank first, rides with the same rank are ordered by ID.
+func (c rankCursor) less(other rankCursor) bool {
+	if c.rank != other.rank {
+		return c.rank < other.rank
+	}
+	return c.id < other.id
 }
@@ -1,12 +1,23 @@
 package repository_test
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	domain "github.com/hawarir/backend-coding-test"
 	"github.com/hawarir/backend-coding-test/repository"
@@ -14,35 +25,170 @@ import (
 
 type setupSQLMock func(sqlmock.Sqlmock)
 
-func createRideRepo(fn setupSQLMock) (domain.RideRepository, *sql.DB) {
-	db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+// backend is an implementation the cases are run against. The expected queries
+// are written with question mark placeholders, they are rewritten to the
+// placeholders of the backend before being matched.
+type backend struct {
+	name           string
+	newRepo        func(*sql.DB) domain.RideRepository
+	newDriverRepo  func(*sql.DB) domain.DriverRepository
+	newRiderRepo   func(*sql.DB) domain.RiderRepository
+	newVehicleRepo func(*sql.DB) domain.VehicleRepository
+	placeholder    sq.PlaceholderFormat
+	returningID    bool
+	// uniqueViolation is what the driver returns for a row breaking a unique
+	// index.
+	uniqueViolation error
+}
+
+func backends() []backend {
+	return []backend{
+		{
+			name:            "SQLite",
+			newRepo:         repository.NewRideRepository,
+			newDriverRepo:   repository.NewDriverRepository,
+			newRiderRepo:    repository.NewRiderRepository,
+			newVehicleRepo:  repository.NewVehicleRepository,
+			placeholder:     sq.Question,
+			uniqueViolation: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique},
+		},
+		{
+			name:            "Postgres",
+			newRepo:         repository.NewPostgresRideRepository,
+			newDriverRepo:   repository.NewPostgresDriverRepository,
+			newRiderRepo:    repository.NewPostgresRiderRepository,
+			newVehicleRepo:  repository.NewPostgresVehicleRepository,
+			placeholder:     sq.Dollar,
+			returningID:     true,
+			uniqueViolation: &pq.Error{Code: "23505"},
+		},
+	}
+}
+
+func createRideRepo(b backend, fn setupSQLMock) (domain.RideRepository, *sql.DB) {
+	db := createSQLMock(b, fn)
+	return b.newRepo(db), db
+}
+
+// createSQLMock matches the queries expected by fn in the placeholders of the
+// backend.
+func createSQLMock(b backend, fn setupSQLMock) *sql.DB {
+	matcher := sqlmock.QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+		expectedSQL, err := b.placeholder.ReplacePlaceholders(expectedSQL)
+		if err != nil {
+			return err
+		}
+		return sqlmock.QueryMatcherEqual.Match(expectedSQL, actualSQL)
+	})
+	db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
 	if fn != nil {
 		fn(mock)
 	}
-	return repository.NewRideRepository(db), db
+	return db
+}
+
+// expectInsert expects a ride to be inserted, Postgres reads the ID back from a
+// RETURNING clause instead of the result.
+func expectInsert(mock sqlmock.Sqlmock, b backend, args []driver.Value, id int64, err error) {
+	query := "INSERT INTO rides (startLat,startLong,endLat,endLong,riderName,driverName,driverVehicle,distance,bearing,status,startedAt,endedAt,createdAt,updatedAt,startGeohash,endGeohash,driverId,riderId,vehicleId,startX,startY,startZ,endX,endY,endZ) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+	if b.returningID {
+		expectation := mock.ExpectQuery(query + " RETURNING id").WithArgs(args...)
+		if err != nil {
+			expectation.WillReturnError(err)
+		} else {
+			expectation.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		}
+		return
+	}
+	expectation := mock.ExpectExec(query).WithArgs(args...)
+	if err != nil {
+		expectation.WillReturnError(err)
+	} else {
+		expectation.WillReturnResult(sqlmock.NewResult(id, 1))
+	}
+}
+
+func testTime() time.Time {
+	return time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC)
+}
+
+// geohashCells returns the predicate matching the given number of geohash cells.
+func geohashCells(column string, count int) string {
+	cells := make([]string, count)
+	for i := range cells {
+		cells[i] = fmt.Sprintf("(%s >= ? AND %s < ?)", column, column)
+	}
+	return "(" + strings.Join(cells, " OR ") + ")"
+}
+
+func newRideRows() *sqlmock.Rows {
+	return sqlmock.NewRows(rideColumns())
+}
+
+func rideColumns() []string {
+	return []string{
+		"id",
+		"startLat",
+		"startLong",
+		"endLat",
+		"endLong",
+		"riderName",
+		"driverName",
+		"driverVehicle",
+		"distance",
+		"bearing",
+		"status",
+		"startedAt",
+		"endedAt",
+		"createdAt",
+		"updatedAt",
+		"deletedAt",
+		"driverId",
+		"riderId",
+		"vehicleId",
+	}
 }
 
 func TestRideRepository_Insert(t *testing.T) {
+	insertArgs := []driver.Value{
+		float64(-90),
+		float64(-180),
+		float64(90),
+		float64(180),
+		"John Doe",
+		"Driver",
+		"Car",
+		float64(20015114.442035925),
+		float64(0),
+		domain.RideStatusRequested,
+		nil,
+		nil,
+		testTime(),
+		testTime(),
+		"000000000000",
+		"zzzzzzzzzzzz",
+		nil,
+		nil,
+		nil,
+		float64(-6.123233995736757e-17),
+		float64(-7.498798913309268e-33),
+		float64(-1),
+		float64(-6.123233995736757e-17),
+		float64(7.498798913309268e-33),
+		float64(1),
+	}
+
 	testCases := []struct {
 		testName     string
-		setupSQLMock setupSQLMock
+		setupSQLMock func(sqlmock.Sqlmock, backend)
 		ride         domain.Ride
 		lastInsertID int64
 		expectedErr  string
 	}{
 		{
 			testName: "When exec returns error, return the error",
-			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO rides (startLat,startLong,endLat,endLong,riderName,driverName,driverVehicle) VALUES (?,?,?,?,?,?,?)").
-					WithArgs(
-						float64(-90),
-						float64(-180),
-						float64(90),
-						float64(180),
-						"John Doe",
-						"Driver",
-						"Car",
-					).WillReturnError(errors.New("Exec error"))
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				expectInsert(mock, b, insertArgs, 0, errors.New("Exec error"))
 			},
 			ride: domain.Ride{
 				StartLatitude:  -90,
@@ -52,22 +198,17 @@ func TestRideRepository_Insert(t *testing.T) {
 				RiderName:      "John Doe",
 				DriverName:     "Driver",
 				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusRequested,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
 			},
 			expectedErr: "Exec error",
 		},
 		{
 			testName: "When successful, return the result",
-			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO rides (startLat,startLong,endLat,endLong,riderName,driverName,driverVehicle) VALUES (?,?,?,?,?,?,?)").
-					WithArgs(
-						float64(-90),
-						float64(-180),
-						float64(90),
-						float64(180),
-						"John Doe",
-						"Driver",
-						"Car",
-					).WillReturnResult(sqlmock.NewResult(123, 1))
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				expectInsert(mock, b, insertArgs, 123, nil)
 			},
 			ride: domain.Ride{
 				StartLatitude:  -90,
@@ -77,45 +218,185 @@ func TestRideRepository_Insert(t *testing.T) {
 				RiderName:      "John Doe",
 				DriverName:     "Driver",
 				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusRequested,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
 			},
 			lastInsertID: 123,
 		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.testName, func(t *testing.T) {
-			rideRepo, db := createRideRepo(tc.setupSQLMock)
-			defer db.Close()
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, func(mock sqlmock.Sqlmock) { tc.setupSQLMock(mock, b) })
+				defer db.Close()
 
-			lastInsertID, err := rideRepo.Insert(tc.ride)
-			if tc.expectedErr != "" {
-				assert.EqualError(t, err, tc.expectedErr)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tc.lastInsertID, lastInsertID)
-			}
-		})
+				lastInsertID, err := rideRepo.Insert(context.Background(), tc.ride)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.lastInsertID, lastInsertID)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_InsertBatch(t *testing.T) {
+	insertArgs := func(riderName string) []driver.Value {
+		return []driver.Value{
+			float64(-90),
+			float64(-180),
+			float64(90),
+			float64(180),
+			riderName,
+			"Driver",
+			"Car",
+			float64(20015114.442035925),
+			float64(0),
+			domain.RideStatusRequested,
+			nil,
+			nil,
+			testTime(),
+			testTime(),
+			"000000000000",
+			"zzzzzzzzzzzz",
+			nil,
+			nil,
+			nil,
+			float64(-6.123233995736757e-17),
+			float64(-7.498798913309268e-33),
+			float64(-1),
+			float64(-6.123233995736757e-17),
+			float64(7.498798913309268e-33),
+			float64(1),
+		}
+	}
+	newRide := func(riderName string) domain.Ride {
+		return domain.Ride{
+			StartLatitude:  -90,
+			StartLongitude: -180,
+			EndLatitude:    90,
+			EndLongitude:   180,
+			RiderName:      riderName,
+			DriverName:     "Driver",
+			DriverVehicle:  "Car",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+		}
+	}
+
+	testCases := []struct {
+		testName     string
+		setupSQLMock func(sqlmock.Sqlmock, backend)
+		rides        []domain.Ride
+		ids          []int64
+		expectedErr  string
+	}{
+		{
+			testName: "When begin returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectBegin().WillReturnError(errors.New("Begin error"))
+			},
+			rides:       []domain.Ride{newRide("John Doe")},
+			expectedErr: "Begin error",
+		},
+		{
+			testName: "When exec returns error, roll back and return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectBegin()
+				expectInsert(mock, b, insertArgs("John Doe"), 123, nil)
+				expectInsert(mock, b, insertArgs("Jane Doe"), 0, errors.New("Exec error"))
+				mock.ExpectRollback()
+			},
+			rides:       []domain.Ride{newRide("John Doe"), newRide("Jane Doe")},
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When commit returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectBegin()
+				expectInsert(mock, b, insertArgs("John Doe"), 123, nil)
+				mock.ExpectCommit().WillReturnError(errors.New("Commit error"))
+			},
+			rides:       []domain.Ride{newRide("John Doe")},
+			expectedErr: "Commit error",
+		},
+		{
+			testName: "When successful, return the IDs in order",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectBegin()
+				expectInsert(mock, b, insertArgs("John Doe"), 123, nil)
+				expectInsert(mock, b, insertArgs("Jane Doe"), 124, nil)
+				mock.ExpectCommit()
+			},
+			rides: []domain.Ride{newRide("John Doe"), newRide("Jane Doe")},
+			ids:   []int64{123, 124},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				var sqlMock sqlmock.Sqlmock
+				rideRepo, db := createRideRepo(b, func(mock sqlmock.Sqlmock) {
+					sqlMock = mock
+					tc.setupSQLMock(mock, b)
+				})
+				defer db.Close()
+
+				ids, err := rideRepo.InsertBatch(context.Background(), tc.rides)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.ids, ids)
+				}
+				assert.NoError(t, sqlMock.ExpectationsWereMet())
+			})
+		}
 	}
 }
 
 func TestRideRepository_SelectAll(t *testing.T) {
+	pageRow := func(rows *sqlmock.Rows, id int64) *sqlmock.Rows {
+		return rows.AddRow(id, 0, 0, 0, 0, "John Doe", "Driver", "Car", 0, 0, "requested", nil, nil, testTime(), testTime(), nil, nil, nil, nil)
+	}
+	pageRide := func(id int64) domain.Ride {
+		return domain.Ride{
+			ID:            id,
+			RiderName:     "John Doe",
+			DriverName:    "Driver",
+			DriverVehicle: "Car",
+			Status:        domain.RideStatusRequested,
+			CreatedAt:     testTime(),
+			UpdatedAt:     testTime(),
+		}
+	}
+
 	testCases := []struct {
 		testName     string
 		setupSQLMock setupSQLMock
 		page         domain.Pagination
 		rides        []domain.Ride
-		cursor       string
+		cursors      domain.PageCursors
+		total        *int64
+		facets       map[string][]domain.FacetCount
 		expectedErr  string
 	}{
 		{
 			testName:    "When failed to parse cursor, return error",
-			page:        domain.Pagination{Cursor: "not-a-number"},
+			page:        domain.Pagination{After: "not-a-number"},
 			expectedErr: "strconv.ParseInt: parsing \"not-a-number\": invalid syntax",
 		},
 		{
 			testName: "When query returns error, return the error",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides ORDER BY id desc").
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
 					WillReturnError(errors.New("Query error"))
 			},
 			expectedErr: "Query error",
@@ -123,18 +404,8 @@ func TestRideRepository_SelectAll(t *testing.T) {
 		{
 			testName: "When scan failed, return error",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides ORDER BY id desc").
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}).
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnRows(newRideRows().
 						AddRow(
 							123,
 							"not-a-number",
@@ -144,6 +415,17 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						))
 			},
 			expectedErr: "sql: Scan error on column index 1, name \"startLat\": converting driver.Value type string (\"not-a-number\") to a float64: invalid syntax",
@@ -151,36 +433,16 @@ func TestRideRepository_SelectAll(t *testing.T) {
 		{
 			testName: "When return no rows, return empty slice",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides ORDER BY id desc").
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnRows(newRideRows())
 			},
 			rides: []domain.Ride{},
 		},
 		{
 			testName: "When successful, return rides",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides ORDER BY id desc").
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}).
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnRows(newRideRows().
 						AddRow(
 							123,
 							-90,
@@ -190,6 +452,17 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						))
 			},
 			rides: []domain.Ride{
@@ -202,25 +475,19 @@ func TestRideRepository_SelectAll(t *testing.T) {
 					RiderName:      "John Doe",
 					DriverName:     "Driver",
 					DriverVehicle:  "Car",
+					DistanceMeters: 20015114.442035925,
+					Status:         domain.RideStatusRequested,
+					CreatedAt:      testTime(),
+					UpdatedAt:      testTime(),
 				},
 			},
 		},
 		{
 			testName: "When provided pagination, use it as part of the query",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides WHERE id <= ? ORDER BY id desc LIMIT 3").
-					WithArgs(int64(3)).
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}).
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND id < ? ORDER BY id desc LIMIT 3").
+					WithArgs(int64(4)).
+					WillReturnRows(newRideRows().
 						AddRow(
 							3,
 							-90,
@@ -230,6 +497,17 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						).
 						AddRow(
 							2,
@@ -240,6 +518,17 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						).
 						AddRow(
 							1,
@@ -250,9 +539,20 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						))
 			},
-			page: domain.Pagination{Cursor: "3", Limit: 2},
+			page: domain.Pagination{After: "4", Limit: 2},
 			rides: []domain.Ride{
 				{
 					ID:             3,
@@ -263,6 +563,10 @@ func TestRideRepository_SelectAll(t *testing.T) {
 					RiderName:      "John Doe",
 					DriverName:     "Driver",
 					DriverVehicle:  "Car",
+					DistanceMeters: 20015114.442035925,
+					Status:         domain.RideStatusRequested,
+					CreatedAt:      testTime(),
+					UpdatedAt:      testTime(),
 				},
 				{
 					ID:             2,
@@ -273,26 +577,20 @@ func TestRideRepository_SelectAll(t *testing.T) {
 					RiderName:      "John Doe",
 					DriverName:     "Driver",
 					DriverVehicle:  "Car",
+					DistanceMeters: 20015114.442035925,
+					Status:         domain.RideStatusRequested,
+					CreatedAt:      testTime(),
+					UpdatedAt:      testTime(),
 				},
 			},
-			cursor: "1",
+			cursors: domain.PageCursors{Next: "2", Prev: "3"},
 		},
 		{
-			testName: "When result count is less than or equal page limit, return all of it without cursor",
+			testName: "When result count is less than or equal page limit, return all of it without next cursor",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides WHERE id <= ? ORDER BY id desc LIMIT 3").
-					WithArgs(int64(3)).
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}).
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND id < ? ORDER BY id desc LIMIT 3").
+					WithArgs(int64(4)).
+					WillReturnRows(newRideRows().
 						AddRow(
 							3,
 							-90,
@@ -302,6 +600,17 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						).
 						AddRow(
 							2,
@@ -312,9 +621,20 @@ func TestRideRepository_SelectAll(t *testing.T) {
 							"John Doe",
 							"Driver",
 							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
 						))
 			},
-			page: domain.Pagination{Cursor: "3", Limit: 2},
+			page: domain.Pagination{After: "4", Limit: 2},
 			rides: []domain.Ride{
 				{
 					ID:             3,
@@ -325,6 +645,10 @@ func TestRideRepository_SelectAll(t *testing.T) {
 					RiderName:      "John Doe",
 					DriverName:     "Driver",
 					DriverVehicle:  "Car",
+					DistanceMeters: 20015114.442035925,
+					Status:         domain.RideStatusRequested,
+					CreatedAt:      testTime(),
+					UpdatedAt:      testTime(),
 				},
 				{
 					ID:             2,
@@ -335,140 +659,1299 @@ func TestRideRepository_SelectAll(t *testing.T) {
 					RiderName:      "John Doe",
 					DriverName:     "Driver",
 					DriverVehicle:  "Car",
+					DistanceMeters: 20015114.442035925,
+					Status:         domain.RideStatusRequested,
+					CreatedAt:      testTime(),
+					UpdatedAt:      testTime(),
 				},
 			},
-			cursor: "",
+			cursors: domain.PageCursors{Prev: "3"},
+		},
+		{
+			testName: "When paging back, read the nearest rides first and return them latest first",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND id > ? ORDER BY id asc LIMIT 3").
+					WithArgs(int64(1)).
+					WillReturnRows(pageRow(pageRow(pageRow(newRideRows(), 2), 3), 4))
+			},
+			page:    domain.Pagination{Before: "1", Limit: 2},
+			rides:   []domain.Ride{pageRide(3), pageRide(2)},
+			cursors: domain.PageCursors{Next: "2", Prev: "3"},
+		},
+		{
+			testName: "When paging back to the latest rides, return no previous cursor",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND id > ? ORDER BY id asc LIMIT 3").
+					WithArgs(int64(3)).
+					WillReturnRows(pageRow(newRideRows(), 4))
+			},
+			page:    domain.Pagination{Before: "3", Limit: 2},
+			rides:   []domain.Ride{pageRide(4)},
+			cursors: domain.PageCursors{Next: "4"},
+		},
+		{
+			testName: "When provided name filters, match names exactly or by prefix",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND riderName >= ? AND riderName < ? AND driverName = ? AND driverVehicle >= ? ORDER BY id desc").
+					WithArgs("Jo", "Jp", "Driver", string(utf8.MaxRune)).
+					WillReturnRows(newRideRows())
+			},
+			page:  domain.Pagination{RiderNamePrefix: "Jo", DriverName: "Driver", DriverVehiclePrefix: string(utf8.MaxRune)},
+			rides: []domain.Ride{},
+		},
+		{
+			testName: "When sorted by several fields, page by all of them and ID",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND ((distance < ?) OR (distance = ? AND riderName > ?) OR (distance = ? AND riderName = ? AND id < ?)) ORDER BY distance desc, riderName asc, id desc LIMIT 2").
+					WithArgs(float64(2500), float64(2500), "Jane Doe", float64(2500), "Jane Doe", int64(4)).
+					WillReturnRows(pageRow(pageRow(newRideRows(), 3), 2))
+			},
+			page:    domain.Pagination{Sort: "-distance,riderName", After: "2500,Jane+Doe,4", Limit: 1},
+			rides:   []domain.Ride{pageRide(3)},
+			cursors: domain.PageCursors{Next: "0,John+Doe,3", Prev: "0,John+Doe,3"},
+		},
+		{
+			testName: "When paging back sorted by a field, reverse the order of every field",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND ((riderName < ?) OR (riderName = ? AND id > ?)) ORDER BY riderName desc, id asc LIMIT 3").
+					WithArgs("John Doe", "John Doe", int64(2)).
+					WillReturnRows(pageRow(newRideRows(), 3))
+			},
+			page:    domain.Pagination{Sort: "riderName", Before: "John+Doe,2", Limit: 2},
+			rides:   []domain.Ride{pageRide(3)},
+			cursors: domain.PageCursors{Next: "John+Doe,3"},
+		},
+		{
+			testName: "When scoped to a rider, filter by riderId",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND riderId = ? AND id < ? ORDER BY id desc LIMIT 2").
+					WithArgs(int64(7), int64(4)).
+					WillReturnRows(pageRow(newRideRows(), 3))
+			},
+			page:    domain.Pagination{RiderID: 7, After: "4", Limit: 1},
+			rides:   []domain.Ride{pageRide(3)},
+			cursors: domain.PageCursors{Prev: "3"},
+		},
+		{
+			testName:    "When cursor doesn't match the sort, return error",
+			page:        domain.Pagination{Sort: "riderName", After: "4"},
+			expectedErr: "4 is not a valid cursor for 2 sort fields",
+		},
+		{
+			testName: "When including deleted rides, don't filter by deletedAt",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides ORDER BY id desc").
+					WillReturnRows(newRideRows())
+			},
+			page:  domain.Pagination{IncludeDeleted: true},
+			rides: []domain.Ride{},
+		},
+		{
+			testName: "When provided time range, filter by creation time",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND createdAt >= ? AND createdAt < ? AND id < ? ORDER BY id desc LIMIT 2").
+					WithArgs(
+						time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+						time.Date(2021, 4, 2, 0, 0, 0, 0, time.UTC),
+						int64(3),
+					).
+					WillReturnRows(newRideRows())
+			},
+			page: domain.Pagination{
+				After: "3",
+				Limit: 1,
+				From:  time.Date(2021, 4, 1, 7, 0, 0, 0, time.FixedZone("WIB", 7*60*60)),
+				To:    time.Date(2021, 4, 2, 0, 0, 0, 0, time.UTC),
+			},
+			rides: []domain.Ride{},
+		},
+		{
+			testName: "When provided distance range, filter by distance",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND distance >= ? AND distance <= ? ORDER BY id desc").
+					WithArgs(float64(1000), float64(2500)).
+					WillReturnRows(newRideRows())
+			},
+			page:  domain.Pagination{MinDistance: 1000, MaxDistance: 2500},
+			rides: []domain.Ride{},
+		},
+		{
+			testName: "When including the total and facets, count them under the filters but not the cursor",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL AND riderName = ? AND id < ? ORDER BY id desc").
+					WithArgs("John Doe", int64(3)).
+					WillReturnRows(newRideRows())
+				mock.ExpectQuery("SELECT COUNT(*) FROM rides WHERE deletedAt IS NULL AND riderName = ?").
+					WithArgs("John Doe").
+					WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(5))
+				mock.ExpectQuery("SELECT driverName, COUNT(*) AS facetCount FROM rides WHERE deletedAt IS NULL AND riderName = ? GROUP BY driverName ORDER BY facetCount desc, driverName asc LIMIT 10").
+					WithArgs("John Doe").
+					WillReturnRows(sqlmock.NewRows([]string{"driverName", "facetCount"}).AddRow("Driver", 3).AddRow("Other Driver", 2))
+				mock.ExpectQuery("SELECT driverVehicle, COUNT(*) AS facetCount FROM rides WHERE deletedAt IS NULL AND riderName = ? GROUP BY driverVehicle ORDER BY facetCount desc, driverVehicle asc LIMIT 10").
+					WithArgs("John Doe").
+					WillReturnRows(sqlmock.NewRows([]string{"driverVehicle", "facetCount"}).AddRow("Car", 5))
+			},
+			page:  domain.Pagination{RiderName: "John Doe", After: "3", Include: "total,facets"},
+			rides: []domain.Ride{},
+			total: func() *int64 { total := int64(5); return &total }(),
+			facets: map[string][]domain.FacetCount{
+				"driverName":    {{Value: "Driver", Count: 3}, {Value: "Other Driver", Count: 2}},
+				"driverVehicle": {{Value: "Car", Count: 5}},
+			},
+		},
+		{
+			testName: "When counting the total returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnRows(newRideRows())
+				mock.ExpectQuery("SELECT COUNT(*) FROM rides WHERE deletedAt IS NULL").
+					WillReturnError(errors.New("Count error"))
+			},
+			page:        domain.Pagination{Include: "total"},
+			expectedErr: "Count error",
 		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.testName, func(t *testing.T) {
-			rideRepo, db := createRideRepo(tc.setupSQLMock)
-			defer db.Close()
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
 
-			rides, cursor, err := rideRepo.SelectAll(tc.page)
-			if tc.expectedErr != "" {
-				assert.EqualError(t, err, tc.expectedErr)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tc.rides, rides)
-				assert.Equal(t, tc.cursor, cursor)
-			}
-		})
+				rides, info, err := rideRepo.SelectAll(context.Background(), tc.page)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.rides, rides)
+					assert.Equal(t, tc.cursors, info.PageCursors)
+					assert.Equal(t, tc.total, info.Total)
+					assert.Equal(t, tc.facets, info.Facets)
+				}
+			})
+		}
 	}
 }
 
-func TestRideRepository_SelectByID(t *testing.T) {
+func TestRideRepository_SelectNearby(t *testing.T) {
+	nearbyRow := func(rows *sqlmock.Rows, id int64, lat, long, rank float64) *sqlmock.Rows {
+		return rows.AddRow(id, lat, long, 0, 0, "John Doe", "Driver", "Car", 0, 0, "requested", nil, nil, testTime(), testTime(), nil, nil, nil, nil, rank)
+	}
+	nearbyRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(append(rideColumns(), "nearbyRank"))
+	}
+	nearbyRide := func(id int64, lat, long float64) domain.Ride {
+		return domain.Ride{
+			ID:             id,
+			StartLatitude:  lat,
+			StartLongitude: long,
+			RiderName:      "John Doe",
+			DriverName:     "Driver",
+			DriverVehicle:  "Car",
+			Status:         domain.RideStatusRequested,
+			CreatedAt:      testTime(),
+			UpdatedAt:      testTime(),
+		}
+	}
+	nearbyRank := func(endpoint string) string {
+		return strings.ReplaceAll("(%X - ?)*(%X - ?) + (%Y - ?)*(%Y - ?) + (%Z - ?)*(%Z - ?)", "%", endpoint)
+	}
+	nearbyQuery := func(endpoint, longitude, cursor string, limit int) string {
+		rank := nearbyRank(endpoint)
+		query := "SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId, " + rank + " AS nearbyRank FROM rides WHERE deletedAt IS NULL AND " +
+			geohashCells(endpoint+"Geohash", 8) +
+			" AND (" + endpoint + "Lat >= ? AND " + endpoint + "Lat <= ?) AND " + longitude +
+			" AND " + rank + " <= ?"
+		if cursor != "" {
+			query += " AND (" + rank + " > ? OR (" + rank + " = ? AND id > ?))"
+		}
+		return query + fmt.Sprintf(" ORDER BY nearbyRank asc, id asc LIMIT %d", limit)
+	}
+	// NOTE: Every search in these cases ranks rides by the squared chord to the
+	// center, is covered by 8 geohash cells, each matched with a lower and
+	// upper bound, the bounding box and the radius. The cursor adds two ranks
+	// and an ID.
+	nearbyArgs := func(cursor bool) []driver.Value {
+		args := make([]driver.Value, 6+8*2+4+7)
+		if cursor {
+			args = make([]driver.Value, len(args)+7+7+1)
+		}
+		for i := range args {
+			args[i] = sqlmock.AnyArg()
+		}
+		return args
+	}
+
 	testCases := []struct {
 		testName     string
 		setupSQLMock setupSQLMock
-		rideID       int64
-		ride         *domain.Ride
+		query        domain.NearbyQuery
+		rides        []domain.Ride
+		cursor       string
 		expectedErr  string
 	}{
+		{
+			testName: "When failed to parse cursor, return error",
+			query: domain.NearbyQuery{
+				Radius:     1000,
+				Endpoint:   domain.RideEndpointStart,
+				Pagination: domain.Pagination{After: "not-a-cursor"},
+			},
+			expectedErr: "not-a-cursor is not a valid cursor",
+		},
 		{
 			testName: "When query returns error, return the error",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides WHERE id = ?").
-					WithArgs(int64(123)).
+				mock.ExpectQuery(nearbyQuery("start", "(startLong >= ? AND startLong <= ?)", "", 10001)).
+					WithArgs(nearbyArgs(false)...).
 					WillReturnError(errors.New("Query error"))
 			},
-			rideID:      123,
+			query:       domain.NearbyQuery{Radius: 1000, Endpoint: domain.RideEndpointStart},
 			expectedErr: "Query error",
 		},
 		{
-			testName: "When query returns errNoRows, return nil",
+			testName: "When searching by end point, filter by end coordinates",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides WHERE id = ?").
-					WithArgs(int64(123)).
-					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(nearbyQuery("end", "(endLong >= ? AND endLong <= ?)", "", 10001)).
+					WithArgs(nearbyArgs(false)...).
+					WillReturnRows(nearbyRows())
 			},
-			rideID: 123,
-			ride:   nil,
+			query: domain.NearbyQuery{Radius: 1000, Endpoint: domain.RideEndpointEnd},
+			rides: []domain.Ride{},
 		},
 		{
-			testName: "When scan failed, return error",
+			testName: "When search crosses the antimeridian, match either side of it",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides WHERE id = ?").
-					WithArgs(int64(123)).
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}).
-						AddRow(
-							123,
-							"not-a-number",
-							"not-a-number",
-							"not-a-number",
-							"not-a-number",
-							"John Doe",
-							"Driver",
-							"Car",
-						))
+				mock.ExpectQuery(nearbyQuery("start", "(startLong >= ? OR startLong <= ?)", "", 10001)).
+					WithArgs(nearbyArgs(false)...).
+					WillReturnRows(nearbyRows())
 			},
-			rideID:      123,
-			expectedErr: "sql: Scan error on column index 1, name \"startLat\": converting driver.Value type string (\"not-a-number\") to a float64: invalid syntax",
+			query: domain.NearbyQuery{Longitude: 180, Radius: 1000, Endpoint: domain.RideEndpointStart},
+			rides: []domain.Ride{},
 		},
 		{
-			testName: "When successful, return ride",
+			testName: "When there are too many rides without a limit, return error",
 			setupSQLMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle FROM rides WHERE id = ?").
-					WithArgs(int64(123)).
-					WillReturnRows(sqlmock.
-						NewRows([]string{
-							"id",
-							"startLat",
-							"startLong",
-							"endLat",
-							"endLong",
-							"riderName",
-							"driverName",
-							"driverVehicle",
-						}).
-						AddRow(
-							123,
-							-90,
-							-180,
-							90,
-							180,
-							"John Doe",
-							"Driver",
-							"Car",
-						))
+				rows := nearbyRows()
+				for i := int64(1); i <= 10001; i++ {
+					nearbyRow(rows, i, 0, 0, 0)
+				}
+				mock.ExpectQuery(nearbyQuery("start", "(startLong >= ? AND startLong <= ?)", "", 10001)).
+					WithArgs(nearbyArgs(false)...).
+					WillReturnRows(rows)
 			},
-			rideID: 123,
-			ride: &domain.Ride{
-				ID:             123,
-				StartLatitude:  -90,
-				StartLongitude: -180,
-				EndLatitude:    90,
-				EndLongitude:   180,
-				RiderName:      "John Doe",
-				DriverName:     "Driver",
-				DriverVehicle:  "Car",
+			query:       domain.NearbyQuery{Radius: 1000, Endpoint: domain.RideEndpointStart},
+			expectedErr: "too many rides around the center, use a limit or a smaller radius",
+		},
+		{
+			testName: "When successful, stop at the first ride outside of radius",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				rows := nearbyRows()
+				nearbyRow(rows, 2, 0.001, 0, 1e-8)
+				nearbyRow(rows, 1, 0.005, 0, 2e-8)
+				nearbyRow(rows, 4, 0, 0.005, 2e-8)
+				nearbyRow(rows, 3, 0.009, 0.009, 3e-8)
+				mock.ExpectQuery(nearbyQuery("start", "(startLong >= ? AND startLong <= ?)", "", 10001)).
+					WithArgs(nearbyArgs(false)...).
+					WillReturnRows(rows)
+			},
+			query: domain.NearbyQuery{Radius: 1000, Endpoint: domain.RideEndpointStart},
+			rides: []domain.Ride{
+				nearbyRide(2, 0.001, 0),
+				nearbyRide(1, 0.005, 0),
+				nearbyRide(4, 0, 0.005),
+			},
+		},
+		{
+			testName: "When provided pagination, start from cursor and return the next one",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				rows := nearbyRows()
+				nearbyRow(rows, 3, 0.003, 0, 3e-8)
+				nearbyRow(rows, 4, 0, 0.005, 4e-8)
+				mock.ExpectQuery(nearbyQuery("start", "(startLong >= ? AND startLong <= ?)", "1e-08_2", 2)).
+					WithArgs(nearbyArgs(true)...).
+					WillReturnRows(rows)
+			},
+			query: domain.NearbyQuery{
+				Radius:     1000,
+				Endpoint:   domain.RideEndpointStart,
+				Pagination: domain.Pagination{After: "1e-08_2", Limit: 1},
 			},
+			rides:  []domain.Ride{nearbyRide(3, 0.003, 0)},
+			cursor: "3e-08_3",
+		},
+		{
+			testName: "When provided a limit past the last ride, return no cursor",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				rows := nearbyRows()
+				nearbyRow(rows, 3, 0.003, 0, 3e-8)
+				mock.ExpectQuery(nearbyQuery("start", "(startLong >= ? AND startLong <= ?)", "", 3)).
+					WithArgs(nearbyArgs(false)...).
+					WillReturnRows(rows)
+			},
+			query: domain.NearbyQuery{
+				Radius:     1000,
+				Endpoint:   domain.RideEndpointStart,
+				Pagination: domain.Pagination{Limit: 2},
+			},
+			rides: []domain.Ride{nearbyRide(3, 0.003, 0)},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				rides, cursor, err := rideRepo.SelectNearby(context.Background(), tc.query)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.rides, rides)
+					assert.Equal(t, tc.cursor, cursor)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_Search(t *testing.T) {
+	const columns = "SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId, searchRank FROM rides JOIN "
+	const sqliteAvailable = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'rides_search_insert'"
+	searchRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "startLat", "startLong", "endLat", "endLong", "riderName", "driverName", "driverVehicle", "distance", "bearing", "status", "startedAt", "endedAt", "createdAt", "updatedAt", "deletedAt", "driverId", "riderId", "vehicleId", "searchRank"})
+	}
+	searchRow := func(rows *sqlmock.Rows, id int64, rank float64) *sqlmock.Rows {
+		return rows.AddRow(id, 0, 0, 0, 0, "John Doe", "Driver", "Car", 0, 0, "requested", nil, nil, testTime(), testTime(), nil, nil, nil, nil, rank)
+	}
+	searchRide := func(id int64) domain.Ride {
+		return domain.Ride{
+			ID:            id,
+			RiderName:     "John Doe",
+			DriverName:    "Driver",
+			DriverVehicle: "Car",
+			Status:        domain.RideStatusRequested,
+			CreatedAt:     testTime(),
+			UpdatedAt:     testTime(),
+		}
+	}
+	query := func(after string, limit uint64) domain.SearchQuery {
+		return domain.SearchQuery{Q: "Jo John", Pagination: domain.Pagination{After: after, Limit: limit}}
+	}
+	// NOTE: Matches are selected differently by every backend, the rest of the
+	// query is the same.
+	matches := map[string]string{
+		"SQLite":   "(SELECT rowid AS searchID, rank AS searchRank FROM rides_search WHERE rides_search MATCH ?)",
+		"Postgres": "(SELECT id AS searchID, -((CASE WHEN lower(riderName || ' ' || driverName || ' ' || driverVehicle) LIKE ? THEN 1 ELSE 0 END) + (CASE WHEN lower(riderName || ' ' || driverName || ' ' || driverVehicle) LIKE ? THEN 1 ELSE 0 END)) AS searchRank FROM rides WHERE (CASE WHEN lower(riderName || ' ' || driverName || ' ' || driverVehicle) LIKE ? THEN 1 ELSE 0 END) + (CASE WHEN lower(riderName || ' ' || driverName || ' ' || driverVehicle) LIKE ? THEN 1 ELSE 0 END) > 0)",
+	}
+	matchArgs := map[string][]driver.Value{
+		"SQLite":   {`"joh" OR "ohn"`},
+		"Postgres": {"%joh%", "%ohn%", "%joh%", "%ohn%"},
+	}
+	expectSearch := func(mock sqlmock.Sqlmock, b backend, where string, args ...driver.Value) *sqlmock.ExpectedQuery {
+		if b.name == "SQLite" {
+			mock.ExpectQuery(sqliteAvailable).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		}
+		return mock.ExpectQuery(columns + matches[b.name] + " AS matches ON matches.searchID = rides.id " + where).
+			WithArgs(append(append([]driver.Value{}, matchArgs[b.name]...), args...)...)
+	}
+
+	testCases := []struct {
+		testName       string
+		setupSQLMock   func(sqlmock.Sqlmock, backend)
+		query          domain.SearchQuery
+		rides          []domain.Ride
+		expectedCursor string
+		expectedErr    string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				expectSearch(mock, b, "WHERE deletedAt IS NULL ORDER BY searchRank asc, id asc").
+					WillReturnError(errors.New("Query error"))
+			},
+			query:       query("", 0),
+			expectedErr: "Query error",
+		},
+		{
+			testName:    "When failed to parse cursor, return error",
+			query:       query("not-a-cursor", 0),
+			expectedErr: "not-a-cursor is not a valid cursor",
+		},
+		{
+			testName: "When there are more rides than the limit, return next cursor",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				expectSearch(mock, b, "WHERE deletedAt IS NULL AND (searchRank > ? OR (searchRank = ? AND id > ?)) ORDER BY searchRank asc, id asc LIMIT 3", -2.5, -2.5, int64(4)).
+					WillReturnRows(searchRow(searchRow(searchRow(searchRows(), 7, -2), 2, -1.5), 5, -1.5))
+			},
+			query:          query("-2.5_4", 2),
+			rides:          []domain.Ride{searchRide(7), searchRide(2)},
+			expectedCursor: "-1.5_2",
+		},
+		{
+			testName: "When the last page is read, return no cursor",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				expectSearch(mock, b, "WHERE deletedAt IS NULL ORDER BY searchRank asc, id asc LIMIT 3").
+					WillReturnRows(searchRow(searchRows(), 7, -2))
+			},
+			query: query("", 2),
+			rides: []domain.Ride{searchRide(7)},
 		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.testName, func(t *testing.T) {
-			rideRepo, db := createRideRepo(tc.setupSQLMock)
-			defer db.Close()
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, func(mock sqlmock.Sqlmock) {
+					if b.name == "SQLite" && tc.setupSQLMock == nil {
+						mock.ExpectQuery(sqliteAvailable).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+					}
+					if tc.setupSQLMock != nil {
+						tc.setupSQLMock(mock, b)
+					}
+				})
+				defer db.Close()
 
-			ride, err := rideRepo.SelectByID(tc.rideID)
-			if tc.expectedErr != "" {
-				assert.EqualError(t, err, tc.expectedErr)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tc.ride, ride)
-			}
+				rides, cursor, err := rideRepo.Search(context.Background(), tc.query)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.rides, rides)
+					assert.Equal(t, tc.expectedCursor, cursor)
+				}
+			})
+		}
+	}
+
+	t.Run("SQLite/When search isn't set up, return ErrSearchUnavailable", func(t *testing.T) {
+		rideRepo, db := createRideRepo(backends()[0], func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(sqliteAvailable).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 		})
+		defer db.Close()
+
+		_, _, err := rideRepo.Search(context.Background(), query("", 0))
+		assert.Equal(t, domain.ErrSearchUnavailable, err)
+	})
+}
+
+func TestRideRepository_Stats(t *testing.T) {
+	// NOTE: Days are formatted differently by every backend, the rest of the
+	// query is the same.
+	dayKeys := map[string]string{
+		"SQLite":   "date(createdAt)",
+		"Postgres": "to_char(createdAt AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+	}
+	statsQuery := func(key, where string) string {
+		return "SELECT statsKey, COUNT(*), SUM(distance), AVG(distance), MIN(CASE WHEN distanceRank * 100 >= groupCount * 50 THEN distance END), MIN(CASE WHEN distanceRank * 100 >= groupCount * 95 THEN distance END), COUNT(DISTINCT riderId) " +
+			"FROM (SELECT " + key + " AS statsKey, distance, riderId, ROW_NUMBER() OVER (PARTITION BY " + key + " ORDER BY distance) AS distanceRank, COUNT(*) OVER (PARTITION BY " + key + ") AS groupCount FROM rides " + where + ") AS ranked " +
+			"GROUP BY statsKey ORDER BY statsKey asc"
+	}
+	statsRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"statsKey", "count", "sum", "avg", "p50", "p95", "riders"})
+	}
+
+	testCases := []struct {
+		testName      string
+		setupSQLMock  func(sqlmock.Sqlmock, backend)
+		query         domain.StatsQuery
+		expectedStats []domain.RideStats
+		expectedErr   string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectQuery(statsQuery(dayKeys[b.name], "WHERE deletedAt IS NULL")).
+					WillReturnError(errors.New("Query error"))
+			},
+			query:       domain.StatsQuery{GroupBy: "day"},
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When grouped by day, return the stats of every day",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectQuery(statsQuery(dayKeys[b.name], "WHERE deletedAt IS NULL")).
+					WillReturnRows(statsRows().
+						AddRow("2021-04-01", 2, 3000, 1500, 1000, 2000, 1).
+						AddRow("2021-04-02", 1, 500, 500, 500, 500, 1))
+			},
+			query: domain.StatsQuery{GroupBy: "day"},
+			expectedStats: []domain.RideStats{
+				{Key: "2021-04-01", Count: 2, TotalDistanceMeters: 3000, AvgDistanceMeters: 1500, P50DistanceMeters: 1000, P95DistanceMeters: 2000, UniqueRiders: 1},
+				{Key: "2021-04-02", Count: 1, TotalDistanceMeters: 500, AvgDistanceMeters: 500, P50DistanceMeters: 500, P95DistanceMeters: 500, UniqueRiders: 1},
+			},
+		},
+		{
+			testName: "When grouped by driver name and filtered, group the matching rides by the column",
+			setupSQLMock: func(mock sqlmock.Sqlmock, b backend) {
+				mock.ExpectQuery(statsQuery("driverName", "WHERE deletedAt IS NULL AND distance >= ?")).
+					WithArgs(float64(1000)).
+					WillReturnRows(statsRows())
+			},
+			query:         domain.StatsQuery{GroupBy: "driverName", Pagination: domain.Pagination{MinDistance: 1000}},
+			expectedStats: []domain.RideStats{},
+		},
+		{
+			testName:    "When groupBy is unknown, return error",
+			query:       domain.StatsQuery{GroupBy: "rider"},
+			expectedErr: "rider is not a valid groupBy",
+		},
 	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, func(mock sqlmock.Sqlmock) {
+					if tc.setupSQLMock != nil {
+						tc.setupSQLMock(mock, b)
+					}
+				})
+				defer db.Close()
+
+				stats, err := rideRepo.Stats(context.Background(), tc.query)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.expectedStats, stats)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_Export(t *testing.T) {
+	header := "id,startLat,startLong,endLat,endLong,riderName,driverName,driverVehicle,distance,bearing,status,startedAt,endedAt,createdAt,updatedAt,deletedAt,driverId,riderId,vehicleId\n"
+
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		page         domain.Pagination
+		output       string
+		expectedErr  string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnError(errors.New("Query error"))
+			},
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When scan failed, return error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnRows(newRideRows().
+						AddRow(123, "not-a-number", -180, 90, 180, "John Doe", "Driver", "Car", 0, 0, "requested", nil, nil, testTime(), testTime(), nil, nil, nil, nil))
+			},
+			expectedErr: "sql: Scan error on column index 1, name \"startLat\": converting driver.Value type string (\"not-a-number\") to a float64: invalid syntax",
+		},
+		{
+			testName: "When return no rows, write only the header",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE deletedAt IS NULL ORDER BY id desc").
+					WillReturnRows(newRideRows())
+			},
+			output: header,
+		},
+		{
+			testName: "When successful, write a row for every ride ignoring the cursor and limit",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE distance >= ? ORDER BY id desc").
+					WithArgs(float64(1000)).
+					WillReturnRows(newRideRows().
+						AddRow(2, -6.2, 106.8, -6.3, 106.9, "Doe, John", "Driver", "Car", 15695.5, 137.5, "completed", testTime(), testTime(), testTime(), testTime(), testTime(), nil, nil, nil).
+						AddRow(1, -90, -180, 90, 180, "John Doe", "Driver", "Car", 20015114.442035925, 0, "requested", nil, nil, testTime(), testTime(), nil, nil, nil, nil))
+			},
+			page: domain.Pagination{After: "1", Limit: 1, IncludeDeleted: true, MinDistance: 1000},
+			output: header +
+				"2,-6.2,106.8,-6.3,106.9,\"Doe, John\",Driver,Car,15695.5,137.5,completed,2021-04-01T10:00:00Z,2021-04-01T10:00:00Z,2021-04-01T10:00:00Z,2021-04-01T10:00:00Z,2021-04-01T10:00:00Z,,,\n" +
+				"1,-90,-180,90,180,John Doe,Driver,Car,20015114.442035925,0,requested,,,2021-04-01T10:00:00Z,2021-04-01T10:00:00Z,,,,\n",
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				var output bytes.Buffer
+				err := rideRepo.Export(context.Background(), tc.page, &output)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.output, output.String())
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_SelectByID(t *testing.T) {
+	deletedAt := time.Date(2021, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		testName       string
+		setupSQLMock   setupSQLMock
+		rideID         int64
+		includeDeleted bool
+		ride           *domain.Ride
+		expectedErr    string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnError(errors.New("Query error"))
+			},
+			rideID:      123,
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When query returns errNoRows, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnError(sql.ErrNoRows)
+			},
+			rideID: 123,
+			ride:   nil,
+		},
+		{
+			testName: "When scan failed, return error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							"not-a-number",
+							"not-a-number",
+							"not-a-number",
+							"not-a-number",
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			rideID:      123,
+			expectedErr: "sql: Scan error on column index 1, name \"startLat\": converting driver.Value type string (\"not-a-number\") to a float64: invalid syntax",
+		},
+		{
+			testName: "When successful, return ride",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							-90,
+							-180,
+							90,
+							180,
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			rideID: 123,
+			ride: &domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusRequested,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
+			},
+		},
+		{
+			testName: "When including deleted rides, don't filter by deletedAt",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							-90,
+							-180,
+							90,
+							180,
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							deletedAt,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			rideID:         123,
+			includeDeleted: true,
+			ride: &domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusRequested,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
+				DeletedAt:      &deletedAt,
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				ride, err := rideRepo.SelectByID(context.Background(), tc.rideID, tc.includeDeleted)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.ride, ride)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_UpdateStatus(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		rideID       int64
+		status       domain.RideStatus
+		ride         *domain.Ride
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET status = ?, updatedAt = ? WHERE id = ? AND status IN (?) AND deletedAt IS NULL").
+					WithArgs(domain.RideStatusAccepted, sqlmock.AnyArg(), int64(123), domain.RideStatusRequested).
+					WillReturnError(errors.New("Exec error"))
+			},
+			rideID:      123,
+			status:      domain.RideStatusAccepted,
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When ride doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET status = ?, updatedAt = ? WHERE id = ? AND status IN (?) AND deletedAt IS NULL").
+					WithArgs(domain.RideStatusAccepted, sqlmock.AnyArg(), int64(123), domain.RideStatusRequested).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnError(sql.ErrNoRows)
+			},
+			rideID: 123,
+			status: domain.RideStatusAccepted,
+			ride:   nil,
+		},
+		{
+			testName: "When current status can't transition, return transition error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET status = ?, updatedAt = ? WHERE id = ? AND status IN (?,?,?) AND deletedAt IS NULL").
+					WithArgs(domain.RideStatusCancelled, sqlmock.AnyArg(), int64(123), domain.RideStatusRequested, domain.RideStatusAccepted, domain.RideStatusStarted).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							-90,
+							-180,
+							90,
+							180,
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"completed",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			rideID:      123,
+			status:      domain.RideStatusCancelled,
+			expectedErr: "can't transition ride from completed to cancelled",
+		},
+		{
+			testName: "When successful, return updated ride",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET status = ?, updatedAt = ? WHERE id = ? AND status IN (?) AND deletedAt IS NULL").
+					WithArgs(domain.RideStatusAccepted, sqlmock.AnyArg(), int64(123), domain.RideStatusRequested).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							-90,
+							-180,
+							90,
+							180,
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"accepted",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			rideID: 123,
+			status: domain.RideStatusAccepted,
+			ride: &domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusAccepted,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				ride, err := rideRepo.UpdateStatus(context.Background(), tc.rideID, tc.status)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.ride, ride)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_Update(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		ride         domain.Ride
+		updated      *domain.Ride
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET startLat = ?, startLong = ?, endLat = ?, endLong = ?, riderName = ?, driverName = ?, driverVehicle = ?, distance = ?, bearing = ?, startedAt = ?, endedAt = ?, updatedAt = ?, startGeohash = ?, endGeohash = ?, driverId = ?, riderId = ?, vehicleId = ?, startX = ?, startY = ?, startZ = ?, endX = ?, endY = ?, endZ = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(float64(-90), float64(-180), float64(90), float64(180), "John Doe", "Driver", "Car", float64(20015114.442035925), float64(0), nil, nil, sqlmock.AnyArg(), "000000000000", "zzzzzzzzzzzz", nil, nil, nil, float64(-6.123233995736757e-17), float64(-7.498798913309268e-33), float64(-1), float64(-6.123233995736757e-17), float64(7.498798913309268e-33), float64(1), int64(123)).
+					WillReturnError(errors.New("Exec error"))
+			},
+			ride: domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+			},
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When no row is affected, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET startLat = ?, startLong = ?, endLat = ?, endLong = ?, riderName = ?, driverName = ?, driverVehicle = ?, distance = ?, bearing = ?, startedAt = ?, endedAt = ?, updatedAt = ?, startGeohash = ?, endGeohash = ?, driverId = ?, riderId = ?, vehicleId = ?, startX = ?, startY = ?, startZ = ?, endX = ?, endY = ?, endZ = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(float64(-90), float64(-180), float64(90), float64(180), "John Doe", "Driver", "Car", float64(20015114.442035925), float64(0), nil, nil, sqlmock.AnyArg(), "000000000000", "zzzzzzzzzzzz", nil, nil, nil, float64(-6.123233995736757e-17), float64(-7.498798913309268e-33), float64(-1), float64(-6.123233995736757e-17), float64(7.498798913309268e-33), float64(1), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			ride: domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+			},
+			updated: nil,
+		},
+		{
+			testName: "When successful, return updated ride",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET startLat = ?, startLong = ?, endLat = ?, endLong = ?, riderName = ?, driverName = ?, driverVehicle = ?, distance = ?, bearing = ?, startedAt = ?, endedAt = ?, updatedAt = ?, startGeohash = ?, endGeohash = ?, driverId = ?, riderId = ?, vehicleId = ?, startX = ?, startY = ?, startZ = ?, endX = ?, endY = ?, endZ = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(float64(-90), float64(-180), float64(90), float64(180), "John Doe", "Driver", "Car", float64(20015114.442035925), float64(0), nil, nil, sqlmock.AnyArg(), "000000000000", "zzzzzzzzzzzz", nil, nil, nil, float64(-6.123233995736757e-17), float64(-7.498798913309268e-33), float64(-1), float64(-6.123233995736757e-17), float64(7.498798913309268e-33), float64(1), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							-90,
+							-180,
+							90,
+							180,
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"started",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			ride: domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+			},
+			updated: &domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusStarted,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				updated, err := rideRepo.Update(context.Background(), tc.ride)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.updated, updated)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_RenameRider(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET riderName = ? WHERE riderId = ?").
+					WithArgs("Johnny Doe", int64(7)).
+					WillReturnError(errors.New("Exec error"))
+			},
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When successful, rename every ride of the rider",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET riderName = ? WHERE riderId = ?").
+					WithArgs("Johnny Doe", int64(7)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				err := rideRepo.RenameRider(context.Background(), 7, "Johnny Doe")
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_Delete(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		rideID       int64
+		deleted      bool
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnError(errors.New("Exec error"))
+			},
+			rideID:      123,
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When no row is affected, return false",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			rideID:  123,
+			deleted: false,
+		},
+		{
+			testName: "When successful, return true",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			rideID:  123,
+			deleted: true,
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				deleted, err := rideRepo.Delete(context.Background(), tc.rideID)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.deleted, deleted)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_Restore(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		rideID       int64
+		ride         *domain.Ride
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NOT NULL").
+					WithArgs(nil, sqlmock.AnyArg(), int64(123)).
+					WillReturnError(errors.New("Exec error"))
+			},
+			rideID:      123,
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When ride doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NOT NULL").
+					WithArgs(nil, sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnError(sql.ErrNoRows)
+			},
+			rideID: 123,
+			ride:   nil,
+		},
+		{
+			testName: "When successful, return restored ride",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE rides SET deletedAt = ?, updatedAt = ? WHERE id = ? AND deletedAt IS NOT NULL").
+					WithArgs(nil, sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id, startLat, startLong, endLat, endLong, riderName, driverName, driverVehicle, distance, bearing, status, startedAt, endedAt, createdAt, updatedAt, deletedAt, driverId, riderId, vehicleId FROM rides WHERE id = ? AND deletedAt IS NULL").
+					WithArgs(int64(123)).
+					WillReturnRows(newRideRows().
+						AddRow(
+							123,
+							-90,
+							-180,
+							90,
+							180,
+							"John Doe",
+							"Driver",
+							"Car",
+							20015114.442035925,
+							0,
+							"requested",
+							nil,
+							nil,
+							testTime(),
+							testTime(),
+							nil,
+							nil,
+							nil,
+							nil,
+						))
+			},
+			rideID: 123,
+			ride: &domain.Ride{
+				ID:             123,
+				StartLatitude:  -90,
+				StartLongitude: -180,
+				EndLatitude:    90,
+				EndLongitude:   180,
+				RiderName:      "John Doe",
+				DriverName:     "Driver",
+				DriverVehicle:  "Car",
+				DistanceMeters: 20015114.442035925,
+				Status:         domain.RideStatusRequested,
+				CreatedAt:      testTime(),
+				UpdatedAt:      testTime(),
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				rideRepo, db := createRideRepo(b, tc.setupSQLMock)
+				defer db.Close()
+
+				ride, err := rideRepo.Restore(context.Background(), tc.rideID)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.ride, ride)
+				}
+			})
+		}
+	}
+}
+
+func TestRideRepository_CancelledContext(t *testing.T) {
+	db := openSQLite(t)
+	_, err := repository.NewMigrator(db).Up(context.Background())
+	require.NoError(t, err)
+	repo := repository.NewRideRepository(db)
+	ride := domain.Ride{
+		StartLatitude:  -6.2,
+		StartLongitude: 106.8,
+		EndLatitude:    -6.3,
+		EndLongitude:   106.9,
+		RiderName:      "John Doe",
+		DriverName:     "Driver",
+		DriverVehicle:  "Car",
+		Status:         domain.RideStatusRequested,
+		CreatedAt:      testTime(),
+		UpdatedAt:      testTime(),
+	}
+	ride.ID, err = repo.Insert(context.Background(), ride)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = repo.Export(ctx, domain.Pagination{}, &bytes.Buffer{})
+	assert.Equal(t, context.Canceled, err)
+	_, err = repo.Update(ctx, ride)
+	assert.Equal(t, context.Canceled, err)
+	_, err = repo.UpdateStatus(ctx, ride.ID, domain.RideStatusAccepted)
+	assert.Equal(t, context.Canceled, err)
+	_, err = repo.Delete(ctx, ride.ID)
+	assert.Equal(t, context.Canceled, err)
+	_, err = repo.Restore(ctx, ride.ID)
+	assert.Equal(t, context.Canceled, err)
 }
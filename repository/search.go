@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// rankedRows reads the rank selected after the ride columns along with them.
+type rankedRows struct {
+	*sql.Rows
+	rank *float64
+}
+
+// Search returns the rides matching the query best first, the cursor is the
+// rank and ID of the last ride on the page. Ranks depend on every ride stored,
+// so a cursor may skip or repeat rides once rides are changed.
+func (r rideRepository) Search(ctx context.Context, query domain.SearchQuery) ([]domain.Ride, string, error) {
+	if r.dialect.searchAvailable != nil {
+		available, err := r.dialect.searchAvailable(ctx, r.db)
+		if err != nil {
+			return nil, "", err
+		}
+		if !available {
+			return nil, "", domain.ErrSearchUnavailable
+		}
+	}
+
+	matches, args, err := r.dialect.searchMatches(searchTerms(query)).ToSql()
+	if err != nil {
+		return nil, "", err
+	}
+	builder := r.sql.Select(r.tableColumns...).
+		Column("searchRank").
+		From("rides").
+		Join("("+matches+") AS matches ON matches.searchID = rides.id", args...)
+	builder = filterRides(builder, query.Pagination).
+		OrderBy("searchRank asc", "id asc").
+		RunWith(r.db)
+
+	if query.After != "" {
+		cursor, err := parseRankCursor(query.After)
+		if err != nil {
+			return nil, "", err
+		}
+		builder = builder.Where(sq.Or{
+			sq.Gt{"searchRank": cursor.rank},
+			sq.And{sq.Eq{"searchRank": cursor.rank}, sq.Gt{"id": cursor.id}},
+		})
+	}
+	if query.Limit > 0 {
+		// NOTE: This is so that we know whether there is another page, make sure
+		// to not return the extra element.
+		builder = builder.Limit(query.Limit + 1)
+	}
+
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	rides := make([]domain.Ride, 0)
+	ranks := map[int64]float64{}
+	for rows.Next() {
+		var rank float64
+		ride, err := scanRide(rankedRows{Rows: rows, rank: &rank})
+		if err != nil {
+			return nil, "", err
+		}
+		ranks[ride.ID] = rank
+		rides = append(rides, ride)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if query.Limit == 0 || uint64(len(rides)) <= query.Limit {
+		return rides, "", nil
+	}
+	rides = rides[:query.Limit]
+	last := rides[len(rides)-1]
+	next := rankCursor{rank: ranks[last.ID], id: last.ID}
+	return rides, next.String(), nil
+}
+
+func (r rankedRows) Scan(dest ...interface{}) error {
+	return r.Rows.Scan(append(dest, r.rank)...)
+}
+
+// searchTerms splits the words of the query into every three consecutive
+// characters of them, a name matches a term when it contains it regardless of
+// case.
+func searchTerms(query domain.SearchQuery) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range query.Words() {
+		runes := []rune(word)
+		for i := 0; i+3 <= len(runes); i++ {
+			term := string(runes[i : i+3])
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+// countMatches counts the terms contained in the names of the ride.
+func countMatches(ride domain.Ride, terms []string) int {
+	names := strings.ToLower(strings.Join([]string{ride.RiderName, ride.DriverName, ride.DriverVehicle}, " "))
+	count := 0
+	for _, term := range terms {
+		if strings.Contains(names, term) {
+			count++
+		}
+	}
+	return count
+}
+
+// sqliteSearchSchema keeps an FTS5 index of the names in sync with the rides
+// table. The trigram tokenizer indexes every three consecutive characters, the
+// same as searchTerms, and rides are ranked by BM25.
+func sqliteSearchSchema() []string {
+	return []string{
+		"CREATE VIRTUAL TABLE IF NOT EXISTS rides_search USING fts5(riderName, driverName, driverVehicle, content='rides', content_rowid='id', tokenize='trigram')",
+		`CREATE TRIGGER rides_search_insert AFTER INSERT ON rides BEGIN
+    INSERT INTO rides_search (rowid, riderName, driverName, driverVehicle) VALUES (new.id, new.riderName, new.driverName, new.driverVehicle);
+END`,
+		`CREATE TRIGGER rides_search_delete AFTER DELETE ON rides BEGIN
+    INSERT INTO rides_search (rides_search, rowid, riderName, driverName, driverVehicle) VALUES ('delete', old.id, old.riderName, old.driverName, old.driverVehicle);
+END`,
+		`CREATE TRIGGER rides_search_update AFTER UPDATE OF riderName, driverName, driverVehicle ON rides BEGIN
+    INSERT INTO rides_search (rides_search, rowid, riderName, driverName, driverVehicle) VALUES ('delete', old.id, old.riderName, old.driverName, old.driverVehicle);
+    INSERT INTO rides_search (rowid, riderName, driverName, driverVehicle) VALUES (new.id, new.riderName, new.driverName, new.driverVehicle);
+END`,
+		"INSERT INTO rides_search (rides_search) VALUES ('rebuild')",
+	}
+}
+
+// setUpSQLiteSearch creates the search index when its triggers are missing. As
+// the index only holds what's in the rides table it isn't a migration, it's
+// rebuilt instead whenever it might be out of date: after the rides table was
+// recreated or written to by a build without FTS5. Such builds drop the
+// triggers, which they can't run, so rides can still be written.
+func setUpSQLiteSearch(ctx context.Context, db *sql.DB) error {
+	var fts5 bool
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return err
+	}
+	if !fts5 {
+		return dropSQLiteSearchTriggers(ctx, db)
+	}
+
+	available, err := sqliteSearchAvailable(ctx, db)
+	if err != nil || available {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := dropSQLiteSearchTriggers(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, query := range sqliteSearchSchema() {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func dropSQLiteSearchTriggers(ctx context.Context, runner sq.ExecerContext) error {
+	for _, trigger := range []string{"rides_search_insert", "rides_search_delete", "rides_search_update"} {
+		if _, err := runner.ExecContext(ctx, fmt.Sprintf("DROP TRIGGER IF EXISTS %s", trigger)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteSearchAvailable tells whether the search index is kept in sync.
+func sqliteSearchAvailable(ctx context.Context, db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'rides_search_insert'").Scan(&count)
+	return count > 0, err
+}
+
+// sqliteSearchMatches matches any of the terms, which are quoted so that they
+// are never read as FTS5 operators.
+func sqliteSearchMatches(terms []string) sq.SelectBuilder {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return sq.Select("rowid AS searchID", "rank AS searchRank").
+		From("rides_search").
+		Where("rides_search MATCH ?", strings.Join(quoted, " OR "))
+}
+
+// postgresSearchMatches ranks rides by how many of the terms their names
+// contain, the same as the memory repository. Every ride is scanned as there
+// is no index for it without the pg_trgm extension.
+func postgresSearchMatches(terms []string) sq.SelectBuilder {
+	matches := make([]string, len(terms))
+	args := make([]interface{}, len(terms))
+	for i, term := range terms {
+		matches[i] = "(CASE WHEN lower(riderName || ' ' || driverName || ' ' || driverVehicle) LIKE ? THEN 1 ELSE 0 END)"
+		args[i] = "%" + likeEscaper().Replace(term) + "%"
+	}
+	count := strings.Join(matches, " + ")
+	return sq.Select("id AS searchID").
+		Column(sq.Expr("-("+count+") AS searchRank", args...)).
+		From("rides").
+		Where(sq.Expr(count+" > 0", args...))
+}
+
+// likeEscaper escapes what LIKE treats as wildcards with its default escape
+// character.
+func likeEscaper() *strings.Replacer {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// sortColumn reads the value a ride is sorted by and parses it back from a
+// cursor.
+type sortColumn struct {
+	value func(domain.Ride) interface{}
+	parse func(string) (interface{}, error)
+}
+
+// sortColumns holds every column listed by domain.Pagination.SortFields.
+func sortColumns() map[string]sortColumn {
+	parseFloat := func(s string) (interface{}, error) {
+		return strconv.ParseFloat(s, 64)
+	}
+	parseString := func(s string) (interface{}, error) {
+		return s, nil
+	}
+	parseTime := func(s string) (interface{}, error) {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	return map[string]sortColumn{
+		"id": {
+			value: func(r domain.Ride) interface{} { return r.ID },
+			parse: func(s string) (interface{}, error) { return strconv.ParseInt(s, 10, 64) },
+		},
+		"startLat":      {value: func(r domain.Ride) interface{} { return r.StartLatitude }, parse: parseFloat},
+		"startLong":     {value: func(r domain.Ride) interface{} { return r.StartLongitude }, parse: parseFloat},
+		"endLat":        {value: func(r domain.Ride) interface{} { return r.EndLatitude }, parse: parseFloat},
+		"endLong":       {value: func(r domain.Ride) interface{} { return r.EndLongitude }, parse: parseFloat},
+		"riderName":     {value: func(r domain.Ride) interface{} { return r.RiderName }, parse: parseString},
+		"driverName":    {value: func(r domain.Ride) interface{} { return r.DriverName }, parse: parseString},
+		"driverVehicle": {value: func(r domain.Ride) interface{} { return r.DriverVehicle }, parse: parseString},
+		"distance":      {value: func(r domain.Ride) interface{} { return r.DistanceMeters }, parse: parseFloat},
+		"bearing":       {value: func(r domain.Ride) interface{} { return r.BearingDegrees }, parse: parseFloat},
+		"status":        {value: func(r domain.Ride) interface{} { return string(r.Status) }, parse: parseString},
+		"createdAt":     {value: func(r domain.Ride) interface{} { return r.CreatedAt.UTC() }, parse: parseTime},
+		"updatedAt":     {value: func(r domain.Ride) interface{} { return r.UpdatedAt.UTC() }, parse: parseTime},
+	}
+}
+
+// sortKey returns the values the ride is sorted by.
+func sortKey(ride domain.Ride, fields []domain.SortField) []interface{} {
+	columns := sortColumns()
+	key := make([]interface{}, len(fields))
+	for i, field := range fields {
+		key[i] = columns[field.Name].value(ride)
+	}
+	return key
+}
+
+// formatPosition formats the sort key of the ride as a cursor, values are
+// escaped and separated by commas so a ride sorted only by ID is its ID.
+func formatPosition(ride domain.Ride, fields []domain.SortField) string {
+	key := sortKey(ride, fields)
+	values := make([]string, len(key))
+	for i, value := range key {
+		switch v := value.(type) {
+		case int64:
+			values[i] = strconv.FormatInt(v, 10)
+		case float64:
+			values[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		case time.Time:
+			values[i] = v.Format(time.RFC3339Nano)
+		default:
+			values[i] = url.QueryEscape(fmt.Sprint(v))
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// parsePosition parses a cursor made by formatPosition for the same fields.
+func parsePosition(position string, fields []domain.SortField) ([]interface{}, error) {
+	values := strings.Split(position, ",")
+	if len(values) != len(fields) {
+		return nil, fmt.Errorf("%s is not a valid cursor for %d sort fields", position, len(fields))
+	}
+	columns := sortColumns()
+	key := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value, err := url.QueryUnescape(values[i])
+		if err != nil {
+			return nil, err
+		}
+		if key[i], err = columns[field.Name].parse(value); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// orderBy sorts by the fields, or the other way around when paging back.
+func orderBy(fields []domain.SortField, back bool) []string {
+	clauses := make([]string, len(fields))
+	for i, field := range fields {
+		direction := "asc"
+		if field.Desc != back {
+			direction = "desc"
+		}
+		clauses[i] = field.Name + " " + direction
+	}
+	return clauses
+}
+
+// keysetPredicate matches the rides sorted after the key, or before it when
+// paging back. A key (a, b) is followed by rides with a past it, or the same a
+// and b past it.
+func keysetPredicate(fields []domain.SortField, key []interface{}, back bool) sq.Sqlizer {
+	past := func(field domain.SortField, value interface{}) sq.Sqlizer {
+		if field.Desc != back {
+			return sq.Lt{field.Name: value}
+		}
+		return sq.Gt{field.Name: value}
+	}
+	if len(fields) == 1 {
+		return past(fields[0], key[0])
+	}
+
+	predicate := sq.Or{}
+	for i, field := range fields {
+		clause := sq.And{}
+		for j := 0; j < i; j++ {
+			clause = append(clause, sq.Eq{fields[j].Name: key[j]})
+		}
+		predicate = append(predicate, append(clause, past(field, key[i])))
+	}
+	return predicate
+}
+
+// compareKeys orders two sort keys the same way as the database, it returns a
+// negative number when a comes first.
+func compareKeys(fields []domain.SortField, a, b []interface{}) int {
+	for i, field := range fields {
+		c := compareValues(a[i], b[i])
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		return compareOrdered(a < b.(int64), a > b.(int64))
+	case float64:
+		return compareOrdered(a < b.(float64), a > b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return compareOrdered(a.Before(b.(time.Time)), a.After(b.(time.Time)))
+	}
+	return 0
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+// Stats summarizes every group of rides matching the filters with a single
+// GROUP BY. Rides are first numbered by their distance within their group, so
+// the percentiles are the distances of the first rides numbered past them.
+func (r rideRepository) Stats(ctx context.Context, query domain.StatsQuery) ([]domain.RideStats, error) {
+	key, ok := r.dialect.statsKeys()[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a valid groupBy", query.GroupBy)
+	}
+	group := "PARTITION BY " + key
+	ranked := filterRides(r.sql.Select(
+		key+" AS statsKey",
+		"distance",
+		"riderId",
+		"ROW_NUMBER() OVER ("+group+" ORDER BY distance) AS distanceRank",
+		"COUNT(*) OVER ("+group+") AS groupCount",
+	).From("rides"), query.Pagination)
+
+	rows, err := r.sql.Select(
+		"statsKey",
+		"COUNT(*)",
+		"SUM(distance)",
+		"AVG(distance)",
+		percentileColumn(50),
+		percentileColumn(95),
+		"COUNT(DISTINCT riderId)",
+	).
+		FromSelect(ranked, "ranked").
+		GroupBy("statsKey").
+		OrderBy("statsKey asc").
+		RunWith(r.db).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]domain.RideStats, 0)
+	for rows.Next() {
+		var s domain.RideStats
+		err := rows.Scan(
+			&s.Key,
+			&s.Count,
+			&s.TotalDistanceMeters,
+			&s.AvgDistanceMeters,
+			&s.P50DistanceMeters,
+			&s.P95DistanceMeters,
+			&s.UniqueRiders,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// statsKeys returns the expression of the key of every group rides can be
+// grouped by.
+func (d dialect) statsKeys() map[string]string {
+	return map[string]string{
+		"day":           d.dayKey,
+		"week":          d.weekKey,
+		"driverName":    "driverName",
+		"driverVehicle": "driverVehicle",
+	}
+}
+
+// percentileColumn selects the distance at the nearest rank of the percentile,
+// which is the first rank that is at least percent of the group.
+func percentileColumn(percent int) string {
+	// NOTE: Ranks are compared as integers so that every database rounds them
+	// the same way.
+	return fmt.Sprintf("MIN(CASE WHEN distanceRank * 100 >= groupCount * %d THEN distance END)", percent)
+}
+
+// rideStatsKeys reads the key of the group of a ride the same way as the
+// dialects do in SQL.
+func rideStatsKeys() map[string]func(domain.Ride) string {
+	return map[string]func(domain.Ride) string{
+		"day": func(r domain.Ride) string {
+			return r.CreatedAt.UTC().Format("2006-01-02")
+		},
+		"week": func(r domain.Ride) string {
+			createdAt := r.CreatedAt.UTC()
+			daysSinceMonday := (int(createdAt.Weekday()) + 6) % 7
+			return createdAt.AddDate(0, 0, -daysSinceMonday).Format("2006-01-02")
+		},
+		"driverName":    func(r domain.Ride) string { return r.DriverName },
+		"driverVehicle": func(r domain.Ride) string { return r.DriverVehicle },
+	}
+}
+
+// summarizeRides returns the stats of a group of rides, there is at least one.
+func summarizeRides(key string, rides []domain.Ride) domain.RideStats {
+	distances := make([]float64, len(rides))
+	riders := map[int64]bool{}
+	stats := domain.RideStats{Key: key, Count: int64(len(rides))}
+	for i, ride := range rides {
+		distances[i] = ride.DistanceMeters
+		if ride.RiderID != nil {
+			riders[*ride.RiderID] = true
+		}
+		stats.TotalDistanceMeters += ride.DistanceMeters
+	}
+	sort.Float64s(distances)
+
+	stats.AvgDistanceMeters = stats.TotalDistanceMeters / float64(len(rides))
+	stats.P50DistanceMeters = nearestRank(distances, 50)
+	stats.P95DistanceMeters = nearestRank(distances, 95)
+	stats.UniqueRiders = int64(len(riders))
+	return stats
+}
+
+// nearestRank returns the value at the nearest rank of the percentile among
+// the sorted values, the same as percentileColumn.
+func nearestRank(sorted []float64, percent int) float64 {
+	rank := (len(sorted)*percent + 99) / 100
+	return sorted[rank-1]
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	domain "github.com/hawarir/backend-coding-test"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type vehicleRepository struct {
+	db      *sql.DB
+	dialect dialect
+	sql     sq.StatementBuilderType
+}
+
+// NewVehicleRepository stores vehicles in SQLite.
+func NewVehicleRepository(db *sql.DB) domain.VehicleRepository {
+	return newVehicleRepository(db, sqliteDialect())
+}
+
+// newVehicleRepository expects the schema to be migrated by the Migrator of the
+// same dialect.
+func newVehicleRepository(db *sql.DB, d dialect) vehicleRepository {
+	return vehicleRepository{
+		db:      db,
+		dialect: d,
+		sql:     sq.StatementBuilder.PlaceholderFormat(d.placeholder),
+	}
+}
+
+func vehicleColumns() []string {
+	return []string{
+		"id",
+		"plateNumber",
+		"country",
+		"make",
+		"model",
+		"capacity",
+		"type",
+		"createdAt",
+		"updatedAt",
+		"deactivatedAt",
+	}
+}
+
+func (r vehicleRepository) Insert(ctx context.Context, vehicle domain.Vehicle) (int64, error) {
+	builder := r.sql.Insert("vehicles").
+		Columns("plateNumber", "country", "make", "model", "capacity", "type", "createdAt", "updatedAt").
+		Values(
+			vehicle.PlateNumber,
+			vehicle.Country,
+			vehicle.Make,
+			vehicle.Model,
+			vehicle.Capacity,
+			vehicle.Type,
+			vehicle.CreatedAt,
+			vehicle.UpdatedAt,
+		).
+		RunWith(r.db)
+
+	if r.dialect.returningID {
+		var id int64
+		if err := builder.Suffix("RETURNING id").QueryRowContext(ctx).Scan(&id); err != nil {
+			return -1, r.plateError(err)
+		}
+		return id, nil
+	}
+	result, err := builder.ExecContext(ctx)
+	if err != nil {
+		return -1, r.plateError(err)
+	}
+	return result.LastInsertId()
+}
+
+// SelectAll returns vehicles oldest first, deactivated ones included. The
+// cursor is the ID of the last vehicle on the page.
+func (r vehicleRepository) SelectAll(ctx context.Context, page domain.VehiclePagination) ([]domain.Vehicle, string, error) {
+	builder := r.sql.Select(vehicleColumns()...).
+		From("vehicles").
+		OrderBy("id asc").
+		RunWith(r.db)
+	if page.After != "" {
+		after, err := strconv.ParseInt(page.After, 10, 64)
+		if err != nil {
+			return nil, "", err
+		}
+		builder = builder.Where(sq.Gt{"id": after})
+	}
+	if page.Limit > 0 {
+		// NOTE: This is so that we know whether there is another page, make sure
+		// to not return the extra element.
+		builder = builder.Limit(page.Limit + 1)
+	}
+
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	vehicles := make([]domain.Vehicle, 0)
+	for rows.Next() {
+		vehicle, err := scanVehicle(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if page.Limit == 0 || uint64(len(vehicles)) <= page.Limit {
+		return vehicles, "", nil
+	}
+	vehicles = vehicles[:page.Limit]
+	return vehicles, strconv.FormatInt(vehicles[len(vehicles)-1].ID, 10), nil
+}
+
+func (r vehicleRepository) SelectByID(ctx context.Context, id int64) (*domain.Vehicle, error) {
+	row := r.sql.Select(vehicleColumns()...).
+		From("vehicles").
+		Where(sq.Eq{"id": id}).
+		RunWith(r.db).
+		QueryRowContext(ctx)
+	vehicle, err := scanVehicle(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// Update replaces the details of the vehicle whether it's deactivated or not.
+func (r vehicleRepository) Update(ctx context.Context, vehicle domain.Vehicle) (*domain.Vehicle, error) {
+	result, err := r.sql.Update("vehicles").
+		Set("plateNumber", vehicle.PlateNumber).
+		Set("country", vehicle.Country).
+		Set("make", vehicle.Make).
+		Set("model", vehicle.Model).
+		Set("capacity", vehicle.Capacity).
+		Set("type", vehicle.Type).
+		Set("updatedAt", time.Now().UTC()).
+		Where(sq.Eq{"id": vehicle.ID}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, r.plateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return nil, err
+	}
+	return r.SelectByID(ctx, vehicle.ID)
+}
+
+// Deactivate marks the vehicle as deactivated unless it already is, either way
+// the vehicle is returned as it's stored. It returns nil when there is no such
+// vehicle.
+func (r vehicleRepository) Deactivate(ctx context.Context, id int64) (*domain.Vehicle, error) {
+	now := time.Now().UTC()
+	_, err := r.sql.Update("vehicles").
+		Set("deactivatedAt", now).
+		Set("updatedAt", now).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"deactivatedAt": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.SelectByID(ctx, id)
+}
+
+// plateError returns ErrDuplicatePlateNumber for an error of the unique plate
+// number of a country, other errors are returned as they are.
+func (r vehicleRepository) plateError(err error) error {
+	if r.dialect.uniqueViolation(err) {
+		return domain.ErrDuplicatePlateNumber
+	}
+	return err
+}
+
+func scanVehicle(s rowScanner) (domain.Vehicle, error) {
+	var vehicle domain.Vehicle
+	err := s.Scan(
+		&vehicle.ID,
+		&vehicle.PlateNumber,
+		&vehicle.Country,
+		&vehicle.Make,
+		&vehicle.Model,
+		&vehicle.Capacity,
+		&vehicle.Type,
+		&vehicle.CreatedAt,
+		&vehicle.UpdatedAt,
+		&vehicle.DeactivatedAt,
+	)
+	return vehicle, err
+}
@@ -0,0 +1,223 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/hawarir/backend-coding-test"
+)
+
+func newVehicleRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "plateNumber", "country", "make", "model", "capacity", "type", "createdAt", "updatedAt", "deactivatedAt"})
+}
+
+func TestVehicleRepository_Insert(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		returnErr    error
+		plateTaken   bool
+		lastInsertID int64
+		expectedErr  string
+	}{
+		{
+			testName:     "When exec returns error, return the error",
+			returnErr:    errors.New("Exec error"),
+			lastInsertID: -1,
+			expectedErr:  "Exec error",
+		},
+		{
+			testName:     "When plate number is taken, return ErrDuplicatePlateNumber",
+			plateTaken:   true,
+			lastInsertID: -1,
+			expectedErr:  domain.ErrDuplicatePlateNumber.Error(),
+		},
+		{
+			testName:     "When successful, return the result",
+			lastInsertID: 123,
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				returnErr := tc.returnErr
+				if tc.plateTaken {
+					returnErr = b.uniqueViolation
+				}
+				db := createSQLMock(b, func(mock sqlmock.Sqlmock) {
+					query := "INSERT INTO vehicles (plateNumber,country,make,model,capacity,type,createdAt,updatedAt) VALUES (?,?,?,?,?,?,?,?)"
+					args := []driver.Value{"B 1234 XYZ", "ID", "Toyota", "Avanza", int64(6), "car", testTime(), testTime()}
+					switch {
+					case b.returningID && returnErr != nil:
+						mock.ExpectQuery(query + " RETURNING id").WithArgs(args...).WillReturnError(returnErr)
+					case b.returningID:
+						mock.ExpectQuery(query + " RETURNING id").WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tc.lastInsertID))
+					case returnErr != nil:
+						mock.ExpectExec(query).WithArgs(args...).WillReturnError(returnErr)
+					default:
+						mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(tc.lastInsertID, 1))
+					}
+				})
+				defer db.Close()
+
+				id, err := b.newVehicleRepo(db).Insert(context.Background(), domain.Vehicle{
+					PlateNumber: "B 1234 XYZ",
+					Country:     "ID",
+					Make:        "Toyota",
+					Model:       "Avanza",
+					Capacity:    6,
+					Type:        domain.VehicleTypeCar,
+					CreatedAt:   testTime(),
+					UpdatedAt:   testTime(),
+				})
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tc.lastInsertID, id)
+			})
+		}
+	}
+}
+
+func TestVehicleRepository_SelectByID(t *testing.T) {
+	deactivatedAt := testTime()
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		vehicle      *domain.Vehicle
+		expectedErr  string
+	}{
+		{
+			testName: "When query returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, plateNumber, country, make, model, capacity, type, createdAt, updatedAt, deactivatedAt FROM vehicles WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnError(errors.New("Query error"))
+			},
+			expectedErr: "Query error",
+		},
+		{
+			testName: "When vehicle doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, plateNumber, country, make, model, capacity, type, createdAt, updatedAt, deactivatedAt FROM vehicles WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newVehicleRows())
+			},
+		},
+		{
+			testName: "When vehicle is deactivated, still return it",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, plateNumber, country, make, model, capacity, type, createdAt, updatedAt, deactivatedAt FROM vehicles WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newVehicleRows().AddRow(123, "B 1234 XYZ", "ID", "Toyota", "Avanza", 6, "car", testTime(), testTime(), testTime()))
+			},
+			vehicle: &domain.Vehicle{
+				ID:            123,
+				PlateNumber:   "B 1234 XYZ",
+				Country:       "ID",
+				Make:          "Toyota",
+				Model:         "Avanza",
+				Capacity:      6,
+				Type:          domain.VehicleTypeCar,
+				CreatedAt:     testTime(),
+				UpdatedAt:     testTime(),
+				DeactivatedAt: &deactivatedAt,
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				vehicle, err := b.newVehicleRepo(db).SelectByID(context.Background(), 123)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.vehicle, vehicle)
+				}
+			})
+		}
+	}
+}
+
+func TestVehicleRepository_Deactivate(t *testing.T) {
+	deactivatedAt := testTime()
+	testCases := []struct {
+		testName     string
+		setupSQLMock setupSQLMock
+		vehicle      *domain.Vehicle
+		expectedErr  string
+	}{
+		{
+			testName: "When exec returns error, return the error",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE vehicles SET deactivatedAt = ?, updatedAt = ? WHERE id = ? AND deactivatedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnError(errors.New("Exec error"))
+			},
+			expectedErr: "Exec error",
+		},
+		{
+			testName: "When vehicle doesn't exist, return nil",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE vehicles SET deactivatedAt = ?, updatedAt = ? WHERE id = ? AND deactivatedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, plateNumber, country, make, model, capacity, type, createdAt, updatedAt, deactivatedAt FROM vehicles WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newVehicleRows())
+			},
+		},
+		{
+			testName: "When vehicle is already deactivated, return it as it is",
+			setupSQLMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE vehicles SET deactivatedAt = ?, updatedAt = ? WHERE id = ? AND deactivatedAt IS NULL").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(123)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, plateNumber, country, make, model, capacity, type, createdAt, updatedAt, deactivatedAt FROM vehicles WHERE id = ?").
+					WithArgs(int64(123)).
+					WillReturnRows(newVehicleRows().AddRow(123, "B 1234 XYZ", "ID", "Toyota", "Avanza", 6, "car", testTime(), testTime(), testTime()))
+			},
+			vehicle: &domain.Vehicle{
+				ID:            123,
+				PlateNumber:   "B 1234 XYZ",
+				Country:       "ID",
+				Make:          "Toyota",
+				Model:         "Avanza",
+				Capacity:      6,
+				Type:          domain.VehicleTypeCar,
+				CreatedAt:     testTime(),
+				UpdatedAt:     testTime(),
+				DeactivatedAt: &deactivatedAt,
+			},
+		},
+	}
+
+	for _, b := range backends() {
+		for _, tc := range testCases {
+			t.Run(b.name+"/"+tc.testName, func(t *testing.T) {
+				db := createSQLMock(b, tc.setupSQLMock)
+				defer db.Close()
+
+				vehicle, err := b.newVehicleRepo(db).Deactivate(context.Background(), 123)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.vehicle, vehicle)
+				}
+			})
+		}
+	}
+}